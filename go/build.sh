@@ -17,6 +17,16 @@ go build -o bin/local-server ./cmd/local
 echo "Building remote server..."
 go build -o bin/remote-server ./cmd/remote
 
+# Build mcpctl
+echo "Building mcpctl..."
+go build -o bin/mcpctl ./cmd/mcpctl
+
+# Build bridge
+echo "Building bridge..."
+go build -o bin/bridge ./cmd/bridge
+
 echo "Build complete!"
 echo "Local server: bin/local-server"
 echo "Remote server: bin/remote-server"
+echo "mcpctl: bin/mcpctl"
+echo "Bridge: bin/bridge"
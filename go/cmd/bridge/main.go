@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/config"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/listener"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/middleware"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+)
+
+// logger is the logger of the "bridge" component
+var logger = logging.Component("bridge")
+
+// The bridge runs in one of two modes:
+//
+//	bridge [flags]                          stdio -> remote: serves MCP on stdin/stdout
+//	                                        and forwards everything to MCP_REMOTE_URL
+//	bridge [flags] serve <command> [args]   HTTP -> stdio: exposes a local stdio server
+//	                                        over SSE and WebSocket with API key auth and
+//	                                        rate limiting
+//
+// Both modes read the shared configuration (-config, environment, flags).
+func main() {
+	if err := godotenv.Load(); err != nil {
+		logger.Info("No .env file found")
+	}
+
+	configPath := flag.String("config", os.Getenv("MCP_CONFIG"), "YAML configuration file (env MCP_CONFIG)")
+	overrides := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath, overrides)
+	if err != nil {
+		logging.Fatal(logger, "Invalid configuration", "error", err)
+	}
+
+	// Log to stderr (stdout is used for MCP protocol), Gin included
+	if err := logging.Setup(cfg.Logging.Options(os.Stderr)); err != nil {
+		logging.Fatal(logger, "Invalid logging configuration", "error", err)
+	}
+	httpLogger := logging.Component("http")
+	gin.DefaultWriter = logging.Writer(httpLogger, slog.LevelDebug)
+	gin.DefaultErrorWriter = logging.Writer(httpLogger, slog.LevelError)
+
+	if args := flag.Args(); len(args) > 0 && args[0] == "serve" {
+		if len(args) < 2 {
+			logging.Fatal(logger, "Usage: bridge [flags] serve <command> [args...]")
+		}
+		serveHTTP(cfg, args[1], args[2:])
+		return
+	}
+	serveStdio(cfg)
+}
+
+// serveStdio proxies a stdio client to the remote server
+func serveStdio(cfg *config.Config) {
+	remoteURL := os.Getenv("MCP_REMOTE_URL")
+	if remoteURL == "" {
+		logging.Fatal(logger, "MCP_REMOTE_URL environment variable is required")
+	}
+
+	header := http.Header{}
+	if cfg.Auth.APIKey != "" {
+		header.Set("Authorization", "Bearer "+cfg.Auth.APIKey)
+	}
+	options := mcp.HTTPOptions{Header: header}
+
+	// The remote server (cmd/remote) serves SSE; Streamable HTTP is for
+	// other servers that support it
+	transport := os.Getenv("MCP_REMOTE_TRANSPORT")
+	if transport == "" {
+		transport = "sse"
+	}
+
+	var dial mcp.Dialer
+	switch transport {
+	case "sse":
+		dial = mcp.SSEDialer(remoteURL, options)
+	case "http":
+		dial = mcp.StreamableHTTPDialer(remoteURL, options)
+	default:
+		logging.Fatal(logger, "Invalid MCP_REMOTE_TRANSPORT (expected sse or http)", "transport", transport)
+	}
+
+	proxy := mcp.NewProxy(dial, mcp.ProxyOptions{})
+
+	logger.Info("MCP bridge started (stdio)", "remote", remoteURL, "transport", transport)
+	if err := proxy.Serve(context.Background(), mcp.NewStdioTransport(cfg.Stdio.Options()...)); err != nil {
+		logging.Fatal(logger, "Bridge error", "error", err)
+	}
+	logger.Info("EOF received, shutting down")
+}
+
+// serveHTTP exposes a stdio server command over HTTP. Every SSE session or
+// WebSocket connection gets its own server process.
+func serveHTTP(cfg *config.Config, command string, args []string) {
+	apiKey := cfg.Auth.APIKey
+	if apiKey == "" {
+		logging.Fatal(logger, "MCP_API_KEY environment variable is required")
+	}
+
+	// Validate has already checked the framing
+	framing, _ := mcp.ParseFraming(cfg.Stdio.Framing)
+	proxy := mcp.NewProxy(mcp.CommandDialer(mcp.CommandSpec{
+		Command: command,
+		Args:    args,
+		Framing: framing,
+	}), mcp.ProxyOptions{})
+
+	corsOrigin := cfg.CORS.Origin
+	sseHandler := mcp.NewSSEHandler(proxy, cfg.SSE.Options())
+	wsOptions := cfg.WebSocket.Options()
+	wsOptions.CheckOrigin = middleware.CheckOrigin(corsOrigin)
+	wsHandler := mcp.NewWebSocketHandler(proxy, wsOptions)
+
+	router := gin.New()
+	router.Use(middleware.RequestLog(), middleware.Recovery())
+	router.Use(middleware.CORS(corsOrigin))
+	limit := cfg.RateLimit.Limit()
+	router.Use(middleware.RateLimitWith(ratelimit.New(), func() ratelimit.Limit { return limit }))
+	authMiddleware := middleware.Auth(apiKey)
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ok",
+			"server":  "go-mcp-bridge",
+			"command": command,
+		})
+	})
+
+	router.GET("/sse", authMiddleware, func(c *gin.Context) {
+		if err := sseHandler.HandleSSE(c.Writer, c.Request, "/message"); err != nil {
+			logger.WarnContext(c.Request.Context(), "SSE error", "error", err)
+		}
+	})
+
+	router.POST("/message", authMiddleware, func(c *gin.Context) {
+		if err := sseHandler.HandleMessage(c.Writer, c.Request); err != nil {
+			logger.WarnContext(c.Request.Context(), "Message error", "error", err)
+		}
+	})
+
+	router.GET("/ws", authMiddleware, func(c *gin.Context) {
+		if err := wsHandler.HandleWebSocket(c.Writer, c.Request); err != nil {
+			logger.WarnContext(c.Request.Context(), "WebSocket error", "error", err)
+		}
+	})
+
+	listenAddr := cfg.Server.ListenAddr()
+	ln, err := listener.Listen(listenAddr, cfg.Server.SocketOptions())
+	if err != nil {
+		logging.Fatal(logger, "Failed to listen", "addr", listenAddr, "error", err)
+	}
+
+	httpServer := &http.Server{
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Duration(),
+		// SSE streams extend their own write deadline with every event
+		WriteTimeout: cfg.Server.WriteTimeout.Duration(),
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration(),
+	}
+
+	logger.Info("MCP bridge started (HTTP)", "addr", ln.Addr().String(), "command", command)
+	if err := httpServer.Serve(ln); err != nil {
+		logging.Fatal(logger, "Server error", "error", err)
+	}
+}
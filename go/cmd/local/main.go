@@ -1,79 +1,131 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/app"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/config"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
 	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/telemetry"
 	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/tools"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/usage"
 )
 
+// logger is the logger of the "server" component
+var logger = logging.Component("server")
+
 func main() {
-	// Set up logging to stderr (stdout is used for MCP protocol)
-	log.SetOutput(os.Stderr)
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	configPath := flag.String("config", os.Getenv("MCP_CONFIG"), "YAML configuration file (env MCP_CONFIG)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	overrides := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath, overrides)
+	if err != nil {
+		logging.Fatal(logger, "Invalid configuration", "error", err)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logging.Fatal(logger, "Failed to print configuration", "error", err)
+		}
+		return
+	}
+
+	// Log to stderr (stdout is used for MCP protocol)
+	if err := logging.Setup(cfg.Logging.Options(os.Stderr)); err != nil {
+		logging.Fatal(logger, "Invalid logging configuration", "error", err)
+	}
 
 	// Create MCP server
 	server := mcp.NewServer()
 
+	// Tool calls are audited like the remote server's, under the
+	// "anonymous" principal
+	redact := cfg.Audit.Redact
+	if _, err := app.StartAudit(server, cfg, func() []string { return redact }); err != nil {
+		logging.Fatal(logger, "Invalid audit.file", "error", err)
+	}
+
+	// The same per-tool rate limits as the remote server
+	toolLimits := cfg.RateLimit.Tools
+	server.UseToolMiddleware(ratelimit.Tools(ratelimit.New(), func() map[string]int { return toolLimits }))
+
+	// Usage and quotas are counted like the remote server's, under the
+	// "anonymous" caller
+	quotas := cfg.Usage.Quotas
+	tracker, err := app.NewUsageTracker(server, cfg, func() map[string]usage.Quota { return quotas })
+	if err != nil {
+		logging.Fatal(logger, "Invalid usage.file", "error", err)
+	}
+
+	// Spans of JSON-RPC requests and tool calls, flushed on shutdown
+	stopTracing, err := telemetry.Setup(context.Background(), cfg.Tracing.Options(os.Stderr))
+	if err != nil {
+		logging.Fatal(logger, "Invalid tracing configuration", "error", err)
+	}
+	server.OnShutdown(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := stopTracing(ctx); err != nil {
+			logger.Warn("Tracing shutdown", "error", err)
+		}
+	})
+
+	// The storage tools keep their data in the configured backend
+	store, err := app.OpenStorage(server, cfg)
+	if err != nil {
+		logging.Fatal(logger, "Invalid storage configuration", "error", err)
+	}
+	storageTools := tools.NewStorageTools(store)
+
 	// Register tools
-	registerTools(server)
+	count, err := tools.Register(server, storageTools, cfg.Tools.Enabled)
+	if err != nil {
+		logging.Fatal(logger, "Failed to register tools", "error", err)
+	}
+	logger.Info("Registered tools", "count", count)
+
+	// Proxy the tools of upstream servers, if configured
+	if _, err := app.StartGateway(server, cfg.Gateway.Config); err != nil {
+		logging.Fatal(logger, "Invalid gateway.config", "error", err)
+	}
 
 	// Create stdio transport
-	transport := mcp.NewStdioTransport(server)
+	transport := mcp.NewStdioTransport(cfg.Stdio.Options()...)
+
+	// Stop on SIGINT/SIGTERM, draining in-flight requests for up to the grace period
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go tracker.Run(ctx, cfg.Usage.FlushInterval.Duration())
 
 	// Start server
-	if err := transport.Start(); err != nil {
-		log.Fatalf("Server error: %v", err)
+	logger.Info("MCP Server (stdio) started")
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(context.Background(), transport)
+	}()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			logging.Fatal(logger, "Server error", "error", err)
+		}
+		// Serve has already waited for the requests read before EOF
+		logger.Info("EOF received, shutting down")
+	case <-ctx.Done():
+		logger.Info("Signal received, shutting down")
 	}
-}
 
-func registerTools(server *mcp.Server) {
-	// Calculator
-	server.RegisterTool(mcp.Tool{
-		Name:        "calculator",
-		Description: "Perform basic arithmetic operations (add, subtract, multiply, divide)",
-		InputSchema: tools.GetCalculatorSchema(),
-	}, tools.Calculator)
-
-	// Storage operations
-	server.RegisterTool(mcp.Tool{
-		Name:        "storage_set",
-		Description: "Store a key-value pair in memory",
-		InputSchema: tools.GetStorageSetSchema(),
-	}, tools.StorageSet)
-
-	server.RegisterTool(mcp.Tool{
-		Name:        "storage_get",
-		Description: "Retrieve a value by key from memory",
-		InputSchema: tools.GetStorageGetSchema(),
-	}, tools.StorageGet)
-
-	server.RegisterTool(mcp.Tool{
-		Name:        "storage_delete",
-		Description: "Delete a key-value pair from memory",
-		InputSchema: tools.GetStorageDeleteSchema(),
-	}, tools.StorageDelete)
-
-	server.RegisterTool(mcp.Tool{
-		Name:        "storage_list",
-		Description: "List all stored keys",
-		InputSchema: tools.GetStorageListSchema(),
-	}, tools.StorageList)
-
-	// System info
-	server.RegisterTool(mcp.Tool{
-		Name:        "system_info",
-		Description: "Get system information about the Go runtime",
-		InputSchema: tools.GetSystemInfoSchema(),
-	}, tools.SystemInfo)
-
-	// Echo
-	server.RegisterTool(mcp.Tool{
-		Name:        "echo",
-		Description: "Echo back a message (for testing)",
-		InputSchema: tools.GetEchoSchema(),
-	}, tools.Echo)
-
-	log.Println("Registered 7 tools")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownGracePeriod.Duration())
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Shutdown", "error", err)
+	}
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+// property describes one argument of a tool's input schema
+type property struct {
+	Name        string
+	Type        string
+	Description string
+	Enum        []string
+	Required    bool
+}
+
+// toolProperties returns the arguments declared in a tool's input schema,
+// required ones first
+func toolProperties(tool mcp.Tool) []property {
+	props, _ := tool.InputSchema["properties"].(map[string]interface{})
+
+	required := map[string]bool{}
+	switch list := tool.InputSchema["required"].(type) {
+	case []interface{}:
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	case []string:
+		for _, name := range list {
+			required[name] = true
+		}
+	}
+
+	result := make([]property, 0, len(props))
+	for name, raw := range props {
+		p := property{Name: name, Required: required[name]}
+		if schema, ok := raw.(map[string]interface{}); ok {
+			p.Type, _ = schema["type"].(string)
+			p.Description, _ = schema["description"].(string)
+			switch enum := schema["enum"].(type) {
+			case []interface{}:
+				for _, v := range enum {
+					p.Enum = append(p.Enum, fmt.Sprint(v))
+				}
+			case []string:
+				p.Enum = append(p.Enum, enum...)
+			}
+		}
+		result = append(result, p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Required != result[j].Required {
+			return result[i].Required
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// buildArguments merges a JSON object and key=value pairs into tool
+// arguments, converting each value to the type its schema declares
+func buildArguments(tool mcp.Tool, raw string, pairs []string) (map[string]interface{}, error) {
+	arguments := map[string]interface{}{}
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+			return nil, fmt.Errorf("invalid -args JSON: %w", err)
+		}
+	}
+
+	types := map[string]string{}
+	for _, p := range toolProperties(tool) {
+		types[p.Name] = p.Type
+	}
+
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid argument %q, expected key=value", pair)
+		}
+		converted, err := convertValue(value, types[key])
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", key, err)
+		}
+		arguments[key] = converted
+	}
+	return arguments, nil
+}
+
+// convertValue converts a command-line string to the given JSON schema type.
+// Without a known type, valid JSON is decoded and anything else is a string.
+func convertValue(value, schemaType string) (interface{}, error) {
+	switch schemaType {
+	case "string":
+		return value, nil
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return n, nil
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", value)
+		}
+		return b, nil
+	case "object", "array":
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, fmt.Errorf("%q is not valid JSON: %w", value, err)
+		}
+		return v, nil
+	default:
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v, nil
+		}
+		return value, nil
+	}
+}
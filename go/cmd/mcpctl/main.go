@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+const usage = `mcpctl - inspect and call MCP servers
+
+Usage:
+  mcpctl [flags] <command> [arguments]
+
+Connection flags:
+  -url URL           connect over HTTP (SSE or Streamable HTTP)
+  -transport NAME    auto, sse or http (default auto: "sse" if the URL path ends in /sse)
+  -api-key KEY       sent as "Authorization: Bearer KEY" (default $MCP_API_KEY)
+  -H "Name: value"   extra HTTP header, may be repeated
+  -stdio COMMAND     start a stdio server, e.g. -stdio ./bin/local-server
+  -stdio-arg ARG     argument for the stdio server, may be repeated
+  -framing NAME      stdio framing: newline or content-length (default newline)
+
+Output flags:
+  -json              print raw JSON results instead of formatted output
+  -timeout DURATION  timeout for each request (default 30s)
+
+Commands:
+  list-tools                          list the server's tools
+  call <tool> [-arg k=v ...] [-args JSON]
+                                      call a tool; values are converted using the input schema
+  list-resources                      list the server's resources
+  read <uri>                          read a resource
+  send [-notify] <method> [params]    send a raw JSON-RPC request or notification
+  repl                                start an interactive session (the default)
+`
+
+// errToolFailed reports a tool result with isError set; the result itself
+// has already been printed
+var errToolFailed = errors.New("tool returned an error")
+
+// stringList collects a repeatable string flag
+type stringList []string
+
+func (h *stringList) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *stringList) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+// options holds the global command-line flags
+type options struct {
+	url       string
+	transport string
+	apiKey    string
+	headers   stringList
+	stdio     string
+	stdioArgs stringList
+	framing   string
+	json      bool
+	timeout   time.Duration
+}
+
+func main() {
+	var opts options
+	flags := flag.NewFlagSet("mcpctl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.StringVar(&opts.url, "url", "", "")
+	flags.StringVar(&opts.transport, "transport", "auto", "")
+	flags.StringVar(&opts.apiKey, "api-key", os.Getenv("MCP_API_KEY"), "")
+	flags.Var(&opts.headers, "H", "")
+	flags.StringVar(&opts.stdio, "stdio", "", "")
+	flags.Var(&opts.stdioArgs, "stdio-arg", "")
+	flags.StringVar(&opts.framing, "framing", "newline", "")
+	flags.BoolVar(&opts.json, "json", false, "")
+	flags.DurationVar(&opts.timeout, "timeout", 30*time.Second, "")
+	flags.Parse(os.Args[1:])
+
+	dialer, err := newDialer(opts)
+	if err != nil {
+		fatal(err)
+	}
+
+	client := mcp.NewClient(dialer, mcp.ClientOptions{
+		ClientInfo: mcp.ClientInfo{Name: "mcpctl", Version: "1.0.0"},
+	})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	err = client.Connect(ctx)
+	cancel()
+	if err != nil {
+		fatal(fmt.Errorf("failed to connect: %w", err))
+	}
+
+	out := &printer{json: opts.json}
+	command, args := "repl", []string(nil)
+	if flags.NArg() > 0 {
+		command, args = flags.Arg(0), flags.Args()[1:]
+	}
+
+	if command == "repl" {
+		err = runREPL(client, out, opts.timeout)
+	} else {
+		err = runCommand(client, out, opts.timeout, command, args)
+	}
+	if err != nil {
+		client.Close()
+		if errors.Is(err, errToolFailed) {
+			os.Exit(1)
+		}
+		fatal(err)
+	}
+}
+
+// newDialer builds the dialer selected by the connection flags
+func newDialer(opts options) (mcp.Dialer, error) {
+	switch {
+	case opts.stdio != "" && opts.url != "":
+		return nil, errors.New("use either -stdio or -url, not both")
+	case len(opts.stdioArgs) > 0 && opts.stdio == "":
+		return nil, errors.New("-stdio-arg needs -stdio")
+	case opts.stdio != "":
+		// The command is run as given, not split, so paths may contain spaces
+		if strings.TrimSpace(opts.stdio) == "" {
+			return nil, errors.New("-stdio needs a command")
+		}
+		framing, err := mcp.ParseFraming(opts.framing)
+		if err != nil {
+			return nil, err
+		}
+		return mcp.CommandDialer(mcp.CommandSpec{
+			Command: opts.stdio,
+			Args:    opts.stdioArgs,
+			Framing: framing,
+		}), nil
+	case opts.url != "":
+		header := http.Header{}
+		if opts.apiKey != "" {
+			header.Set("Authorization", "Bearer "+opts.apiKey)
+		}
+		for _, h := range opts.headers {
+			name, value, ok := strings.Cut(h, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", h)
+			}
+			header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+		httpOptions := mcp.HTTPOptions{Header: header}
+
+		transport := opts.transport
+		if transport == "auto" {
+			transport = "http"
+			if u, err := url.Parse(opts.url); err == nil && strings.HasSuffix(u.Path, "/sse") {
+				transport = "sse"
+			}
+		}
+		switch transport {
+		case "sse":
+			return mcp.SSEDialer(opts.url, httpOptions), nil
+		case "http":
+			return mcp.StreamableHTTPDialer(opts.url, httpOptions), nil
+		default:
+			return nil, fmt.Errorf("unknown transport %q (expected auto, sse or http)", opts.transport)
+		}
+	default:
+		return nil, errors.New("no server given: use -stdio or -url (see mcpctl -h)")
+	}
+}
+
+// runCommand executes a single non-interactive command
+func runCommand(client *mcp.Client, out *printer, timeout time.Duration, command string, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch command {
+	case "list-tools":
+		tools, err := client.ListTools(ctx)
+		if err != nil {
+			return err
+		}
+		out.tools(tools)
+
+	case "call":
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			return errors.New("usage: call <tool> [-arg k=v ...] [-args JSON]")
+		}
+		name := args[0]
+
+		var pairs stringList
+		var raw string
+		flags := flag.NewFlagSet("call", flag.ContinueOnError)
+		flags.Var(&pairs, "arg", "tool argument as key=value, may be repeated")
+		flags.StringVar(&raw, "args", "", "tool arguments as a JSON object")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		pairs = append(pairs, flags.Args()...)
+
+		tool, err := findTool(ctx, client, name)
+		if err != nil {
+			return err
+		}
+		arguments, err := buildArguments(tool, raw, pairs)
+		if err != nil {
+			return err
+		}
+
+		result, err := client.CallTool(ctx, name, arguments)
+		if err != nil {
+			return err
+		}
+		out.toolResult(result)
+		if result.IsError {
+			return errToolFailed
+		}
+
+	case "list-resources":
+		resources, err := client.ListResources(ctx)
+		if err != nil {
+			return err
+		}
+		out.resources(resources)
+
+	case "read":
+		if len(args) != 1 {
+			return errors.New("usage: read <uri>")
+		}
+		result, err := client.ReadResource(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		out.resourceContents(result)
+
+	case "send":
+		flags := flag.NewFlagSet("send", flag.ContinueOnError)
+		notify := flags.Bool("notify", false, "send a notification instead of a request")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() == 0 || flags.NArg() > 2 {
+			return errors.New("usage: send [-notify] <method> [params JSON]")
+		}
+		return send(ctx, client, out, *notify, flags.Arg(0), flags.Arg(1))
+
+	default:
+		return fmt.Errorf("unknown command %q (see mcpctl -h)", command)
+	}
+	return nil
+}
+
+// send issues a raw JSON-RPC request or notification and prints the result
+func send(ctx context.Context, client *mcp.Client, out *printer, notify bool, method, params string) error {
+	var rawParams interface{}
+	if params != "" {
+		if !json.Valid([]byte(params)) {
+			return fmt.Errorf("params are not valid JSON: %s", params)
+		}
+		rawParams = json.RawMessage(params)
+	}
+
+	if notify {
+		return client.Notify(ctx, method, rawParams)
+	}
+
+	var result json.RawMessage
+	if err := client.Call(ctx, method, rawParams, &result); err != nil {
+		return err
+	}
+	out.raw(result)
+	return nil
+}
+
+// findTool looks up a tool's definition so its schema can drive argument parsing
+func findTool(ctx context.Context, client *mcp.Client, name string) (mcp.Tool, error) {
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return mcp.Tool{}, err
+	}
+	for _, tool := range tools {
+		if tool.Name == name {
+			return tool, nil
+		}
+	}
+	return mcp.Tool{}, fmt.Errorf("unknown tool %q", name)
+}
+
+// fatal prints err and exits
+func fatal(err error) {
+	var rpcErr *mcp.JSONRPCError
+	if errors.As(err, &rpcErr) {
+		fmt.Fprintf(os.Stderr, "mcpctl: server error %d: %s\n", rpcErr.Code, rpcErr.Message)
+	} else {
+		fmt.Fprintf(os.Stderr, "mcpctl: %v\n", err)
+	}
+	os.Exit(1)
+}
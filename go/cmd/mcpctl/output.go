@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+// printer writes results either formatted for humans or as indented JSON
+type printer struct {
+	json bool
+	out  io.Writer
+}
+
+// writer returns the output destination, stdout by default
+func (p *printer) writer() io.Writer {
+	if p.out != nil {
+		return p.out
+	}
+	return os.Stdout
+}
+
+// printJSON writes v as indented JSON
+func (p *printer) printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mcpctl: %v\n", err)
+		return
+	}
+	fmt.Fprintln(p.writer(), string(data))
+}
+
+// tools prints a tool list with each tool's arguments
+func (p *printer) tools(tools []mcp.Tool) {
+	if p.json {
+		p.printJSON(tools)
+		return
+	}
+
+	w := p.writer()
+	for _, tool := range tools {
+		fmt.Fprintf(w, "%s\n", tool.Name)
+		if tool.Description != "" {
+			fmt.Fprintf(w, "    %s\n", tool.Description)
+		}
+		for _, prop := range toolProperties(tool) {
+			fmt.Fprintf(w, "    - %s %s", prop.Name, describeType(prop))
+			if prop.Description != "" {
+				fmt.Fprintf(w, ": %s", prop.Description)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(tools) == 0 {
+		fmt.Fprintln(w, "(no tools)")
+	}
+}
+
+// toolResult prints the content of a tool call; errors are marked as such
+func (p *printer) toolResult(result *mcp.CallToolResult) {
+	if p.json {
+		p.printJSON(result)
+		return
+	}
+
+	w := p.writer()
+	if result.IsError {
+		fmt.Fprint(w, "error: ")
+	}
+	for _, content := range result.Content {
+		switch content.Type {
+		case "text":
+			fmt.Fprintln(w, content.Text)
+		default:
+			fmt.Fprintf(w, "[%s %s, %d bytes base64]\n", content.Type, content.MimeType, len(content.Data))
+		}
+	}
+}
+
+// resources prints a resource list
+func (p *printer) resources(resources []mcp.Resource) {
+	if p.json {
+		p.printJSON(resources)
+		return
+	}
+
+	w := p.writer()
+	for _, resource := range resources {
+		fmt.Fprintf(w, "%s  %s", resource.URI, resource.Name)
+		if resource.MimeType != "" {
+			fmt.Fprintf(w, " (%s)", resource.MimeType)
+		}
+		fmt.Fprintln(w)
+		if resource.Description != "" {
+			fmt.Fprintf(w, "    %s\n", resource.Description)
+		}
+	}
+	if len(resources) == 0 {
+		fmt.Fprintln(w, "(no resources)")
+	}
+}
+
+// resourceContents prints the contents of a read resource
+func (p *printer) resourceContents(result *mcp.ReadResourceResult) {
+	if p.json {
+		p.printJSON(result)
+		return
+	}
+
+	w := p.writer()
+	for _, contents := range result.Contents {
+		if len(result.Contents) > 1 {
+			fmt.Fprintf(w, "--- %s\n", contents.URI)
+		}
+		if contents.Blob != "" {
+			fmt.Fprintf(w, "[blob %s, %d bytes base64]\n", contents.MimeType, len(contents.Blob))
+			continue
+		}
+		fmt.Fprint(w, contents.Text)
+		if !strings.HasSuffix(contents.Text, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// raw prints an undecoded JSON-RPC result; it is always JSON
+func (p *printer) raw(result json.RawMessage) {
+	var v interface{}
+	if err := json.Unmarshal(result, &v); err != nil {
+		fmt.Fprintln(p.writer(), string(result))
+		return
+	}
+	p.printJSON(v)
+}
+
+// notification prints a notification received from the server
+func (p *printer) notification(method string, params json.RawMessage) {
+	if p.json {
+		p.printJSON(map[string]interface{}{"method": method, "params": params})
+		return
+	}
+	if len(params) == 0 {
+		fmt.Fprintf(p.writer(), "<- %s\n", method)
+		return
+	}
+	fmt.Fprintf(p.writer(), "<- %s %s\n", method, params)
+}
+
+// describeType renders a property's type for help output, e.g. "(number, required)"
+func describeType(prop property) string {
+	parts := []string{}
+	if prop.Type != "" {
+		parts = append(parts, prop.Type)
+	}
+	if len(prop.Enum) > 0 {
+		parts = append(parts, "one of "+strings.Join(prop.Enum, "|"))
+	}
+	if prop.Required {
+		parts = append(parts, "required")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
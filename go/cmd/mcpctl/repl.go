@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chzyer/readline"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+const replHelp = `Commands:
+  tools                      list tools
+  call <tool> [k=v ...]      call a tool; without arguments, prompts for each one
+  resources                  list resources
+  read <uri>                 read a resource
+  send <method> [params]     send a raw JSON-RPC request
+  notify <method> [params]   send a raw JSON-RPC notification
+  ping                       ping the server
+  json on|off                toggle JSON output
+  help                       show this help
+  exit                       quit
+Values containing spaces can be quoted: call echo message="hello world"`
+
+// replCommands are the REPL commands offered by tab completion
+var replCommands = []string{"tools", "call", "resources", "read", "send", "notify", "ping", "json", "help", "exit"}
+
+// repl is an interactive session with a connected server
+type repl struct {
+	client  *mcp.Client
+	out     *printer
+	timeout time.Duration
+	rl      *readline.Instance
+
+	mu        sync.Mutex
+	tools     []mcp.Tool
+	resources []mcp.Resource
+	prompting *property
+}
+
+// runREPL reads and executes commands until EOF or exit
+func runREPL(client *mcp.Client, out *printer, timeout time.Duration) error {
+	r := &repl{client: client, out: out, timeout: timeout}
+
+	config := &readline.Config{
+		Prompt:          "mcp> ",
+		AutoComplete:    r,
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		config.HistoryFile = filepath.Join(home, ".mcpctl_history")
+	}
+
+	rl, err := readline.NewEx(config)
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+	r.rl = rl
+	out.out = rl.Stdout()
+
+	client.OnNotification("*", func(method string, params json.RawMessage) {
+		out.notification(method, params)
+		// Refresh asynchronously: notifications are dispatched by the read loop
+		switch method {
+		case "notifications/tools/list_changed":
+			go r.refreshTools()
+		case "notifications/resources/list_changed":
+			go r.refreshResources()
+		}
+	})
+	r.refreshTools()
+	r.refreshResources()
+
+	info := client.ServerInfo()
+	fmt.Fprintf(rl.Stdout(), "Connected to %s %s (protocol %s). Type \"help\" for commands.\n",
+		info.ServerInfo.Name, info.ServerInfo.Version, info.ProtocolVersion)
+
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "exit" || line == "quit" {
+			return nil
+		}
+		if err := r.execute(line); err != nil {
+			var rpcErr *mcp.JSONRPCError
+			if errors.As(err, &rpcErr) {
+				fmt.Fprintf(rl.Stderr(), "server error %d: %s\n", rpcErr.Code, rpcErr.Message)
+			} else if !errors.Is(err, errToolFailed) {
+				fmt.Fprintf(rl.Stderr(), "error: %v\n", err)
+			}
+		}
+	}
+}
+
+// execute runs a single REPL command line
+func (r *repl) execute(line string) error {
+	command, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch command {
+	case "help":
+		fmt.Fprintln(r.rl.Stdout(), replHelp)
+		return nil
+	case "json":
+		switch rest {
+		case "on":
+			r.out.json = true
+		case "off":
+			r.out.json = false
+		default:
+			return errors.New("usage: json on|off")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	switch command {
+	case "tools":
+		tools, err := r.client.ListTools(ctx)
+		if err != nil {
+			return err
+		}
+		r.setTools(tools)
+		r.out.tools(tools)
+
+	case "call":
+		words, err := splitWords(rest)
+		if err != nil {
+			return err
+		}
+		if len(words) == 0 {
+			return errors.New("usage: call <tool> [k=v ...]")
+		}
+		tool, ok := r.findTool(words[0])
+		if !ok {
+			return fmt.Errorf("unknown tool %q", words[0])
+		}
+
+		pairs := words[1:]
+		if len(pairs) == 0 {
+			if pairs, err = r.promptArguments(tool); err != nil {
+				return err
+			}
+		}
+		arguments, err := buildArguments(tool, "", pairs)
+		if err != nil {
+			return err
+		}
+
+		// Prompting may have taken a while, so the call gets a fresh timeout
+		callCtx, callCancel := context.WithTimeout(context.Background(), r.timeout)
+		defer callCancel()
+		result, err := r.client.CallTool(callCtx, tool.Name, arguments)
+		if err != nil {
+			return err
+		}
+		r.out.toolResult(result)
+		if result.IsError {
+			return errToolFailed
+		}
+
+	case "resources":
+		resources, err := r.client.ListResources(ctx)
+		if err != nil {
+			return err
+		}
+		r.setResources(resources)
+		r.out.resources(resources)
+
+	case "read":
+		if rest == "" {
+			return errors.New("usage: read <uri>")
+		}
+		result, err := r.client.ReadResource(ctx, rest)
+		if err != nil {
+			return err
+		}
+		r.out.resourceContents(result)
+
+	case "send", "notify":
+		method, params, _ := strings.Cut(rest, " ")
+		if method == "" {
+			return fmt.Errorf("usage: %s <method> [params JSON]", command)
+		}
+		return send(ctx, r.client, r.out, command == "notify", method, strings.TrimSpace(params))
+
+	case "ping":
+		start := time.Now()
+		if err := r.client.Ping(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintf(r.rl.Stdout(), "pong in %s\n", time.Since(start).Round(time.Millisecond))
+
+	default:
+		return fmt.Errorf("unknown command %q, type \"help\" for commands", command)
+	}
+	return nil
+}
+
+// promptArguments asks for each argument declared in the tool's schema.
+// Optional arguments may be left empty; Ctrl-C aborts the call.
+func (r *repl) promptArguments(tool mcp.Tool) ([]string, error) {
+	props := toolProperties(tool)
+	if len(props) == 0 {
+		return nil, nil
+	}
+
+	r.rl.HistoryDisable()
+	defer func() {
+		r.rl.HistoryEnable()
+		r.rl.SetPrompt("mcp> ")
+		r.setPrompting(nil)
+	}()
+
+	var pairs []string
+	for i := range props {
+		prop := props[i]
+		if prop.Description != "" {
+			fmt.Fprintf(r.rl.Stdout(), "  %s: %s\n", prop.Name, prop.Description)
+		}
+		r.setPrompting(&prop)
+		r.rl.SetPrompt(fmt.Sprintf("  %s %s: ", prop.Name, describeType(prop)))
+
+		for {
+			value, err := r.rl.Readline()
+			if errors.Is(err, readline.ErrInterrupt) || err == io.EOF {
+				return nil, errors.New("call aborted")
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			value = strings.TrimSpace(value)
+			if value == "" {
+				if prop.Required {
+					continue
+				}
+				break
+			}
+			if _, err := convertValue(value, prop.Type); err != nil {
+				fmt.Fprintf(r.rl.Stderr(), "  %v\n", err)
+				continue
+			}
+			pairs = append(pairs, prop.Name+"="+value)
+			break
+		}
+	}
+	return pairs, nil
+}
+
+// Do implements readline.AutoCompleter. It completes command names, tool
+// names, argument keys from the tool's schema, enum values and resource URIs.
+func (r *repl) Do(line []rune, pos int) ([][]rune, int) {
+	text := string(line[:pos])
+
+	r.mu.Lock()
+	prompting := r.prompting
+	r.mu.Unlock()
+	if prompting != nil {
+		return complete(text, prompting.Enum, " ")
+	}
+
+	words := strings.Fields(text)
+	current := ""
+	if len(words) > 0 && !strings.HasSuffix(text, " ") {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	if len(words) == 0 {
+		return complete(current, replCommands, " ")
+	}
+
+	switch words[0] {
+	case "call":
+		if len(words) == 1 {
+			return complete(current, r.toolNames(), " ")
+		}
+		tool, ok := r.findTool(words[1])
+		if !ok {
+			return nil, 0
+		}
+		props := toolProperties(tool)
+
+		// After "key=", complete the property's enum values
+		if key, value, ok := strings.Cut(current, "="); ok {
+			for _, prop := range props {
+				if prop.Name == key {
+					return complete(value, prop.Enum, " ")
+				}
+			}
+			return nil, 0
+		}
+
+		given := map[string]bool{}
+		for _, word := range words[2:] {
+			key, _, _ := strings.Cut(word, "=")
+			given[key] = true
+		}
+		var keys []string
+		for _, prop := range props {
+			if !given[prop.Name] {
+				keys = append(keys, prop.Name)
+			}
+		}
+		return complete(current, keys, "=")
+
+	case "read":
+		if len(words) == 1 {
+			return complete(current, r.resourceURIs(), "")
+		}
+	case "json":
+		if len(words) == 1 {
+			return complete(current, []string{"on", "off"}, "")
+		}
+	}
+	return nil, 0
+}
+
+// complete returns the remainder of every candidate starting with prefix
+func complete(prefix string, candidates []string, suffix string) ([][]rune, int) {
+	var matches [][]rune
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, []rune(candidate[len(prefix):]+suffix))
+		}
+	}
+	return matches, len([]rune(prefix))
+}
+
+// refreshTools reloads the cached tool list used for completion
+func (r *repl) refreshTools() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if tools, err := r.client.ListTools(ctx); err == nil {
+		r.setTools(tools)
+	}
+}
+
+// refreshResources reloads the cached resource list; servers without
+// resources simply leave it empty
+func (r *repl) refreshResources() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	if resources, err := r.client.ListResources(ctx); err == nil {
+		r.setResources(resources)
+	}
+}
+
+func (r *repl) setTools(tools []mcp.Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools = tools
+}
+
+func (r *repl) setResources(resources []mcp.Resource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resources = resources
+}
+
+func (r *repl) setPrompting(prop *property) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prompting = prop
+}
+
+// findTool looks up a tool in the cache
+func (r *repl) findTool(name string) (mcp.Tool, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tool := range r.tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return mcp.Tool{}, false
+}
+
+// toolNames returns the cached tool names, sorted
+func (r *repl) toolNames() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.tools))
+	for _, tool := range r.tools {
+		names = append(names, tool.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resourceURIs returns the cached resource URIs
+func (r *repl) resourceURIs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	uris := make([]string, 0, len(r.resources))
+	for _, resource := range r.resources {
+		uris = append(uris, resource.URI)
+	}
+	return uris
+}
+
+// splitWords splits a command line on spaces, honouring single and double
+// quotes and backslash escapes
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, c := range line {
+		switch {
+		case escaped:
+			word.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -1,238 +1,676 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
-	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/admin"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/app"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/audit"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/config"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/gateway"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/health"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/listener"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
 	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/middleware"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/oauth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/telemetry"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/tlsconfig"
 	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/tools"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/usage"
 )
 
-var (
-	apiKey string
-	port   string
-)
+// logger is the logger of the "server" component
+var logger = logging.Component("server")
 
 func main() {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found")
+		logger.Info("No .env file found")
+	}
+
+	configPath := flag.String("config", os.Getenv("MCP_CONFIG"), "YAML configuration file (env MCP_CONFIG)")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	generateKey := flag.Bool("generate-key", false, "print a new API key and its hash for auth.keys, then exit")
+	hashKey := flag.Bool("hash-key", false, "read an API key from stdin, print its hash for auth.keys, then exit")
+	hashPassword := flag.Bool("hash-password", false, "read a password from stdin, print its hash for auth.authorization_server.users, then exit")
+	overrides := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *generateKey || *hashKey {
+		printKey(*generateKey)
+		return
+	}
+	if *hashPassword {
+		printPasswordHash()
+		return
+	}
+	if flag.Arg(0) == "verify" {
+		verifyAudit(*configPath, overrides, flag.Args()[1:])
+		return
+	}
+
+	cfg, err := config.Load(*configPath, overrides)
+	if err != nil {
+		logging.Fatal(logger, "Invalid configuration", "error", err)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logging.Fatal(logger, "Failed to print configuration", "error", err)
+		}
+		return
 	}
 
-	// Get configuration from environment
-	apiKey = os.Getenv("MCP_API_KEY")
-	if apiKey == "" {
-		log.Fatal("MCP_API_KEY environment variable is required")
+	if err := checkConfig(cfg); err != nil {
+		logging.Fatal(logger, "Invalid configuration", "error", err)
 	}
 
-	port = os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Everything, Gin included, logs to one stream in the configured format
+	if err := logging.Setup(cfg.Logging.Options(os.Stderr)); err != nil {
+		logging.Fatal(logger, "Invalid logging configuration", "error", err)
+	}
+	httpLogger := logging.Component("http")
+	gin.DefaultWriter = logging.Writer(httpLogger, slog.LevelDebug)
+	gin.DefaultErrorWriter = logging.Writer(httpLogger, slog.LevelError)
+
+	var tlsReloader *tlsconfig.Reloader
+	if cfg.TLS.Enabled() {
+		if tlsReloader, err = tlsconfig.New(cfg.TLS.Options()); err != nil {
+			logging.Fatal(logger, "Invalid TLS configuration", "error", err)
+		}
 	}
 
+	// The key store is rebuilt whenever the config is reloaded
+	var keys atomic.Pointer[auth.KeyStore]
+	keyStore, _ := cfg.Auth.KeyStore() // checked by config.Load
+	keys.Store(keyStore)
+
 	// Create MCP server
 	server := mcp.NewServer()
 
+	// Callers only see and call the tools their key or certificate allows.
+	// The read-only hint of gateway tools comes from the upstream, so they
+	// always need tools:call.
+	var gw *gateway.Gateway
+	server.SetToolFilter(func(ctx context.Context, tool mcp.Tool) bool {
+		identity := auth.IdentityFromContext(ctx)
+		readOnly := tool.ReadOnly() && (gw == nil || !gw.Owns(tool.Name))
+		return identity == nil || identity.CanCallTool(tool.Name, readOnly)
+	})
+
+	// The storage tools keep their data in the configured backend
+	store, err := app.OpenStorage(server, cfg)
+	if err != nil {
+		logging.Fatal(logger, "Invalid storage configuration", "error", err)
+	}
+	storageTools := tools.NewStorageTools(store)
+
 	// Register tools
-	registerTools(server)
+	count, err := tools.Register(server, storageTools, cfg.Tools.Enabled)
+	if err != nil {
+		logging.Fatal(logger, "Failed to register tools", "error", err)
+	}
+	logger.Info("Registered tools", "count", count)
+
+	// Proxy the tools of upstream servers, if configured
+	if gw, err = app.StartGateway(server, cfg.Gateway.Config); err != nil {
+		logging.Fatal(logger, "Invalid gateway.config", "error", err)
+	}
+
+	// Reload the config file on SIGHUP or when it changes. API keys, rate
+	// limits, CORS, log levels and the tool set apply without dropping
+	// sessions.
+	reloader := config.NewReloader(*configPath, overrides, cfg, checkConfig)
+	reloader.OnReload(func(previous, current *config.Config) {
+		if keyStore, err := current.Auth.KeyStore(); err == nil {
+			keys.Store(keyStore)
+			closeRevokedSessions(server, keyStore)
+		}
+		logging.SetLevels(current.Logging.Level, current.Logging.Levels)
+		if err := tools.Update(server, storageTools, previous.Tools.Enabled, current.Tools.Enabled); err != nil {
+			logger.Error("Failed to update tools", "error", err)
+		}
+	})
+
+	// Tool calls are audited before rate limits and quotas, so calls they
+	// reject are recorded too
+	auditLog, err := app.StartAudit(server, cfg, func() []string { return reloader.Current().Audit.Redact })
+	if err != nil {
+		logging.Fatal(logger, "Invalid audit.file", "error", err)
+	}
+
+	// Rate limits are token buckets per caller: HTTP requests, and calls of
+	// each tool at the JSON-RPC layer
+	limiter := ratelimit.New()
+	server.UseToolMiddleware(ratelimit.Tools(limiter, func() map[string]int { return reloader.Current().RateLimit.Tools }))
+
+	// Usage is counted per caller and tool at the JSON-RPC layer, after
+	// rate limiting, and saved on shutdown
+	tracker, err := app.NewUsageTracker(server, cfg, func() map[string]usage.Quota { return reloader.Current().Usage.Quotas })
+	if err != nil {
+		logging.Fatal(logger, "Invalid usage.file", "error", err)
+	}
 
-	// Create Gin router
-	router := gin.Default()
+	// Spans of JSON-RPC requests and tool calls, flushed on shutdown
+	stopTracing, err := telemetry.Setup(context.Background(), cfg.Tracing.Options(os.Stdout))
+	if err != nil {
+		logging.Fatal(logger, "Invalid tracing configuration", "error", err)
+	}
+	server.OnShutdown(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := stopTracing(ctx); err != nil {
+			logger.Warn("Tracing shutdown", "error", err)
+		}
+	})
+
+	// Readiness fails until the server is serving, while a critical check
+	// fails and once shutdown begins; upstreams being down only degrade it
+	checks := health.New(health.Options{Timeout: cfg.Server.HealthCheckTimeout.Duration()})
+	checks.Register("storage", storageTools)
+	if auditLog != nil {
+		checks.Register("audit", auditLog)
+	}
+	if gw != nil {
+		checks.RegisterOptional("gateway", gw)
+	}
+
+	listenAddr := cfg.Server.ListenAddr()
+	ln, err := listener.Listen(listenAddr, cfg.Server.SocketOptions())
+	if err != nil {
+		logging.Fatal(logger, "Failed to listen", "addr", listenAddr, "error", err)
+	}
+
+	// Stop on SIGINT/SIGTERM, draining in-flight requests for up to the grace period
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	gracePeriod := cfg.Server.ShutdownGracePeriod.Duration()
+
+	go reloader.Watch(ctx, cfg.Server.ConfigReloadInterval.Duration())
+	go reloadOnHangup(ctx, reloader)
+	go tracker.Run(ctx, cfg.Usage.FlushInterval.Duration())
+
+	if cfg.Server.Mode == "stream" {
+		// A systemd socket is only known to be TCP now
+		if listener.IsTCP(ln) {
+			logging.Fatal(logger, "Stream mode requires a Unix socket", "addr", ln.Addr().String())
+		}
+		transport := mcp.NewListenerTransport(ln)
+		go func() {
+			<-ctx.Done()
+			transport.Close()
+		}()
+
+		logger.Info("Starting Go MCP Server (stream)", "addr", ln.Addr().String())
+		served := make(chan error, 1)
+		go func() {
+			served <- server.Serve(context.Background(), transport)
+		}()
+
+		select {
+		case err := <-served:
+			if err != nil {
+				logging.Fatal(logger, "Server error", "error", err)
+			}
+		case <-ctx.Done():
+			shutdown(server, nil, gracePeriod)
+		}
+		return
+	}
+
+	// SSE session handling
+	sseOptions := cfg.SSE.Options()
+	sseOptions.AuthorizeMessage = func(stream, message context.Context) bool {
+		return auth.SameCaller(auth.IdentityFromContext(stream), auth.IdentityFromContext(message))
+	}
+	sseHandler := mcp.NewSSEHandler(server, sseOptions)
+
+	// WebSocket handling, mirroring CORS: any origin is accepted unless restricted
+	corsOrigin := func() string { return reloader.Current().CORS.Origin }
+	wsOptions := cfg.WebSocket.Options()
+	wsOptions.CheckOrigin = middleware.CheckOriginFunc(corsOrigin)
+	wsHandler := mcp.NewWebSocketHandler(server, wsOptions)
+
+	// API keys, plus OAuth access tokens when an issuer is configured
+	authOptions := middleware.AuthOptions{Keys: keys.Load}
+	var authServer *oauth.AuthorizationServer
+	if oauthConfig := &cfg.Auth.OAuth; oauthConfig.Enabled() {
+		// Tokens come from the built-in authorization server or an external one
+		var keySet *oauth.KeySet
+		if asConfig := &cfg.Auth.AuthorizationServer; asConfig.Enabled {
+			authServer, keySet = newAuthorizationServer(asConfig, oauthConfig.Audience)
+		} else {
+			keySet = loadKeySet(ctx, oauthConfig)
+		}
+		logger.Info("Accepting OAuth access tokens", "issuer", oauthConfig.Issuer, "audience", oauthConfig.Audience)
+		authOptions.Tokens = oauth.NewValidator(oauthConfig.ValidatorOptions(keySet))
+		authOptions.ResourceMetadata = oauth.MetadataURL(oauthConfig.Resource)
+	}
+	authenticator := middleware.NewAuthenticator(authOptions)
+	authMiddleware := authenticator.Require()
+
+	// Create Gin router; requests are logged with an id that also tags
+	// the records logged while handling them
+	router := gin.New()
+	router.Use(middleware.RequestLog(), middleware.Recovery())
 
 	// CORS middleware
-	router.Use(corsMiddleware())
+	router.Use(middleware.CORSFunc(corsOrigin))
 
-	// Rate limiting middleware
-	router.Use(rateLimitMiddleware())
+	// Verified client certificates authenticate on their own
+	if tlsReloader != nil {
+		router.Use(middleware.ClientCert(clientIdentities(cfg.TLS.ClientIdentities)))
+	}
+
+	// Identify callers first so each gets their own rate limit; routes
+	// that need authentication reject anonymous callers afterwards
+	router.Use(authenticator.Identify())
+
+	// Rate limiting middleware (default: 100 requests per minute per caller)
+	router.Use(middleware.RateLimitWith(limiter, func() ratelimit.Limit { return reloader.Current().RateLimit.Limit() }))
+
+	// Clients discover the authorization server here
+	if oauthConfig := &cfg.Auth.OAuth; oauthConfig.Enabled() {
+		metadata := gin.WrapF(oauthConfig.Metadata().Handler())
+		router.GET(oauth.MetadataPath, metadata)
+		if path := oauth.MetadataPathFor(oauthConfig.Resource); path != oauth.MetadataPath {
+			router.GET(path, metadata)
+		}
+	}
+	if authServer != nil {
+		router.GET(oauth.ServerMetadataPath, gin.WrapF(authServer.HandleMetadata))
+		router.GET(oauth.JWKSPath, gin.WrapF(authServer.HandleJWKS))
+		// Failed sign-ins are throttled by the client IP gin resolves
+		authorize := func(c *gin.Context) {
+			authServer.HandleAuthorize(c.Writer, c.Request.WithContext(oauth.WithClientIP(c.Request.Context(), c.ClientIP())))
+		}
+		router.GET(oauth.AuthorizePath, authorize)
+		router.POST(oauth.AuthorizePath, authorize)
+		router.POST(oauth.TokenPath, gin.WrapF(authServer.HandleToken))
+		// Registration is open, so it has a tighter limit of its own
+		registerLimit := ratelimit.Limit{PerMinute: cfg.Auth.AuthorizationServer.RegisterRateLimit}
+		router.POST(oauth.RegisterPath,
+			middleware.RateLimitWith(ratelimit.New(), func() ratelimit.Limit { return registerLimit }),
+			gin.WrapF(authServer.HandleRegister))
+	}
 
-	// Health check
+	// Liveness and readiness probes
+	router.GET("/livez", gin.WrapF(checks.LivenessHandler()))
+	router.GET("/readyz", gin.WrapF(checks.ReadinessHandler()))
+
+	// Health check, kept for existing monitors; it reports like /readyz
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "ok",
+		report := checks.Report(c.Request.Context())
+		status := gin.H{
+			"status": report.Status,
 			"server": "go-mcp-server",
-		})
+			"checks": report.Checks,
+		}
+		if gw != nil {
+			status["upstreams"] = gw.Status()
+		}
+		c.Header("Cache-Control", "no-store")
+		c.JSON(report.HTTPStatus(), status)
 	})
 
 	// SSE endpoint
-	router.GET("/sse", authMiddleware(), func(c *gin.Context) {
-		if err := mcp.HandleSSERequest(server, c.Writer, c.Request, "/message"); err != nil {
-			log.Printf("SSE error: %v", err)
+	router.GET("/sse", authMiddleware, func(c *gin.Context) {
+		if err := sseHandler.HandleSSE(c.Writer, c.Request, "/message"); err != nil {
+			logger.WarnContext(c.Request.Context(), "SSE error", "error", err)
 		}
 	})
 
 	// Message endpoint
-	router.POST("/message", authMiddleware(), func(c *gin.Context) {
-		if err := mcp.HandleMessagePost(server, c.Writer, c.Request); err != nil {
-			log.Printf("Message error: %v", err)
+	router.POST("/message", authMiddleware, func(c *gin.Context) {
+		if err := sseHandler.HandleMessage(c.Writer, c.Request); err != nil {
+			logger.WarnContext(c.Request.Context(), "Message error", "error", err)
 		}
 	})
 
-	// Start server
-	addr := fmt.Sprintf(":%s", port)
-	log.Printf("Starting Go MCP Server on %s", addr)
-	log.Printf("SSE endpoint: http://localhost:%s/sse", port)
-	log.Printf("Message endpoint: http://localhost:%s/message", port)
+	// WebSocket endpoint
+	router.GET("/ws", authMiddleware, func(c *gin.Context) {
+		if err := wsHandler.HandleWebSocket(c.Writer, c.Request); err != nil {
+			logger.WarnContext(c.Request.Context(), "WebSocket error", "error", err)
+		}
+	})
 
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("Server error: %v", err)
+	// Admin API and usage report (JSON or CSV) for keys with the admin scope
+	if cfg.Admin.Enabled {
+		adminAPI := admin.New(admin.Options{
+			Server:  server,
+			Limiter: limiter,
+			Config:  reloader.Current,
+			Usage:   tracker.Handler(),
+		})
+		router.Any(admin.Prefix+"/*path", authMiddleware, middleware.RequireScope(auth.ScopeAdmin), gin.WrapH(adminAPI))
+	} else {
+		router.GET(usage.ReportPath, authMiddleware, middleware.RequireScope(auth.ScopeAdmin), gin.WrapF(tracker.Handler()))
 	}
-}
-
-func registerTools(server *mcp.Server) {
-	// Calculator
-	server.RegisterTool(mcp.Tool{
-		Name:        "calculator",
-		Description: "Perform basic arithmetic operations (add, subtract, multiply, divide)",
-		InputSchema: tools.GetCalculatorSchema(),
-	}, tools.Calculator)
-
-	// Storage operations
-	server.RegisterTool(mcp.Tool{
-		Name:        "storage_set",
-		Description: "Store a key-value pair in memory",
-		InputSchema: tools.GetStorageSetSchema(),
-	}, tools.StorageSet)
-
-	server.RegisterTool(mcp.Tool{
-		Name:        "storage_get",
-		Description: "Retrieve a value by key from memory",
-		InputSchema: tools.GetStorageGetSchema(),
-	}, tools.StorageGet)
-
-	server.RegisterTool(mcp.Tool{
-		Name:        "storage_delete",
-		Description: "Delete a key-value pair from memory",
-		InputSchema: tools.GetStorageDeleteSchema(),
-	}, tools.StorageDelete)
-
-	server.RegisterTool(mcp.Tool{
-		Name:        "storage_list",
-		Description: "List all stored keys",
-		InputSchema: tools.GetStorageListSchema(),
-	}, tools.StorageList)
-
-	// System info
-	server.RegisterTool(mcp.Tool{
-		Name:        "system_info",
-		Description: "Get system information about the Go runtime",
-		InputSchema: tools.GetSystemInfoSchema(),
-	}, tools.SystemInfo)
-
-	// Echo
-	server.RegisterTool(mcp.Tool{
-		Name:        "echo",
-		Description: "Echo back a message (for testing)",
-		InputSchema: tools.GetEchoSchema(),
-	}, tools.Echo)
-
-	log.Println("Registered 7 tools")
-}
 
-// authMiddleware validates the API key
-func authMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		auth := c.GetHeader("Authorization")
-		if auth == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing Authorization header"})
-			c.Abort()
-			return
+	// Prometheus metrics
+	if cfg.Metrics.Enabled {
+		metrics := gin.WrapH(promhttp.HandlerFor(newMetricsRegistry(server, storageTools), promhttp.HandlerOpts{}))
+		if cfg.Metrics.RequireAuth {
+			router.GET("/metrics", authMiddleware, middleware.RequireScope(auth.ScopeAdmin), metrics)
+		} else {
+			router.GET("/metrics", metrics)
 		}
+	}
 
-		// Check for Bearer token
-		if !strings.HasPrefix(auth, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-			c.Abort()
-			return
-		}
+	// Start server
+	scheme, wsScheme := "http", "ws"
+	if tlsReloader != nil {
+		scheme, wsScheme = "https", "wss"
+	}
+	logger.Info("Starting Go MCP Server", "addr", ln.Addr().String())
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+		logger.Info("Endpoints",
+			"sse", fmt.Sprintf("%s://localhost:%d/sse", scheme, addr.Port),
+			"message", fmt.Sprintf("%s://localhost:%d/message", scheme, addr.Port),
+			"websocket", fmt.Sprintf("%s://localhost:%d/ws", wsScheme, addr.Port))
+	}
+
+	httpServer := &http.Server{
+		Handler:           router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout.Duration(),
+		// SSE streams extend their own write deadline with every event
+		WriteTimeout: cfg.Server.WriteTimeout.Duration(),
+		IdleTimeout:  cfg.Server.IdleTimeout.Duration(),
+	}
 
-		token := strings.TrimPrefix(auth, "Bearer ")
-		if token != apiKey {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
-			c.Abort()
+	served := make(chan error, 1)
+	go func() {
+		if tlsReloader == nil {
+			served <- httpServer.Serve(ln)
 			return
 		}
-
-		c.Next()
+		httpServer.TLSConfig = tlsReloader.Config()
+		go tlsReloader.Watch(ctx)
+		served <- httpServer.ServeTLS(ln, "", "")
+	}()
+	checks.SetReady(true, "")
+
+	select {
+	case err := <-served:
+		logging.Fatal(logger, "Server error", "error", err)
+	case <-ctx.Done():
+		// Fail readiness first so load balancers stop sending traffic
+		checks.SetReady(false, "shutting down")
+		if delay := cfg.Server.ShutdownDelay.Duration(); delay > 0 {
+			logger.Info("Waiting before shutdown", "delay", delay.String())
+			time.Sleep(delay)
+		}
+		shutdown(server, httpServer, gracePeriod)
 	}
 }
 
-// corsMiddleware adds CORS headers
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		corsOrigin := os.Getenv("CORS_ORIGIN")
-		if corsOrigin == "" {
-			corsOrigin = "*"
-		}
+// shutdown stops accepting connections, lets in-flight requests finish
+// within gracePeriod, then closes all sessions and runs shutdown hooks
+func shutdown(server *mcp.Server, httpServer *http.Server, gracePeriod time.Duration) {
+	logger.Info("Shutting down", "grace_period", gracePeriod.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	// http.Server.Shutdown closes the listener right away but waits for
+	// SSE streams, which only end once the MCP server closes its sessions
+	httpDone := make(chan error, 1)
+	if httpServer != nil {
+		go func() {
+			httpDone <- httpServer.Shutdown(ctx)
+		}()
+	} else {
+		httpDone <- nil
+	}
 
-		c.Writer.Header().Set("Access-Control-Allow-Origin", corsOrigin)
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Warn("MCP server shutdown", "error", err)
+	}
+	if err := <-httpDone; err != nil {
+		logger.Warn("HTTP server shutdown", "error", err)
+	}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+	logger.Info("Server stopped")
+}
+
+// loadKeySet loads the external issuer's signing keys and keeps them
+// refreshed until ctx ends
+func loadKeySet(ctx context.Context, cfg *config.OAuthConfig) *oauth.KeySet {
+	keySet := oauth.NewKeySet(cfg.JWKSURL, cfg.JWKSFile)
+	if err := keySet.Refresh(ctx); err != nil {
+		if cfg.JWKSURL == "" {
+			logging.Fatal(logger, "Invalid auth.oauth.jwks_file", "error", err)
 		}
+		// The issuer may come up after this server; tokens with unknown
+		// keys trigger another fetch
+		logger.Warn("JWKS fetch failed, retrying on demand", "error", err)
+	}
+	go keySet.Watch(ctx, cfg.JWKSRefreshInterval.Duration())
+	return keySet
+}
 
-		c.Next()
+// newAuthorizationServer creates the built-in authorization server and
+// returns it with the keys that verify its tokens
+func newAuthorizationServer(cfg *config.AuthorizationServerConfig, resource string) (*oauth.AuthorizationServer, *oauth.KeySet) {
+	var signer *oauth.Signer
+	var err error
+	if cfg.SigningKeyFile != "" {
+		signer, err = oauth.LoadSigner(cfg.SigningKeyFile)
+	} else {
+		logger.Warn("No auth.authorization_server.signing_key_file, issued tokens won't survive a restart")
+		signer, err = oauth.NewSigner()
 	}
+	if err != nil {
+		logging.Fatal(logger, "Invalid auth.authorization_server.signing_key_file", "error", err)
+	}
+
+	as, err := oauth.NewAuthorizationServer(cfg.ServerOptions(signer, resource))
+	if err != nil {
+		logging.Fatal(logger, "Invalid auth.authorization_server", "error", err)
+	}
+	logger.Info("Authorization server enabled", "issuer", cfg.Issuer, "users", len(cfg.Users))
+	return as, signer.KeySet()
 }
 
-// rateLimitMiddleware implements simple rate limiting
-func rateLimitMiddleware() gin.HandlerFunc {
-	type client struct {
-		lastSeen time.Time
-		count    int
+// checkConfig adds the remote server's requirements to config validation
+func checkConfig(cfg *config.Config) error {
+	// With required client certificates the API key becomes optional
+	mtlsOnly := cfg.TLS.Enabled() && cfg.TLS.ClientAuth == "require"
+	noKeys := cfg.Auth.APIKey == "" && len(cfg.Auth.Keys) == 0 && !cfg.Auth.OAuth.Enabled()
+	if noKeys && cfg.Server.Mode == "http" && !mtlsOnly {
+		return errors.New("auth.api_key (MCP_API_KEY), auth.keys or auth.oauth is required")
 	}
+	return nil
+}
 
-	clients := make(map[string]*client)
-	var mu sync.Mutex
+// printKey prints the hash of a new or given API key in the form used by
+// auth.keys, so only the hash has to be stored in the config file
+func printKey(generate bool) {
+	var key string
+	if generate {
+		var err error
+		if key, err = auth.GenerateKey(); err != nil {
+			logging.Fatal(logger, "Failed to generate key", "error", err)
+		}
+		fmt.Printf("key:  %s\n", key)
+	} else {
+		key = readLine("key")
+	}
+	fmt.Printf("hash: %s\n", auth.HashKey(key))
+}
 
-	return func(c *gin.Context) {
-		mu.Lock()
-		defer mu.Unlock()
+// printPasswordHash prints the hash of a password read from stdin in the
+// form used by auth.authorization_server.users
+func printPasswordHash() {
+	hash, err := oauth.HashPassword(readLine("password"))
+	if err != nil {
+		logging.Fatal(logger, "Failed to hash password", "error", err)
+	}
+	fmt.Printf("password_hash: %s\n", hash)
+}
 
-		ip := c.ClientIP()
-		now := time.Now()
+// readLine reads one line from stdin
+func readLine(what string) string {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		logging.Fatal(logger, "Failed to read "+what, "error", err)
+	}
+	return strings.TrimSpace(line)
+}
 
-		cl, exists := clients[ip]
-		if !exists {
-			clients[ip] = &client{
-				lastSeen: now,
-				count:    1,
+// reloadOnHangup reloads the configuration on every SIGHUP until ctx ends
+func reloadOnHangup(ctx context.Context, reloader *config.Reloader) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-hangup:
+			logger.Info("SIGHUP received, reloading config")
+			if err := reloader.Reload(); err != nil {
+				logger.Error("Config reload rejected, keeping the running config", "error", err)
 			}
-			c.Next()
+		case <-ctx.Done():
 			return
 		}
+	}
+}
 
-		// Reset count if more than 1 minute has passed
-		if now.Sub(cl.lastSeen) > time.Minute {
-			cl.count = 1
-			cl.lastSeen = now
-			c.Next()
-			return
-		}
+// clientIdentities loads the client certificate subject to identity map
+// at path. Without one, certificates are identified by their common name.
+func clientIdentities(path string) auth.SubjectMap {
+	if path == "" {
+		return nil
+	}
 
-		// Check rate limit (default: 100 requests per minute)
-		rateLimit := 100
-		if limit := os.Getenv("RATE_LIMIT"); limit != "" {
-			fmt.Sscanf(limit, "%d", &rateLimit)
+	subjects, err := auth.LoadSubjectMap(path)
+	if err != nil {
+		logging.Fatal(logger, "Invalid tls.client_identities", "error", err)
+	}
+	return subjects
+}
+
+// closeRevokedSessions closes the sessions opened with an API key that was
+// removed, rotated or changed, so e.g. WebSocket clients can't keep using it
+func closeRevokedSessions(server *mcp.Server, keys *auth.KeyStore) {
+	for _, session := range server.Sessions() {
+		identity := auth.IdentityFromContext(session.Context())
+		if keys.Resolves(identity) {
+			continue
+		}
+		if server.CloseSession(session.ID()) {
+			logger.Info("Closed session of a revoked key", "closed_session", session.ID(), "key", identity.Name)
 		}
+	}
+}
 
-		if cl.count >= rateLimit {
-			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
-			c.Abort()
-			return
+// verifyAudit checks the hash chains of the audit logs at args, or of
+// audit.file, including their rotated files, and exits with status 1 if
+// any is broken. A chain must reach its checkpoint, or the one given with
+// -seq and -hash, so entries removed from the end are found too.
+func verifyAudit(configPath string, overrides *config.Flags, args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s verify [-seq N -hash H] [audit file...]\n\n", os.Args[0])
+		fmt.Fprintln(flags.Output(), "Checks the hash chain of the audit logs. Entries removed from the end")
+		fmt.Fprintln(flags.Output(), "of a log keep the chain intact; they are found by comparing it with the")
+		fmt.Fprintln(flags.Output(), "checkpoint written next to the log when it rotates or closes, or with a")
+		fmt.Fprintln(flags.Output(), "seq and hash kept elsewhere, e.g. from the \"Audit log checkpoint\" server log.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	seq := flags.Int64("seq", 0, "sequence number of an entry the log must contain, instead of its checkpoint")
+	hash := flags.String("hash", "", "hash of the entry at -seq")
+	flags.Parse(args)
+	if (*seq == 0) != (*hash == "") {
+		logging.Fatal(logger, "-seq and -hash must be given together")
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		cfg, err := config.Load(configPath, overrides)
+		if err != nil {
+			logging.Fatal(logger, "Invalid configuration", "error", err)
 		}
+		if cfg.Audit.File == "" {
+			logging.Fatal(logger, "No audit log to verify: pass its path or set audit.file")
+		}
+		paths = []string{cfg.Audit.File}
+	}
+	if *seq != 0 && len(paths) > 1 {
+		logging.Fatal(logger, "-seq and -hash check a single audit log")
+	}
 
-		cl.count++
-		c.Next()
+	failed := false
+	for _, path := range paths {
+		files, err := audit.Files(path)
+		if err == nil && len(files) == 0 {
+			err = fmt.Errorf("no such file")
+		}
+		var anchor *audit.Checkpoint
+		if err == nil {
+			if *seq != 0 {
+				anchor = &audit.Checkpoint{Seq: *seq, Hash: *hash}
+			} else {
+				anchor, err = audit.ReadCheckpoint(audit.CheckpointFile(path))
+			}
+		}
+		var result audit.Result
+		if err == nil {
+			result, err = audit.Verify(files, anchor)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: FAILED: %v\n", path, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: OK, %d entries (seq %d-%d) in %d file(s), last hash %s\n",
+			path, result.Entries, result.FirstSeq, result.LastSeq, result.Files, result.LastHash)
+		if result.FirstSeq > 1 {
+			fmt.Printf("%s: chain starts at seq %d; earlier entries were removed\n", path, result.FirstSeq)
+		}
+		if anchor == nil {
+			fmt.Printf("%s: no checkpoint; entries removed from the end can't be detected\n", path)
+		}
 	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// newMetricsRegistry collects the MCP server's metrics, authentication and
+// rate limit rejections, the storage tools' data and the Go runtime
+func newMetricsRegistry(server *mcp.Server, storage *tools.StorageTools) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		server.Metrics(),
+		middleware.AuthFailures,
+		ratelimit.Rejections,
+		tools.NewStorageMetrics(storage),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
 }
@@ -0,0 +1,357 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/config"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/usage"
+)
+
+// Prefix is where the admin API is served
+const Prefix = "/admin"
+
+// logger is the logger of the "admin" component
+var logger = logging.Component("admin")
+
+// Options configures the admin API
+type Options struct {
+	Server *mcp.Server
+	// Limiter holds the buckets of HTTP requests and tool calls
+	Limiter *ratelimit.Limiter
+	// Config returns the effective configuration, which changes on reload
+	Config func() *config.Config
+	// Usage serves the usage report at usage.ReportPath, if set
+	Usage http.Handler
+}
+
+// Handler serves the admin API:
+//
+//	GET    /admin/sessions                     connected sessions
+//	GET    /admin/sessions/{id}                a session and its in-flight requests
+//	DELETE /admin/sessions/{id}                close a session
+//	DELETE /admin/sessions/{id}/requests/{rid} cancel an in-flight request
+//	GET    /admin/requests                     in-flight requests of all sessions
+//	GET    /admin/tools                        registered tools
+//	POST   /admin/tools/{name}/enable|disable  enable or disable a tool
+//	GET    /admin/ratelimit                    rate limits and partly used buckets
+//	GET    /admin/config                       effective configuration (YAML, secrets redacted)
+//	GET    /admin/usage                        usage report, see usage.Tracker.Handler
+//
+// It does not check who is asking; mount it behind authentication.
+type Handler struct {
+	options Options
+}
+
+// New creates the admin API handler
+func New(options Options) *Handler {
+	return &Handler{options: options}
+}
+
+// session is the JSON form of a session
+type session struct {
+	ID           string          `json:"id"`
+	Client       mcp.ClientInfo  `json:"client"`
+	Initialized  bool            `json:"initialized"`
+	CreatedAt    time.Time       `json:"created_at"`
+	LastActivity time.Time       `json:"last_activity"`
+	IdleSeconds  float64         `json:"idle_seconds"`
+	InFlight     int             `json:"in_flight"`
+	Requests     []inflightEntry `json:"requests,omitempty"`
+}
+
+// inflightEntry is the JSON form of an in-flight request
+type inflightEntry struct {
+	Session string `json:"session_id"`
+	mcp.Request
+	ElapsedMS float64 `json:"elapsed_ms"`
+}
+
+// tool is the JSON form of a registered tool
+type tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ReadOnly    bool   `json:"read_only"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// bucket is the JSON form of a rate-limit bucket
+type bucket struct {
+	Principal string  `json:"principal"`
+	Tool      string  `json:"tool,omitempty"`
+	Tokens    float64 `json:"tokens"`
+	Capacity  float64 `json:"capacity"`
+}
+
+// rateLimit is the JSON form of the rate-limit state
+type rateLimit struct {
+	RequestsPerMinute int            `json:"requests_per_minute"`
+	Burst             int            `json:"burst"`
+	Tools             map[string]int `json:"tools"`
+	Buckets           []bucket       `json:"buckets"`
+}
+
+// ServeHTTP routes a request to its endpoint
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts, ok := splitPath(r.URL)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "usage" && h.options.Usage != nil:
+		if allow(w, r, http.MethodGet) {
+			h.options.Usage.ServeHTTP(w, r)
+		}
+	case len(parts) == 1 && parts[0] == "sessions":
+		if allow(w, r, http.MethodGet) {
+			h.listSessions(w)
+		}
+	case len(parts) == 2 && parts[0] == "sessions":
+		if allow(w, r, http.MethodGet, http.MethodDelete) {
+			if r.Method == http.MethodGet {
+				h.getSession(w, parts[1])
+			} else {
+				h.closeSession(w, r, parts[1])
+			}
+		}
+	case len(parts) == 4 && parts[0] == "sessions" && parts[2] == "requests":
+		if allow(w, r, http.MethodDelete) {
+			h.cancelRequest(w, r, parts[1], parts[3])
+		}
+	case len(parts) == 1 && parts[0] == "requests":
+		if allow(w, r, http.MethodGet) {
+			h.listRequests(w)
+		}
+	case len(parts) == 1 && parts[0] == "tools":
+		if allow(w, r, http.MethodGet) {
+			h.listTools(w)
+		}
+	case len(parts) == 3 && parts[0] == "tools" && (parts[2] == "enable" || parts[2] == "disable"):
+		if allow(w, r, http.MethodPost) {
+			h.setToolEnabled(w, r, parts[1], parts[2] == "enable")
+		}
+	case len(parts) == 1 && parts[0] == "ratelimit":
+		if allow(w, r, http.MethodGet) {
+			h.rateLimit(w)
+		}
+	case len(parts) == 1 && parts[0] == "config":
+		if allow(w, r, http.MethodGet) {
+			h.config(w)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// listSessions writes the connected sessions, most recently active first
+func (h *Handler) listSessions(w http.ResponseWriter) {
+	sessions := h.options.Server.Sessions()
+	list := make([]session, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, describeSession(s, false))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastActivity.After(list[j].LastActivity) })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": list})
+}
+
+// getSession writes a session with its in-flight requests
+func (h *Handler) getSession(w http.ResponseWriter, id string) {
+	s := h.options.Server.Session(id)
+	if s == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, describeSession(s, true))
+}
+
+// closeSession closes a session, cancelling its in-flight requests
+func (h *Handler) closeSession(w http.ResponseWriter, r *http.Request, id string) {
+	if !h.options.Server.CloseSession(id) {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	logger.InfoContext(r.Context(), "Session closed", "admin", caller(r), "closed_session", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cancelRequest cancels an in-flight request. The id is the JSON-RPC id as
+// sent, e.g. 3 or "abc"; string ids may also be given without quotes.
+func (h *Handler) cancelRequest(w http.ResponseWriter, r *http.Request, sessionID, requestID string) {
+	s := h.options.Server.Session(sessionID)
+	if s == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	if !s.CancelRequest(requestID) && !s.CancelRequest(strconv.Quote(requestID)) {
+		writeError(w, http.StatusNotFound, "request not found")
+		return
+	}
+	logger.InfoContext(r.Context(), "Request cancelled", "admin", caller(r), "cancelled_session", sessionID, "cancelled_id", requestID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listRequests writes the in-flight requests of all sessions, oldest first
+func (h *Handler) listRequests(w http.ResponseWriter) {
+	list := []inflightEntry{}
+	for _, s := range h.options.Server.Sessions() {
+		list = append(list, inflight(s)...)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"requests": list})
+}
+
+// listTools writes the registered tools
+func (h *Handler) listTools(w http.ResponseWriter) {
+	server := h.options.Server
+	tools := server.Tools()
+	list := make([]tool, 0, len(tools))
+	for _, t := range tools {
+		list = append(list, tool{
+			Name:        t.Name,
+			Description: t.Description,
+			ReadOnly:    t.ReadOnly(),
+			Enabled:     server.ToolEnabled(t.Name),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tools": list})
+}
+
+// setToolEnabled enables or disables a tool
+func (h *Handler) setToolEnabled(w http.ResponseWriter, r *http.Request, name string, enabled bool) {
+	if err := h.options.Server.SetToolEnabled(name, enabled); err != nil {
+		if errors.Is(err, mcp.ErrToolNotFound) {
+			writeError(w, http.StatusNotFound, "tool not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	logger.InfoContext(r.Context(), "Tool state changed", "admin", caller(r), "tool", name, "enabled", enabled)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"name": name, "enabled": enabled})
+}
+
+// rateLimit writes the effective limits and the buckets that are not full
+func (h *Handler) rateLimit(w http.ResponseWriter) {
+	cfg := h.options.Config().RateLimit
+	state := rateLimit{
+		RequestsPerMinute: cfg.RequestsPerMinute,
+		Burst:             cfg.Burst,
+		Tools:             cfg.Tools,
+		Buckets:           []bucket{},
+	}
+	if state.Tools == nil {
+		state.Tools = map[string]int{}
+	}
+	if h.options.Limiter != nil {
+		for _, b := range h.options.Limiter.Buckets() {
+			principal, tool := ratelimit.SplitKey(b.Key)
+			state.Buckets = append(state.Buckets, bucket{
+				Principal: principal,
+				Tool:      tool,
+				Tokens:    b.Tokens,
+				Capacity:  b.Capacity,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// config writes the effective configuration as YAML with secrets redacted
+func (h *Handler) config(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/yaml")
+	if err := h.options.Config().Print(w); err != nil {
+		logger.Error("Failed to write configuration", "error", err)
+	}
+}
+
+// describeSession returns the JSON form of s, with its in-flight requests
+// if withRequests is set
+func describeSession(s *mcp.Session, withRequests bool) session {
+	requests := inflight(s)
+	d := session{
+		ID:           s.ID(),
+		Client:       s.ClientInfo(),
+		Initialized:  s.Initialized(),
+		CreatedAt:    s.CreatedAt(),
+		LastActivity: s.LastActivity(),
+		IdleSeconds:  time.Since(s.LastActivity()).Seconds(),
+		InFlight:     len(requests),
+	}
+	if withRequests {
+		d.Requests = requests
+	}
+	return d
+}
+
+// inflight returns the JSON form of the in-flight requests of s
+func inflight(s *mcp.Session) []inflightEntry {
+	requests := s.Requests()
+	list := make([]inflightEntry, 0, len(requests))
+	for _, r := range requests {
+		list = append(list, inflightEntry{
+			Session:   s.ID(),
+			Request:   r,
+			ElapsedMS: float64(time.Since(r.StartedAt).Microseconds()) / 1000,
+		})
+	}
+	return list
+}
+
+// splitPath returns the unescaped segments of u's path below Prefix
+func splitPath(u *url.URL) ([]string, bool) {
+	rest, ok := strings.CutPrefix(u.EscapedPath(), Prefix+"/")
+	if !ok {
+		return nil, false
+	}
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil || unescaped == "" {
+			return nil, false
+		}
+		parts[i] = unescaped
+	}
+	return parts, true
+}
+
+// allow reports whether r uses one of methods, answering 405 otherwise
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+// caller returns who is making an admin request, for the log
+func caller(r *http.Request) string {
+	return usage.Caller(auth.IdentityFromContext(r.Context()))
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
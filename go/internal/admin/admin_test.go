@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/config"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/middleware"
+)
+
+// newTestRouter mounts the admin API the way the remote server does, behind
+// authentication and the admin scope
+func newTestRouter(t *testing.T, server *mcp.Server) http.Handler {
+	t.Helper()
+	keys, err := auth.NewKeyStore([]auth.Key{
+		{Name: "admin", Hash: auth.HashKey("admin-key"), Scopes: []string{auth.ScopeAdmin}},
+		{Name: "caller", Hash: auth.HashKey("caller-key"), Scopes: []string{auth.ScopeToolsCall}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := New(Options{Server: server, Config: config.Default})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Any(Prefix+"/*path",
+		middleware.AuthFunc(func() *auth.KeyStore { return keys }),
+		middleware.RequireScope(auth.ScopeAdmin),
+		gin.WrapH(api))
+	return router
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   int
+		// wantEnabled is whether the echo tool is enabled afterwards
+		wantEnabled bool
+	}{
+		{"admin lists tools", "admin-key", http.MethodGet, "/admin/tools", http.StatusOK, true},
+		{"admin disables a tool", "admin-key", http.MethodPost, "/admin/tools/echo/disable", http.StatusOK, false},
+		{"admin reads the config", "admin-key", http.MethodGet, "/admin/config", http.StatusOK, true},
+		{"caller without admin scope", "caller-key", http.MethodGet, "/admin/tools", http.StatusForbidden, true},
+		{"caller can't disable a tool", "caller-key", http.MethodPost, "/admin/tools/echo/disable", http.StatusForbidden, true},
+		{"unknown key", "wrong-key", http.MethodGet, "/admin/sessions", http.StatusUnauthorized, true},
+		{"no key", "", http.MethodPost, "/admin/tools/echo/disable", http.StatusUnauthorized, true},
+		{"unknown tool", "admin-key", http.MethodPost, "/admin/tools/missing/disable", http.StatusNotFound, true},
+		{"wrong method", "admin-key", http.MethodDelete, "/admin/tools", http.StatusMethodNotAllowed, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mcp.NewServer()
+			server.RegisterTool(mcp.Tool{Name: "echo", InputSchema: map[string]interface{}{"type": "object"}},
+				func(args map[string]interface{}) (interface{}, error) { return args, nil })
+			router := newTestRouter(t, server)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if enabled := server.ToolEnabled("echo"); enabled != tt.wantEnabled {
+				t.Errorf("echo enabled = %v, want %v", enabled, tt.wantEnabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package app wires the optional components shared by the server binaries
+// onto an MCP server: the gateway, usage accounting, the audit log and the
+// storage backend.
+package app
+
+import (
+	"context"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/audit"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/config"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/gateway"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/tools"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/usage"
+)
+
+// logger is the logger of the "server" component
+var logger = logging.Component("server")
+
+// StartGateway connects to the upstream servers listed in the gateway
+// config file at path and registers their tools. It returns nil if path is empty.
+func StartGateway(server *mcp.Server, path string) (*gateway.Gateway, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	gatewayConfig, err := gateway.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gw := gateway.New(server, gatewayConfig)
+	gw.Start(context.Background())
+	server.OnShutdown(gw.Stop)
+	logger.Info("Gateway started", "upstreams", len(gatewayConfig.Upstreams))
+	return gw, nil
+}
+
+// NewUsageTracker loads the usage counters, counts every tool call of
+// server against quotas and saves the counters when the server shuts down
+func NewUsageTracker(server *mcp.Server, cfg *config.Config, quotas func() map[string]usage.Quota) (*usage.Tracker, error) {
+	tracker, err := usage.NewTracker(cfg.Usage.File, cfg.Usage.RetentionDays)
+	if err != nil {
+		return nil, err
+	}
+
+	server.UseToolMiddleware(usage.Tools(tracker, quotas))
+	server.OnShutdown(func() {
+		if err := tracker.Save(); err != nil {
+			logger.Error("Usage save failed", "error", err)
+		}
+	})
+	return tracker, nil
+}
+
+// StartAudit writes every tool call of server to the audit log, if
+// audit.file is set, and closes the log when the server shuts down. It
+// returns nil if audit.file is empty.
+func StartAudit(server *mcp.Server, cfg *config.Config, redact func() []string) (*audit.Log, error) {
+	if cfg.Audit.File == "" {
+		return nil, nil
+	}
+
+	auditLog, err := audit.Open(cfg.Audit.Options())
+	if err != nil {
+		return nil, err
+	}
+	server.UseToolMiddleware(audit.Tools(auditLog, redact))
+	server.OnShutdown(func() {
+		if err := auditLog.Close(); err != nil {
+			logger.Error("Audit log close failed", "error", err)
+		}
+	})
+	logger.Info("Audit log enabled", "file", cfg.Audit.File)
+	return auditLog, nil
+}
+
+// OpenStorage opens the configured storage backend and closes it when the
+// server shuts down, after the last tool call has finished
+func OpenStorage(server *mcp.Server, cfg *config.Config) (tools.Store, error) {
+	store, err := cfg.Storage.Open()
+	if err != nil {
+		return nil, err
+	}
+	server.OnShutdown(func() {
+		if err := store.Close(); err != nil {
+			logger.Error("Storage close failed", "error", err)
+		}
+	})
+	return store, nil
+}
@@ -0,0 +1,368 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of an Entry
+const (
+	StatusOK        = "ok"
+	StatusError     = "error"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+)
+
+// rotatedFormat is the timestamp appended to the names of rotated files,
+// so they sort in the order they were written
+const rotatedFormat = "20060102T150405.000000000Z"
+
+// Entry is one tool call in the audit log
+type Entry struct {
+	// Seq numbers the entries of a chain from 1, across rotated files
+	Seq        int64                  `json:"seq"`
+	Time       time.Time              `json:"time"`
+	Principal  string                 `json:"principal"`
+	Session    string                 `json:"session,omitempty"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMS float64                `json:"duration_ms"`
+	// Prev is the hash of the previous entry; empty for the first one
+	Prev string `json:"prev"`
+	// Hash is written after the other fields, see hashLine
+	Hash string `json:"hash,omitempty"`
+}
+
+// Options configures a Log
+type Options struct {
+	// File is the current log file; rotated files are written next to it
+	// with a timestamp appended to the name
+	File string
+	// MaxSize rotates the file once it holds this many bytes; 0 disables
+	MaxSize int64
+	// RotateInterval rotates the file once it is this old; 0 disables
+	RotateInterval time.Duration
+}
+
+// Log appends hash-chained entries to a JSONL file. Each line ends with
+// the SHA-256 hash of the line and the previous entry's hash, so changing,
+// removing or reordering entries breaks the chain (see Verify). Removing
+// the last entries doesn't, so the log also records a Checkpoint of its
+// last entry when it rotates and closes.
+type Log struct {
+	options Options
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	seq      int64
+	last     string
+}
+
+// Open opens the audit log, continuing the chain of its last entry
+func Open(options Options) (*Log, error) {
+	if options.File == "" {
+		return nil, errors.New("audit log file is required")
+	}
+	l := &Log{options: options}
+
+	// The chain continues from the newest entry, which is in the current
+	// file or, right after a rotation, in the newest rotated one
+	files, err := Files(options.File)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		entry, err := lastEntry(files[i])
+		if err != nil {
+			return nil, fmt.Errorf("audit log %s is damaged, check it with verify: %w", files[i], err)
+		}
+		if entry != nil {
+			l.seq, l.last = entry.Seq, entry.Hash
+			break
+		}
+	}
+
+	// Continuing a chain that lost its last entries would overwrite the
+	// checkpoint that shows it
+	checkpoint, err := ReadCheckpoint(CheckpointFile(options.File))
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint != nil && (l.seq < checkpoint.Seq || l.seq == checkpoint.Seq && l.last != checkpoint.Hash) {
+		return nil, fmt.Errorf("audit log %s does not reach its checkpoint at seq %d, check it with verify", options.File, checkpoint.Seq)
+	}
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the current file for appending
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.options.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	// A file's age counts from its first entry
+	l.openedAt = time.Now()
+	if first, ok := firstEntryTime(l.options.File); ok {
+		l.openedAt = first
+	}
+	return nil
+}
+
+// Append adds entry to the log, filling in its sequence number and hashes
+func (l *Log) Append(entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return errors.New("audit log closed")
+	}
+	if err := l.rotateIfDue(); err != nil {
+		return err
+	}
+
+	entry.Seq = l.seq + 1
+	entry.Prev = l.last
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line, hash := hashLine(l.last, data)
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	l.seq, l.last = entry.Seq, hash
+	return nil
+}
+
+// rotateIfDue renames the current file once it is too large or too old
+// and starts a new one; l.mu must be held
+func (l *Log) rotateIfDue() error {
+	if l.size == 0 {
+		return nil
+	}
+	due := l.options.MaxSize > 0 && l.size >= l.options.MaxSize
+	due = due || l.options.RotateInterval > 0 && time.Since(l.openedAt) >= l.options.RotateInterval
+	if !due {
+		return nil
+	}
+
+	if err := l.checkpoint(); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	l.file = nil
+	rotated := l.options.File + "." + time.Now().UTC().Format(rotatedFormat)
+	if err := os.Rename(l.options.File, rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return l.open()
+}
+
+// Check reports whether entries can still be appended: the log is open
+// and its file has not been removed or replaced
+func (l *Log) Check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return errors.New("audit log closed")
+	}
+	open, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	current, err := os.Stat(l.options.File)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	if !os.SameFile(open, current) {
+		return fmt.Errorf("audit log %s was replaced", l.options.File)
+	}
+	return nil
+}
+
+// Close records a checkpoint of the last entry and closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.checkpoint()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// checkpoint syncs the current file and records the last entry in the
+// checkpoint file and the server log, so removing entries from the end
+// can be detected; l.mu must be held
+func (l *Log) checkpoint() error {
+	if l.seq == 0 {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	checkpoint := Checkpoint{Seq: l.seq, Hash: l.last, Time: time.Now().UTC()}
+	// The server log is a copy kept outside the log's directory
+	logger.Info("Audit log checkpoint", "file", l.options.File, "seq", checkpoint.Seq, "hash", checkpoint.Hash)
+	return WriteCheckpoint(CheckpointFile(l.options.File), checkpoint)
+}
+
+// hashLine returns the JSONL line of an encoded entry, with its hash added
+// as the last field, and the hash. The hash covers the previous hash and
+// the entry as encoded without it.
+func hashLine(prev string, data []byte) ([]byte, string) {
+	sum := sha256.New()
+	sum.Write([]byte(prev))
+	sum.Write([]byte("\n"))
+	sum.Write(data)
+	hash := hex.EncodeToString(sum.Sum(nil))
+
+	line := make([]byte, 0, len(data)+len(hash)+12)
+	line = append(line, data[:len(data)-1]...)
+	line = append(line, `,"hash":"`...)
+	line = append(line, hash...)
+	line = append(line, "\"}\n"...)
+	return line, hash
+}
+
+// splitHash splits a line written by hashLine into the encoded entry it
+// was computed over and the hash
+func splitHash(line []byte) ([]byte, string, bool) {
+	const prefix = `,"hash":"`
+	// prefix, 64 hex digits, closing quote and brace
+	n := len(prefix) + sha256.Size*2 + 2
+	if len(line) < n+2 || !bytes.HasPrefix(line[len(line)-n:], []byte(prefix)) || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, "", false
+	}
+	hash := string(line[len(line)-n+len(prefix) : len(line)-2])
+	data := append(append([]byte{}, line[:len(line)-n]...), '}')
+	return data, hash, true
+}
+
+// Files returns the rotated files of the log at path, oldest first,
+// followed by path itself if it exists
+func Files(path string) ([]string, error) {
+	rotated, err := filepath.Glob(globEscape(path) + ".*")
+	if err != nil {
+		return nil, err
+	}
+	files := rotated[:0]
+	for _, f := range rotated {
+		if _, err := time.Parse(rotatedFormat, strings.TrimPrefix(f, path+".")); err == nil {
+			files = append(files, f)
+		}
+	}
+	sort.Strings(files)
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// globEscape escapes the glob metacharacters of path
+func globEscape(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// lastEntry returns the last entry of the file at path, or nil if it has none
+func lastEntry(path string) (*Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var last []byte
+	scanner := newScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, nil
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return nil, fmt.Errorf("last entry: %w", err)
+	}
+	if _, _, ok := splitHash(last); !ok || entry.Hash == "" {
+		return nil, errors.New("last entry has no hash")
+	}
+	return &entry, nil
+}
+
+// firstEntryTime returns the time of the first entry of the file at path
+func firstEntryTime(path string) (time.Time, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer file.Close()
+
+	scanner := newScanner(file)
+	if !scanner.Scan() {
+		return time.Time{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+		return time.Time{}, false
+	}
+	return entry.Time, true
+}
+
+// newScanner returns a line scanner for entries with large arguments
+func newScanner(file *os.File) *bufio.Scanner {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return scanner
+}
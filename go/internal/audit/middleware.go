@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/usage"
+)
+
+// logger is the logger of the "audit" component
+var logger = logging.Component("audit")
+
+// Redacted replaces the values of redacted arguments
+const Redacted = "REDACTED"
+
+// Tools returns tool middleware writing every tool call to log. redact
+// lists the argument fields whose values are left out, as "field" for
+// every tool or "tool.field"; it is looked up on every call so it can be
+// reloaded. Added first, it also records calls rejected by later
+// middleware, such as rate limits and quotas.
+func Tools(log *Log, redact func() []string) mcp.ToolMiddleware {
+	return func(next mcp.ToolCallFunc) mcp.ToolCallFunc {
+		return func(ctx context.Context, tool mcp.Tool, args map[string]interface{}) (interface{}, error) {
+			start := time.Now()
+			result, err := next(ctx, tool, args)
+
+			entry := Entry{
+				Time:       start.UTC(),
+				Principal:  usage.Caller(auth.IdentityFromContext(ctx)),
+				Tool:       tool.Name,
+				Arguments:  redactArguments(tool.Name, args, redact()),
+				Status:     StatusOK,
+				DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if session := mcp.SessionFromContext(ctx); session != nil {
+				entry.Session = session.ID()
+			}
+			switch {
+			case err != nil:
+				entry.Status, entry.Error = status(ctx, err), err.Error()
+			case isErrorResult(result):
+				entry.Status = StatusError
+			}
+
+			if err := log.Append(entry); err != nil {
+				logger.ErrorContext(ctx, "Audit entry not written", "tool", tool.Name, "error", err)
+			}
+			return result, err
+		}
+	}
+}
+
+// status returns the status of a call that failed with err
+func status(ctx context.Context, err error) string {
+	if _, ok := err.(*mcp.JSONRPCError); ok {
+		return StatusRejected
+	}
+	if ctx.Err() != nil {
+		return StatusCancelled
+	}
+	return StatusError
+}
+
+// isErrorResult reports whether a tool returned an error result
+func isErrorResult(result interface{}) bool {
+	switch r := result.(type) {
+	case *mcp.CallToolResult:
+		return r != nil && r.IsError
+	case mcp.CallToolResult:
+		return r.IsError
+	}
+	return false
+}
+
+// redactArguments returns a copy of args with the fields redact names for
+// tool replaced by Redacted
+func redactArguments(tool string, args map[string]interface{}, redact []string) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for field, value := range args {
+		out[field] = value
+	}
+	for _, rule := range redact {
+		field := rule
+		if t, f, ok := strings.Cut(rule, "."); ok {
+			if t != tool && t != "*" {
+				continue
+			}
+			field = f
+		}
+		if _, ok := out[field]; ok {
+			out[field] = Redacted
+		}
+	}
+	return out
+}
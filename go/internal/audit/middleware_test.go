@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactArguments(t *testing.T) {
+	args := map[string]interface{}{"key": "name", "value": "secret", "password": "hunter2"}
+	tests := []struct {
+		name   string
+		tool   string
+		redact []string
+		want   map[string]interface{}
+	}{
+		{"none", "storage_set", nil, map[string]interface{}{"key": "name", "value": "secret", "password": "hunter2"}},
+		{"every tool", "storage_set", []string{"password"}, map[string]interface{}{"key": "name", "value": "secret", "password": Redacted}},
+		{"this tool", "storage_set", []string{"storage_set.value"}, map[string]interface{}{"key": "name", "value": Redacted, "password": "hunter2"}},
+		{"other tool", "echo", []string{"storage_set.value"}, map[string]interface{}{"key": "name", "value": "secret", "password": "hunter2"}},
+		{"wildcard tool", "echo", []string{"*.value"}, map[string]interface{}{"key": "name", "value": Redacted, "password": "hunter2"}},
+		{"missing field", "storage_set", []string{"token"}, map[string]interface{}{"key": "name", "value": "secret", "password": "hunter2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactArguments(tt.tool, args, tt.redact)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("redactArguments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if args["value"] != "secret" {
+		t.Error("redactArguments() changed the arguments")
+	}
+	if got := redactArguments("echo", nil, []string{"password"}); got != nil {
+		t.Errorf("redactArguments(nil) = %v, want nil", got)
+	}
+}
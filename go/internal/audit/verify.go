@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Result summarizes a verified chain
+type Result struct {
+	Files   int
+	Entries int64
+	// FirstSeq is 1 unless older files were removed, in which case the
+	// start of the chain can't be checked
+	FirstSeq int64
+	LastSeq  int64
+	LastHash string
+}
+
+// Verify checks the hash chain of the audit log files, given oldest first
+// as returned by Files. It reports the first entry that was changed,
+// removed, inserted or reordered. If anchor is not nil, the chain must
+// also reach it, so entries removed from the end are reported too.
+func Verify(files []string, anchor *Checkpoint) (Result, error) {
+	result := Result{Files: len(files)}
+	var prev string
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return result, err
+		}
+
+		for n, line := range bytes.Split(data, []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			fail := func(format string, args ...interface{}) (Result, error) {
+				return result, fmt.Errorf("%s:%d: %s", path, n+1, fmt.Sprintf(format, args...))
+			}
+
+			encoded, hash, ok := splitHash(line)
+			if !ok {
+				return fail("entry has no hash")
+			}
+			var entry Entry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return fail("malformed entry: %v", err)
+			}
+
+			if result.Entries == 0 {
+				// Entries before the first file may have been removed
+				result.FirstSeq = entry.Seq
+				if entry.Seq == 1 && entry.Prev != "" {
+					return fail("first entry has a previous hash")
+				}
+			} else {
+				if entry.Seq != result.LastSeq+1 {
+					return fail("sequence %d follows %d", entry.Seq, result.LastSeq)
+				}
+				if entry.Prev != prev {
+					return fail("entry %d does not chain to entry %d", entry.Seq, result.LastSeq)
+				}
+			}
+			if _, want := hashLine(entry.Prev, encoded); want != hash {
+				return fail("entry %d was modified", entry.Seq)
+			}
+			if anchor != nil && entry.Seq == anchor.Seq && hash != anchor.Hash {
+				return fail("entry %d does not match the checkpoint", entry.Seq)
+			}
+
+			prev = hash
+			result.Entries++
+			result.LastSeq = entry.Seq
+			result.LastHash = hash
+		}
+	}
+	if anchor != nil && result.LastSeq < anchor.Seq {
+		return result, fmt.Errorf("log ends at seq %d before the checkpoint at seq %d: entries were removed", result.LastSeq, anchor.Seq)
+	}
+	return result, nil
+}
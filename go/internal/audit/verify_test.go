@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeLog appends n entries to a new log in a temporary directory, closes
+// it and returns its path
+func writeLog(t *testing.T, n int, options Options) string {
+	t.Helper()
+	if options.File == "" {
+		options.File = filepath.Join(t.TempDir(), "audit.jsonl")
+	}
+	l, err := Open(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		entry := Entry{Time: time.Now().UTC(), Principal: "ci", Tool: "echo", Status: StatusOK}
+		if err := l.Append(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return options.File
+}
+
+// readLines returns the lines of the file at path
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// writeLines replaces the file at path with lines
+func writeLines(t *testing.T, path string, lines []string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper changes the five lines of the log
+		tamper       func(lines []string) []string
+		useAnchor    bool
+		wantErr      string
+		wantFirstSeq int64
+		wantLastSeq  int64
+	}{
+		{
+			name:         "intact",
+			tamper:       func(lines []string) []string { return lines },
+			useAnchor:    true,
+			wantFirstSeq: 1,
+			wantLastSeq:  5,
+		},
+		{
+			name: "field changed",
+			tamper: func(lines []string) []string {
+				lines[2] = strings.Replace(lines[2], `"tool":"echo"`, `"tool":"storage_set"`, 1)
+				return lines
+			},
+			wantErr: "entry 3 was modified",
+		},
+		{
+			name: "hash recomputed",
+			tamper: func(lines []string) []string {
+				// A forger can rehash the changed entry, but not the
+				// entries chained to it
+				data, _, _ := splitHash([]byte(strings.Replace(lines[2], `"principal":"ci"`, `"principal":"admin"`, 1)))
+				line, _ := hashLine(hashOf(lines[1]), data)
+				lines[2] = strings.TrimSuffix(string(line), "\n")
+				return lines
+			},
+			wantErr: "entry 4 does not chain to entry 3",
+		},
+		{
+			name:    "entry removed",
+			tamper:  func(lines []string) []string { return append(lines[:2], lines[3:]...) },
+			wantErr: "sequence 4 follows 2",
+		},
+		{
+			name: "entries reordered",
+			tamper: func(lines []string) []string {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			wantErr: "sequence 3 follows 1",
+		},
+		{
+			name:    "entry duplicated",
+			tamper:  func(lines []string) []string { return append(lines[:3], lines[2:]...) },
+			wantErr: "sequence 3 follows 3",
+		},
+		{
+			name: "hash removed",
+			tamper: func(lines []string) []string {
+				lines[4] = lines[4][:strings.Index(lines[4], `,"hash"`)] + "}"
+				return lines
+			},
+			wantErr: "entry has no hash",
+		},
+		{
+			name: "first entry removed",
+			// Older entries may have been rotated away, so the chain is
+			// checked from the first entry left
+			tamper:       func(lines []string) []string { return lines[1:] },
+			wantFirstSeq: 2,
+			wantLastSeq:  5,
+		},
+		{
+			name:         "last entries removed without a checkpoint",
+			tamper:       func(lines []string) []string { return lines[:3] },
+			wantFirstSeq: 1,
+			wantLastSeq:  3,
+		},
+		{
+			name:      "last entries removed",
+			tamper:    func(lines []string) []string { return lines[:3] },
+			useAnchor: true,
+			wantErr:   "log ends at seq 3 before the checkpoint at seq 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeLog(t, 5, Options{})
+			writeLines(t, path, tt.tamper(readLines(t, path)))
+
+			var anchor *Checkpoint
+			if tt.useAnchor {
+				var err error
+				if anchor, err = ReadCheckpoint(CheckpointFile(path)); err != nil || anchor == nil {
+					t.Fatalf("ReadCheckpoint() = %v, %v", anchor, err)
+				}
+			}
+			result, err := Verify([]string{path}, anchor)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if result.FirstSeq != tt.wantFirstSeq || result.LastSeq != tt.wantLastSeq {
+				t.Errorf("Verify() = seq %d-%d, want %d-%d", result.FirstSeq, result.LastSeq, tt.wantFirstSeq, tt.wantLastSeq)
+			}
+		})
+	}
+}
+
+// hashOf returns the hash of a log line
+func hashOf(line string) string {
+	_, hash, _ := splitHash([]byte(line))
+	return hash
+}
+
+func TestVerifyRewrittenTail(t *testing.T) {
+	path := writeLog(t, 5, Options{})
+	checkpoint, err := ReadCheckpoint(CheckpointFile(path))
+	if err != nil || checkpoint == nil {
+		t.Fatalf("ReadCheckpoint() = %v, %v", checkpoint, err)
+	}
+
+	// Rewrite the entries from seq 3 on: they chain correctly, but can't
+	// match the checkpoint
+	writeLines(t, path, readLines(t, path)[:2])
+	if err := os.Remove(CheckpointFile(path)); err != nil {
+		t.Fatal(err)
+	}
+	writeLog(t, 3, Options{File: path})
+
+	if _, err := Verify([]string{path}, nil); err != nil {
+		t.Fatalf("Verify() without checkpoint error = %v", err)
+	}
+	_, err = Verify([]string{path}, checkpoint)
+	if want := "entry 5 does not match the checkpoint"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Verify() error = %v, want %q", err, want)
+	}
+}
+
+func TestOpenRefusesTruncatedLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		keep    int
+		wantErr bool
+	}{
+		{"intact", 5, false},
+		{"truncated", 3, true},
+		{"emptied", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeLog(t, 5, Options{})
+			lines := readLines(t, path)[:tt.keep]
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			l, err := Open(Options{File: path})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if l != nil {
+				l.Close()
+			}
+		})
+	}
+}
+
+func TestVerifyRotated(t *testing.T) {
+	path := writeLog(t, 12, Options{MaxSize: 1})
+	files, err := Files(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each entry fills a file, and the next one rotates it
+	if len(files) != 12 {
+		t.Fatalf("Files() = %d files, want 12", len(files))
+	}
+
+	checkpoint, err := ReadCheckpoint(CheckpointFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Verify(files, checkpoint)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if result.Files != 12 || result.Entries != 12 || result.LastSeq != 12 {
+		t.Errorf("Verify() = %+v", result)
+	}
+
+	// A rotated file removed from the middle breaks the chain
+	if _, err := Verify(append(files[:5:5], files[6:]...), checkpoint); err == nil {
+		t.Error("Verify() accepted a missing rotated file")
+	}
+	// ... and the current file removed misses the checkpoint
+	if _, err := Verify(files[:11], checkpoint); err == nil {
+		t.Error("Verify() accepted a missing current file")
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SubjectMap maps client certificate subjects to identity names. Keys are
+// either a full subject in RFC 2253 form ("CN=alice,OU=eng,O=Example") or
+// just a common name ("CN=alice"); the full subject is tried first.
+type SubjectMap map[string]string
+
+// LoadSubjectMap reads a SubjectMap from a JSON object file
+func LoadSubjectMap(path string) (SubjectMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client identities: %w", err)
+	}
+
+	var subjects SubjectMap
+	if err := json.Unmarshal(data, &subjects); err != nil {
+		return nil, fmt.Errorf("failed to parse client identities: %w", err)
+	}
+	return subjects, nil
+}
+
+// Identity returns the identity for a verified client certificate, which
+// may call any tool. Without a map the certificate's common name is used;
+// with one, certificates whose subject isn't listed are refused.
+func (m SubjectMap) Identity(cert *x509.Certificate) (*Identity, bool) {
+	if m == nil {
+		name := cert.Subject.CommonName
+		if name == "" {
+			name = cert.Subject.String()
+		}
+		return &Identity{Name: name, Method: MethodClientCert, Scopes: []string{ScopeToolsCall}}, name != ""
+	}
+
+	name, ok := m[cert.Subject.String()]
+	if !ok && cert.Subject.CommonName != "" {
+		name, ok = m["CN="+cert.Subject.CommonName]
+	}
+	if !ok {
+		return nil, false
+	}
+	return &Identity{Name: name, Method: MethodClientCert, Scopes: []string{ScopeToolsCall}}, true
+}
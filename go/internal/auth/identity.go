@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"path"
+	"slices"
+)
+
+// Authentication methods recorded on an Identity
+const (
+	MethodAPIKey     = "api_key"
+	MethodClientCert = "client_cert"
+	MethodOAuth      = "oauth"
+)
+
+// Identity is the authenticated caller of a request. It is attached to the
+// request context by the HTTP middleware and reaches tool handlers through
+// the MCP session context.
+type Identity struct {
+	// Name identifies the caller, e.g. the identity mapped from a client
+	// certificate subject
+	Name string `json:"name"`
+	// Method is how the caller authenticated
+	Method string `json:"method"`
+	// Scopes granted to the caller
+	Scopes []string `json:"scopes,omitempty"`
+	// Tools, if set, limits the caller to tools matching these patterns
+	Tools []string `json:"tools,omitempty"`
+	// Claims holds the validated access token's claims for OAuth callers
+	Claims map[string]interface{} `json:"claims,omitempty"`
+
+	// keyHash is the hash of the API key the caller authenticated with
+	keyHash []byte
+}
+
+// HasScope reports whether the caller was granted scope
+func (i *Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CanCallTool reports whether the caller may see and call the named tool.
+// readOnly tells whether the tool is annotated as read-only.
+func (i *Identity) CanCallTool(name string, readOnly bool) bool {
+	if len(i.Tools) > 0 {
+		allowed := false
+		for _, pattern := range i.Tools {
+			if ok, _ := path.Match(pattern, name); ok {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return i.HasScope(ScopeToolsCall) || (readOnly && i.HasScope(ScopeToolsRead))
+}
+
+// SameCaller reports whether two identities are the same caller with the
+// same permissions. A session opened before a key's scopes changed no
+// longer matches, so the client has to reconnect with the new permissions.
+func SameCaller(a, b *Identity) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Name == b.Name && a.Method == b.Method &&
+		slices.Equal(a.Scopes, b.Scopes) && slices.Equal(a.Tools, b.Tools)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller's identity, or nil if the request
+// was not authenticated
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
@@ -0,0 +1,57 @@
+package auth
+
+import "testing"
+
+func TestCanCallTool(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity Identity
+		tool     string
+		readOnly bool
+		want     bool
+	}{
+		{"call scope", Identity{Scopes: []string{ScopeToolsCall}}, "storage_set", false, true},
+		{"call scope, read-only tool", Identity{Scopes: []string{ScopeToolsCall}}, "storage_get", true, true},
+		{"read scope, read-only tool", Identity{Scopes: []string{ScopeToolsRead}}, "storage_get", true, true},
+		{"read scope, other tool", Identity{Scopes: []string{ScopeToolsRead}}, "storage_set", false, false},
+		{"admin scope only", Identity{Scopes: []string{ScopeAdmin}}, "storage_get", true, false},
+		{"no scopes", Identity{}, "storage_get", true, false},
+		{"tool allowed", Identity{Scopes: []string{ScopeToolsCall}, Tools: []string{"echo"}}, "echo", false, true},
+		{"tool not allowed", Identity{Scopes: []string{ScopeToolsCall}, Tools: []string{"echo"}}, "calculator", false, false},
+		{"pattern", Identity{Scopes: []string{ScopeToolsCall}, Tools: []string{"storage_*"}}, "storage_set", false, true},
+		{"pattern mismatch", Identity{Scopes: []string{ScopeToolsCall}, Tools: []string{"storage_*"}}, "github__storage_set", false, false},
+		{"second pattern", Identity{Scopes: []string{ScopeToolsCall}, Tools: []string{"echo", "github__*"}}, "github__search", false, true},
+		{"allowed tool still needs scope", Identity{Scopes: []string{ScopeToolsRead}, Tools: []string{"storage_*"}}, "storage_set", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.identity.CanCallTool(tt.tool, tt.readOnly); got != tt.want {
+				t.Errorf("CanCallTool(%q, %v) = %v, want %v", tt.tool, tt.readOnly, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameCaller(t *testing.T) {
+	alice := &Identity{Name: "alice", Method: MethodAPIKey, Scopes: []string{ScopeToolsCall}}
+	tests := []struct {
+		name string
+		a, b *Identity
+		want bool
+	}{
+		{"both nil", nil, nil, true},
+		{"one nil", alice, nil, false},
+		{"same", alice, &Identity{Name: "alice", Method: MethodAPIKey, Scopes: []string{ScopeToolsCall}}, true},
+		{"other name", alice, &Identity{Name: "bob", Method: MethodAPIKey, Scopes: []string{ScopeToolsCall}}, false},
+		{"other method", alice, &Identity{Name: "alice", Method: MethodOAuth, Scopes: []string{ScopeToolsCall}}, false},
+		{"other scopes", alice, &Identity{Name: "alice", Method: MethodAPIKey, Scopes: []string{ScopeToolsRead}}, false},
+		{"tools limited", alice, &Identity{Name: "alice", Method: MethodAPIKey, Scopes: []string{ScopeToolsCall}, Tools: []string{"echo"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SameCaller(tt.a, tt.b); got != tt.want {
+				t.Errorf("SameCaller() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
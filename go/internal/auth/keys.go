@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes granted to API keys
+const (
+	// ScopeToolsRead allows calling tools annotated as read-only
+	ScopeToolsRead = "tools:read"
+	// ScopeToolsCall allows calling any tool
+	ScopeToolsCall = "tools:call"
+	// ScopeAdmin allows the admin endpoints, such as usage reports
+	ScopeAdmin = "admin"
+)
+
+// knownScopes lists the scopes a key may be given
+var knownScopes = map[string]bool{
+	ScopeToolsRead: true,
+	ScopeToolsCall: true,
+	ScopeAdmin:     true,
+}
+
+// hashPrefix marks the hash algorithm of a stored key
+const hashPrefix = "sha256:"
+
+// Errors returned by KeyStore.Authenticate
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrExpiredKey = errors.New("API key expired")
+)
+
+// Key is an API key as configured. Only a hash of the key is stored; API
+// keys are long random strings, so a fast hash is enough.
+type Key struct {
+	// Name identifies the key's holder in logs and the identity
+	Name string `yaml:"name"`
+	// Hash is "sha256:<hex>" of the key, as printed by HashKey
+	Hash string `yaml:"hash"`
+	// ExpiresAt, if set, is when the key stops working
+	ExpiresAt *time.Time `yaml:"expires_at,omitempty"`
+	// Scopes granted to the key; empty grants tools:call
+	Scopes []string `yaml:"scopes,omitempty"`
+	// Tools, if set, limits the key to tools matching these names or
+	// patterns such as "storage_*" or "github__*"
+	Tools []string `yaml:"tools,omitempty"`
+}
+
+// Validate checks the key's fields
+func (k *Key) Validate() error {
+	if k.Name == "" {
+		return errors.New("name is required")
+	}
+	if _, err := decodeHash(k.Hash); err != nil {
+		return err
+	}
+	for _, scope := range k.Scopes {
+		if !knownScopes[scope] {
+			return fmt.Errorf("unknown scope %q (expected %s, %s or %s)", scope, ScopeToolsRead, ScopeToolsCall, ScopeAdmin)
+		}
+	}
+	for _, pattern := range k.Tools {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// storedKey is a validated Key with its decoded hash
+type storedKey struct {
+	Key
+	hash []byte
+}
+
+// grantedScopes returns the key's scopes, tools:call if none are listed
+func (k *Key) grantedScopes() []string {
+	if len(k.Scopes) == 0 {
+		return []string{ScopeToolsCall}
+	}
+	return k.Scopes
+}
+
+// KeyStore authenticates bearer tokens against a set of hashed API keys
+type KeyStore struct {
+	keys []storedKey
+}
+
+// NewKeyStore creates a key store, checking every key
+func NewKeyStore(keys []Key) (*KeyStore, error) {
+	store := &KeyStore{}
+	names := make(map[string]bool)
+	for i, key := range keys {
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("key %d (%s): %w", i, key.Name, err)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("key %d (%s): duplicate name", i, key.Name)
+		}
+		names[key.Name] = true
+
+		hash, _ := decodeHash(key.Hash)
+		store.keys = append(store.keys, storedKey{Key: key, hash: hash})
+	}
+	return store, nil
+}
+
+// LegacyKey returns the key named "api-key" for a plaintext key such as
+// MCP_API_KEY. It may call tools but not use the admin endpoints, which
+// need a key with the admin scope listed explicitly.
+func LegacyKey(key string) Key {
+	return Key{Name: "api-key", Hash: HashKey(key), Scopes: []string{ScopeToolsCall}}
+}
+
+// SingleKey returns a store holding the LegacyKey for key, or an empty
+// store if key is empty
+func SingleKey(key string) *KeyStore {
+	if key == "" {
+		return &KeyStore{}
+	}
+	store, _ := NewKeyStore([]Key{LegacyKey(key)}) // a legacy key is always valid
+	return store
+}
+
+// Empty reports whether the store has no keys
+func (s *KeyStore) Empty() bool {
+	return s == nil || len(s.keys) == 0
+}
+
+// Authenticate returns the identity of the key matching token
+func (s *KeyStore) Authenticate(token string) (*Identity, error) {
+	if s == nil {
+		return nil, ErrInvalidKey
+	}
+
+	// Compare against every key so the time taken doesn't reveal which,
+	// if any, matched
+	sum := sha256.Sum256([]byte(token))
+	var match *storedKey
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(sum[:], s.keys[i].hash) == 1 {
+			match = &s.keys[i]
+		}
+	}
+	if match == nil {
+		return nil, ErrInvalidKey
+	}
+	if match.ExpiresAt != nil && time.Now().After(*match.ExpiresAt) {
+		return nil, ErrExpiredKey
+	}
+
+	return &Identity{
+		Name:    match.Name,
+		Method:  MethodAPIKey,
+		Scopes:  match.grantedScopes(),
+		Tools:   match.Tools,
+		keyHash: match.hash,
+	}, nil
+}
+
+// Resolves reports whether the API key identity authenticated with is still
+// in the store, unexpired and with the same scopes and tools. Callers that
+// didn't authenticate with a key from a store always resolve.
+func (s *KeyStore) Resolves(identity *Identity) bool {
+	if identity == nil || identity.keyHash == nil {
+		return true
+	}
+	if s == nil {
+		return false
+	}
+	for i := range s.keys {
+		key := &s.keys[i]
+		if key.Name != identity.Name || subtle.ConstantTimeCompare(key.hash, identity.keyHash) != 1 {
+			continue
+		}
+		if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+			return false
+		}
+		return slices.Equal(key.grantedScopes(), identity.Scopes) && slices.Equal(key.Tools, identity.Tools)
+	}
+	return false
+}
+
+// HashKey returns the stored form of an API key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// GenerateKey returns a new random API key
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "mcp_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeHash parses a "sha256:<hex>" key hash
+func decodeHash(hash string) ([]byte, error) {
+	if !strings.HasPrefix(hash, hashPrefix) {
+		return nil, fmt.Errorf("hash must start with %q", hashPrefix)
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(hash, hashPrefix))
+	if err != nil || len(b) != sha256.Size {
+		return nil, errors.New("hash must be 64 hex digits after sha256:")
+	}
+	return b, nil
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestKeyValidate(t *testing.T) {
+	hash := HashKey("secret")
+	tests := []struct {
+		name    string
+		key     Key
+		wantErr bool
+	}{
+		{"valid", Key{Name: "ci", Hash: hash, Scopes: []string{ScopeToolsRead, ScopeAdmin}, Tools: []string{"storage_*"}}, false},
+		{"no name", Key{Hash: hash}, true},
+		{"no hash prefix", Key{Name: "ci", Hash: hash[len("sha256:"):]}, true},
+		{"short hash", Key{Name: "ci", Hash: "sha256:abcd"}, true},
+		{"unknown scope", Key{Name: "ci", Hash: hash, Scopes: []string{"tools:write"}}, true},
+		{"invalid pattern", Key{Name: "ci", Hash: hash, Tools: []string{"storage_["}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.key.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyStoreAuthenticate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	store, err := NewKeyStore([]Key{
+		{Name: "default", Hash: HashKey("default-key")},
+		{Name: "reader", Hash: HashKey("reader-key"), Scopes: []string{ScopeToolsRead}, Tools: []string{"storage_*"}},
+		{Name: "expired", Hash: HashKey("expired-key"), ExpiresAt: &past},
+		{Name: "expiring", Hash: HashKey("expiring-key"), ExpiresAt: &future},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantName   string
+		wantScopes []string
+		wantErr    error
+	}{
+		{"default scopes", "default-key", "default", []string{ScopeToolsCall}, nil},
+		{"listed scopes", "reader-key", "reader", []string{ScopeToolsRead}, nil},
+		{"not yet expired", "expiring-key", "expiring", []string{ScopeToolsCall}, nil},
+		{"expired", "expired-key", "", nil, ErrExpiredKey},
+		{"unknown", "other-key", "", nil, ErrInvalidKey},
+		{"empty", "", "", nil, ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := store.Authenticate(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if identity.Name != tt.wantName || identity.Method != MethodAPIKey || !slices.Equal(identity.Scopes, tt.wantScopes) {
+				t.Errorf("Authenticate() = %+v, want %s with %v", identity, tt.wantName, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestNewKeyStoreDuplicateName(t *testing.T) {
+	_, err := NewKeyStore([]Key{
+		{Name: "ci", Hash: HashKey("a")},
+		{Name: "ci", Hash: HashKey("b")},
+	})
+	if err == nil {
+		t.Error("NewKeyStore() accepted duplicate names")
+	}
+}
+
+func TestLegacyKey(t *testing.T) {
+	identity, err := SingleKey("legacy").Authenticate("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.HasScope(ScopeAdmin) {
+		t.Error("legacy key was granted admin")
+	}
+	if !identity.HasScope(ScopeToolsCall) {
+		t.Error("legacy key can't call tools")
+	}
+	if !SingleKey("").Empty() {
+		t.Error("SingleKey(\"\") is not empty")
+	}
+}
+
+func TestKeyStoreResolves(t *testing.T) {
+	keys := []Key{
+		{Name: "ci", Hash: HashKey("ci-key"), Scopes: []string{ScopeToolsCall}},
+		{Name: "reader", Hash: HashKey("reader-key"), Scopes: []string{ScopeToolsRead}},
+	}
+	before, err := NewKeyStore(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci, _ := before.Authenticate("ci-key")
+	reader, _ := before.Authenticate("reader-key")
+
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		after    []Key
+		identity *Identity
+		want     bool
+	}{
+		{"unchanged", keys, ci, true},
+		{"removed", keys[1:], ci, false},
+		{"key replaced", []Key{{Name: "ci", Hash: HashKey("new-key"), Scopes: []string{ScopeToolsCall}}}, ci, false},
+		{"renamed", []Key{{Name: "build", Hash: HashKey("ci-key"), Scopes: []string{ScopeToolsCall}}}, ci, false},
+		{"scopes changed", []Key{{Name: "ci", Hash: HashKey("ci-key"), Scopes: []string{ScopeToolsRead}}}, ci, false},
+		{"tools limited", []Key{{Name: "ci", Hash: HashKey("ci-key"), Scopes: []string{ScopeToolsCall}, Tools: []string{"echo"}}}, ci, false},
+		{"expired", []Key{{Name: "ci", Hash: HashKey("ci-key"), Scopes: []string{ScopeToolsCall}, ExpiresAt: &past}}, ci, false},
+		{"other key changed", keys[:1], reader, false},
+		{"not a key", nil, &Identity{Name: "alice", Method: MethodOAuth}, true},
+		{"anonymous", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after, err := NewKeyStore(tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := after.Resolves(tt.identity); got != tt.want {
+				t.Errorf("Resolves() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
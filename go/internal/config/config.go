@@ -0,0 +1,539 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/audit"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/health"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/listener"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/oauth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/telemetry"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/tlsconfig"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/tools"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/usage"
+)
+
+// Config is the configuration shared by the server binaries. Every field
+// can be set in the YAML file (yaml tag), overridden by an environment
+// variable (env tag) and, for the common ones, by a command-line flag
+// (flag tag). Fields tagged secret are redacted when printed, and fields
+// tagged reload take effect when a running server reloads its config file.
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	TLS       TLSConfig       `yaml:"tls"`
+	Auth      AuthConfig      `yaml:"auth"`
+	CORS      CORSConfig      `yaml:"cors"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Usage     UsageConfig     `yaml:"usage"`
+	Audit     AuditConfig     `yaml:"audit"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Admin     AdminConfig     `yaml:"admin"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	Tools     ToolsConfig     `yaml:"tools"`
+	Storage   StorageConfig   `yaml:"storage"`
+	SSE       SSEConfig       `yaml:"sse"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	Stdio     StdioConfig     `yaml:"stdio"`
+	Gateway   GatewayConfig   `yaml:"gateway"`
+}
+
+// ServerConfig configures the listener and HTTP server
+type ServerConfig struct {
+	// Port is used when Listen is empty
+	Port int `yaml:"port" env:"PORT" flag:"port"`
+	// Listen is ":8080", "unix:/path/to.sock", "systemd" or "systemd:<name>"
+	Listen string `yaml:"listen" env:"MCP_LISTEN" flag:"listen"`
+	// Mode is "http" or "stream" (newline-delimited JSON-RPC)
+	Mode        string `yaml:"mode" env:"MCP_LISTEN_MODE" flag:"listen-mode"`
+	SocketMode  string `yaml:"socket_mode" env:"MCP_SOCKET_MODE"`
+	SocketOwner string `yaml:"socket_owner" env:"MCP_SOCKET_OWNER"`
+
+	ReadHeaderTimeout   Duration `yaml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	WriteTimeout        Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout         Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	ShutdownGracePeriod Duration `yaml:"shutdown_grace_period" env:"SHUTDOWN_GRACE_PERIOD" flag:"shutdown-grace-period"`
+	// ShutdownDelay is how long /readyz fails before shutdown begins, so
+	// load balancers stop sending traffic first
+	ShutdownDelay Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+	// HealthCheckTimeout bounds each check of /readyz
+	HealthCheckTimeout Duration `yaml:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+
+	// ConfigReloadInterval is how often the config file is checked for
+	// changes; 0 disables watching (SIGHUP still reloads)
+	ConfigReloadInterval Duration `yaml:"config_reload_interval" env:"MCP_CONFIG_RELOAD_INTERVAL"`
+}
+
+// TLSConfig enables native TLS when CertFile is set
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file" env:"MCP_TLS_CERT_FILE" flag:"tls-cert"`
+	KeyFile      string `yaml:"key_file" env:"MCP_TLS_KEY_FILE" flag:"tls-key"`
+	ClientCAFile string `yaml:"client_ca_file" env:"MCP_TLS_CLIENT_CA_FILE" flag:"tls-client-ca"`
+	// ClientAuth is "none", "request" or "require"; it defaults to
+	// "require" when ClientCAFile is set
+	ClientAuth string `yaml:"client_auth" env:"MCP_TLS_CLIENT_AUTH"`
+	// ClientIdentities is a JSON file mapping certificate subjects to identities
+	ClientIdentities string   `yaml:"client_identities" env:"MCP_TLS_CLIENT_IDENTITIES"`
+	ReloadInterval   Duration `yaml:"reload_interval" env:"MCP_TLS_RELOAD_INTERVAL"`
+}
+
+// AuthConfig configures client authentication
+type AuthConfig struct {
+	// APIKey is a single plaintext key with full access
+	APIKey string `yaml:"api_key" env:"MCP_API_KEY" secret:"true" reload:"true"`
+	// Keys are hashed keys with their own scopes, tools and expiry
+	Keys []auth.Key `yaml:"keys" reload:"true"`
+	// OAuth accepts JWT access tokens from an authorization server
+	OAuth OAuthConfig `yaml:"oauth"`
+	// AuthorizationServer runs a built-in authorization server
+	AuthorizationServer AuthorizationServerConfig `yaml:"authorization_server"`
+}
+
+// AuthorizationServerConfig runs an OAuth authorization server inside the
+// remote server, for setups without an identity provider. Enabling it also
+// makes the server accept the tokens it issues.
+type AuthorizationServerConfig struct {
+	Enabled bool `yaml:"enabled" env:"MCP_AUTH_SERVER_ENABLED"`
+	// Issuer is the server's public base URL, e.g. https://mcp.example.com
+	Issuer string `yaml:"issuer" env:"MCP_AUTH_SERVER_ISSUER"`
+	// SigningKeyFile holds the PEM key that signs access tokens and is
+	// created if missing; empty uses a new key on every start
+	SigningKeyFile  string   `yaml:"signing_key_file" env:"MCP_AUTH_SERVER_SIGNING_KEY_FILE"`
+	AccessTokenTTL  Duration `yaml:"access_token_ttl" env:"MCP_AUTH_SERVER_ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL Duration `yaml:"refresh_token_ttl" env:"MCP_AUTH_SERVER_REFRESH_TOKEN_TTL"`
+	// RegisterRateLimit bounds client registrations per IP per minute
+	RegisterRateLimit int `yaml:"register_rate_limit" env:"MCP_AUTH_SERVER_REGISTER_RATE_LIMIT"`
+	// Users sign in on the consent page
+	Users []oauth.User `yaml:"users"`
+}
+
+// OAuthConfig makes the server an OAuth protected resource. Setting Issuer
+// enables it; API keys keep working alongside access tokens.
+type OAuthConfig struct {
+	// Issuer is the required iss claim
+	Issuer string `yaml:"issuer" env:"MCP_OAUTH_ISSUER"`
+	// Resource is the server's public URL, e.g. https://mcp.example.com
+	Resource string `yaml:"resource" env:"MCP_OAUTH_RESOURCE"`
+	// Audience is the required aud claim; it defaults to Resource
+	Audience string `yaml:"audience" env:"MCP_OAUTH_AUDIENCE"`
+	// AuthorizationServers are advertised in the resource metadata; they
+	// default to Issuer
+	AuthorizationServers []string `yaml:"authorization_servers" env:"MCP_OAUTH_AUTHORIZATION_SERVERS"`
+	// JWKSURL or JWKSFile holds the issuer's signing keys
+	JWKSURL             string   `yaml:"jwks_url" env:"MCP_OAUTH_JWKS_URL"`
+	JWKSFile            string   `yaml:"jwks_file" env:"MCP_OAUTH_JWKS_FILE"`
+	JWKSRefreshInterval Duration `yaml:"jwks_refresh_interval" env:"MCP_OAUTH_JWKS_REFRESH_INTERVAL"`
+	// NameClaim is the claim identifying the caller
+	NameClaim string `yaml:"name_claim" env:"MCP_OAUTH_NAME_CLAIM"`
+	// ScopeMap renames token scopes to tools:read or tools:call; other
+	// scopes are ignored
+	ScopeMap  map[string]string `yaml:"scope_map"`
+	ClockSkew Duration          `yaml:"clock_skew" env:"MCP_OAUTH_CLOCK_SKEW"`
+}
+
+// CORSConfig configures cross-origin requests
+type CORSConfig struct {
+	// Origin is the allowed origin; "*" allows any
+	Origin string `yaml:"origin" env:"CORS_ORIGIN" flag:"cors-origin" reload:"true"`
+}
+
+// RateLimitConfig configures token-bucket rate limits per caller (API
+// key, token subject or certificate identity, or IP when unauthenticated)
+type RateLimitConfig struct {
+	// RequestsPerMinute is each caller's sustained HTTP request rate
+	RequestsPerMinute int `yaml:"requests_per_minute" env:"RATE_LIMIT" flag:"rate-limit" reload:"true"`
+	// Burst is how many requests may arrive at once; 0 uses RequestsPerMinute
+	Burst int `yaml:"burst" env:"RATE_LIMIT_BURST" reload:"true"`
+	// Tools limits each caller's calls per minute of a tool; "*" applies
+	// to tools not listed. Tool limits also apply to the stdio server.
+	Tools map[string]int `yaml:"tools" reload:"true"`
+}
+
+// Limit returns the HTTP request limit
+func (c *RateLimitConfig) Limit() ratelimit.Limit {
+	return ratelimit.Limit{PerMinute: c.RequestsPerMinute, Burst: c.Burst}
+}
+
+// UsageConfig configures usage accounting and quotas. Usage is counted per
+// caller name (API key, token subject or certificate identity) and tool.
+type UsageConfig struct {
+	// File persists the counters across restarts; empty keeps them in memory
+	File string `yaml:"file" env:"MCP_USAGE_FILE"`
+	// FlushInterval is how often the counters are written to File
+	FlushInterval Duration `yaml:"flush_interval" env:"MCP_USAGE_FLUSH_INTERVAL"`
+	// RetentionDays is how many days of usage File keeps; 0 keeps everything
+	RetentionDays int `yaml:"retention_days" env:"MCP_USAGE_RETENTION_DAYS"`
+	// Quotas limits each caller's usage; "*" applies to callers not listed
+	Quotas map[string]usage.Quota `yaml:"quotas" reload:"true"`
+}
+
+// AuditConfig configures the audit log of tool calls
+type AuditConfig struct {
+	// File is the current audit log; empty disables auditing
+	File string `yaml:"file" env:"MCP_AUDIT_FILE"`
+	// MaxSizeMB rotates the file once it reaches this size; 0 disables
+	MaxSizeMB int `yaml:"max_size_mb" env:"MCP_AUDIT_MAX_SIZE_MB"`
+	// RotateInterval rotates the file once it is this old; 0 disables
+	RotateInterval Duration `yaml:"rotate_interval" env:"MCP_AUDIT_ROTATE_INTERVAL"`
+	// Redact lists the argument fields whose values are left out of the
+	// log, as "field" for every tool or "tool.field"
+	Redact []string `yaml:"redact" env:"MCP_AUDIT_REDACT" reload:"true"`
+}
+
+// Options returns the audit log options
+func (c *AuditConfig) Options() audit.Options {
+	return audit.Options{
+		File:           c.File,
+		MaxSize:        int64(c.MaxSizeMB) << 20,
+		RotateInterval: c.RotateInterval.Duration(),
+	}
+}
+
+// MetricsConfig configures the Prometheus endpoint of the remote server
+type MetricsConfig struct {
+	// Enabled serves /metrics
+	Enabled bool `yaml:"enabled" env:"MCP_METRICS_ENABLED"`
+	// RequireAuth limits /metrics to callers with the admin scope
+	RequireAuth bool `yaml:"require_auth" env:"MCP_METRICS_REQUIRE_AUTH"`
+}
+
+// AdminConfig configures the admin API of the remote server
+type AdminConfig struct {
+	// Enabled serves /admin/sessions, /admin/tools and the other admin
+	// endpoints to callers with the admin scope
+	Enabled bool `yaml:"enabled" env:"MCP_ADMIN_ENABLED"`
+}
+
+// TracingConfig configures OpenTelemetry tracing of JSON-RPC requests and
+// tool calls
+type TracingConfig struct {
+	// Exporter is "none", "otlp", "stdout" or "file"
+	Exporter string `yaml:"exporter" env:"MCP_TRACING_EXPORTER" flag:"tracing"`
+	// Endpoint is the OTLP/HTTP endpoint, e.g. http://localhost:4318; empty
+	// uses OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint string `yaml:"endpoint" env:"MCP_TRACING_ENDPOINT"`
+	// File is where the file exporter writes spans
+	File        string  `yaml:"file" env:"MCP_TRACING_FILE"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"MCP_TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" env:"MCP_TRACING_SERVICE_NAME"`
+}
+
+// Options returns the telemetry options; stdout is where the stdout
+// exporter writes
+func (c *TracingConfig) Options(stdout io.Writer) telemetry.Options {
+	return telemetry.Options{
+		Exporter:       c.Exporter,
+		Endpoint:       c.Endpoint,
+		File:           c.File,
+		SampleRatio:    c.SampleRatio,
+		ServiceName:    c.ServiceName,
+		ServiceVersion: mcp.ServerVersion,
+		Stdout:         stdout,
+	}
+}
+
+// LoggingConfig configures the log output of the server binaries
+type LoggingConfig struct {
+	// Format is "text" or "json"
+	Format string `yaml:"format" env:"MCP_LOG_FORMAT" flag:"log-format"`
+	// Level is "debug", "info", "warn" or "error"
+	Level string `yaml:"level" env:"MCP_LOG_LEVEL" flag:"log-level" reload:"true"`
+	// Levels overrides Level per component, e.g. {mcp: debug, http: warn}
+	Levels map[string]string `yaml:"levels" reload:"true"`
+}
+
+// Options returns the logging options; output is where records are written
+func (c *LoggingConfig) Options(output io.Writer) logging.Options {
+	return logging.Options{
+		Format: c.Format,
+		Level:  c.Level,
+		Levels: c.Levels,
+		Output: output,
+	}
+}
+
+// ToolsConfig selects the built-in tools
+type ToolsConfig struct {
+	// Enabled lists the tools to register; empty registers all of them
+	Enabled []string `yaml:"enabled" env:"MCP_TOOLS" flag:"tools" reload:"true"`
+}
+
+// StorageConfig selects where the storage tools keep their data
+type StorageConfig struct {
+	// Backend is "memory" or "file"
+	Backend string `yaml:"backend" env:"MCP_STORAGE_BACKEND" flag:"storage"`
+	// File is where the file backend keeps the data
+	File string `yaml:"file" env:"MCP_STORAGE_FILE" flag:"storage-file"`
+}
+
+// Open opens the selected backend
+func (c *StorageConfig) Open() (tools.Store, error) {
+	switch c.Backend {
+	case "memory":
+		return tools.NewMemoryStore(), nil
+	case "file":
+		return tools.OpenFileStore(c.File)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", c.Backend)
+	}
+}
+
+// SSEConfig configures SSE keepalive, where 0 disables a timer, and the
+// message size limit
+type SSEConfig struct {
+	HeartbeatInterval Duration `yaml:"heartbeat_interval" env:"SSE_HEARTBEAT_INTERVAL"`
+	WriteTimeout      Duration `yaml:"write_timeout" env:"SSE_WRITE_TIMEOUT"`
+	IdleTimeout       Duration `yaml:"idle_timeout" env:"SSE_IDLE_TIMEOUT"`
+	MaxLifetime       Duration `yaml:"max_lifetime" env:"SSE_MAX_LIFETIME"`
+	// MaxMessageSize is the largest message body posted to /message in bytes
+	MaxMessageSize int64 `yaml:"max_message_size" env:"SSE_MAX_MESSAGE_SIZE"`
+}
+
+// WebSocketConfig configures the /ws transport
+type WebSocketConfig struct {
+	PingInterval   Duration `yaml:"ping_interval" env:"WS_PING_INTERVAL"`
+	PongTimeout    Duration `yaml:"pong_timeout" env:"WS_PONG_TIMEOUT"`
+	WriteTimeout   Duration `yaml:"write_timeout" env:"WS_WRITE_TIMEOUT"`
+	MaxMessageSize int64    `yaml:"max_message_size" env:"WS_MAX_MESSAGE_SIZE"`
+	Compression    bool     `yaml:"compression" env:"WS_COMPRESSION"`
+}
+
+// StdioConfig configures the stdio transport of the local server
+type StdioConfig struct {
+	// Framing is "auto", "newline" or "content-length"
+	Framing string `yaml:"framing" env:"MCP_STDIO_FRAMING" flag:"framing"`
+	// MaxMessageSize is the largest message accepted in bytes; 0 uses the default
+	MaxMessageSize int `yaml:"max_message_size" env:"MCP_MAX_MESSAGE_SIZE"`
+}
+
+// GatewayConfig points at the upstream servers to aggregate
+type GatewayConfig struct {
+	// Config is the path of the gateway's JSON upstream list
+	Config string `yaml:"config" env:"MCP_GATEWAY_CONFIG" flag:"gateway-config"`
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	sse := mcp.DefaultSSEOptions()
+	ws := mcp.DefaultWebSocketOptions()
+
+	return &Config{
+		Server: ServerConfig{
+			Port:                 8080,
+			Mode:                 "http",
+			SocketMode:           "0660",
+			ReadHeaderTimeout:    Duration(10 * time.Second),
+			WriteTimeout:         Duration(60 * time.Second),
+			IdleTimeout:          Duration(120 * time.Second),
+			ShutdownGracePeriod:  Duration(30 * time.Second),
+			HealthCheckTimeout:   Duration(health.DefaultTimeout),
+			ConfigReloadInterval: Duration(5 * time.Second),
+		},
+		TLS: TLSConfig{
+			ReloadInterval: Duration(10 * time.Second),
+		},
+		Auth: AuthConfig{
+			OAuth: OAuthConfig{
+				JWKSRefreshInterval: Duration(time.Hour),
+				NameClaim:           "sub",
+				ClockSkew:           Duration(30 * time.Second),
+			},
+			AuthorizationServer: AuthorizationServerConfig{
+				AccessTokenTTL:    Duration(time.Hour),
+				RefreshTokenTTL:   Duration(30 * 24 * time.Hour),
+				RegisterRateLimit: 10,
+			},
+		},
+		CORS:      CORSConfig{Origin: "*"},
+		RateLimit: RateLimitConfig{RequestsPerMinute: 100},
+		Usage: UsageConfig{
+			FlushInterval: Duration(time.Minute),
+			RetentionDays: 400,
+		},
+		Audit: AuditConfig{
+			MaxSizeMB:      100,
+			RotateInterval: Duration(24 * time.Hour),
+			Redact:         []string{"storage_set.value"},
+		},
+		Metrics: MetricsConfig{Enabled: true},
+		Admin:   AdminConfig{Enabled: true},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: mcp.ServerName,
+		},
+		Logging: LoggingConfig{
+			Format: logging.FormatText,
+			Level:  "info",
+		},
+		Storage: StorageConfig{Backend: "memory"},
+		SSE: SSEConfig{
+			HeartbeatInterval: Duration(sse.HeartbeatInterval),
+			WriteTimeout:      Duration(sse.WriteTimeout),
+			IdleTimeout:       Duration(sse.IdleTimeout),
+			MaxLifetime:       Duration(sse.MaxLifetime),
+			MaxMessageSize:    sse.MaxMessageSize,
+		},
+		WebSocket: WebSocketConfig{
+			PingInterval:   Duration(ws.PingInterval),
+			PongTimeout:    Duration(ws.PongTimeout),
+			WriteTimeout:   Duration(ws.WriteTimeout),
+			MaxMessageSize: ws.MaxMessageSize,
+		},
+		Stdio: StdioConfig{Framing: "auto"},
+	}
+}
+
+// ListenAddr returns the address to listen on
+func (c *ServerConfig) ListenAddr() string {
+	if c.Listen != "" {
+		return c.Listen
+	}
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// SocketOptions returns the Unix socket permissions
+func (c *ServerConfig) SocketOptions() listener.UnixSocketOptions {
+	// Validate has already checked the mode
+	mode, _ := strconv.ParseUint(c.SocketMode, 8, 32)
+	return listener.UnixSocketOptions{Mode: os.FileMode(mode), Owner: c.SocketOwner}
+}
+
+// KeyStore returns a key store holding APIKey, if set, and Keys
+func (c *AuthConfig) KeyStore() (*auth.KeyStore, error) {
+	keys := c.Keys
+	if c.APIKey != "" {
+		keys = append([]auth.Key{auth.LegacyKey(c.APIKey)}, keys...)
+	}
+	return auth.NewKeyStore(keys)
+}
+
+// Enabled reports whether access tokens are accepted
+func (c *OAuthConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// ValidatorOptions returns the options for an oauth.Validator checking
+// signatures with keys
+func (c *OAuthConfig) ValidatorOptions(keys *oauth.KeySet) oauth.ValidatorOptions {
+	return oauth.ValidatorOptions{
+		Issuer:    c.Issuer,
+		Audience:  c.Audience,
+		Keys:      keys,
+		NameClaim: c.NameClaim,
+		ScopeMap:  c.ScopeMap,
+		ClockSkew: c.ClockSkew.Duration(),
+	}
+}
+
+// Metadata returns the protected resource metadata to serve
+func (c *OAuthConfig) Metadata() oauth.ResourceMetadata {
+	return oauth.ResourceMetadata{
+		Resource:               c.Resource,
+		AuthorizationServers:   c.AuthorizationServers,
+		ScopesSupported:        []string{auth.ScopeToolsRead, auth.ScopeToolsCall},
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           "Go MCP Server",
+	}
+}
+
+// ServerOptions returns the options for an oauth.AuthorizationServer
+// issuing tokens for resource
+func (c *AuthorizationServerConfig) ServerOptions(signer *oauth.Signer, resource string) oauth.ServerOptions {
+	return oauth.ServerOptions{
+		Issuer:          c.Issuer,
+		Resource:        resource,
+		Signer:          signer,
+		Users:           c.Users,
+		AccessTokenTTL:  c.AccessTokenTTL.Duration(),
+		RefreshTokenTTL: c.RefreshTokenTTL.Duration(),
+	}
+}
+
+// Enabled reports whether native TLS is configured
+func (c *TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Options returns the options for a tlsconfig.Reloader
+func (c *TLSConfig) Options() tlsconfig.Options {
+	return tlsconfig.Options{
+		CertFile:       c.CertFile,
+		KeyFile:        c.KeyFile,
+		ClientCAFile:   c.ClientCAFile,
+		ClientAuth:     c.ClientAuth,
+		ReloadInterval: c.ReloadInterval.Duration(),
+	}
+}
+
+// Options returns the SSE transport options
+func (c *SSEConfig) Options() mcp.SSEOptions {
+	return mcp.SSEOptions{
+		HeartbeatInterval: c.HeartbeatInterval.Duration(),
+		WriteTimeout:      c.WriteTimeout.Duration(),
+		IdleTimeout:       c.IdleTimeout.Duration(),
+		MaxLifetime:       c.MaxLifetime.Duration(),
+		MaxMessageSize:    c.MaxMessageSize,
+	}
+}
+
+// Options returns the WebSocket transport options, without an origin check
+func (c *WebSocketConfig) Options() mcp.WebSocketOptions {
+	return mcp.WebSocketOptions{
+		PingInterval:      c.PingInterval.Duration(),
+		PongTimeout:       c.PongTimeout.Duration(),
+		WriteTimeout:      c.WriteTimeout.Duration(),
+		MaxMessageSize:    c.MaxMessageSize,
+		EnableCompression: c.Compression,
+	}
+}
+
+// Options returns the stdio transport options
+func (c *StdioConfig) Options() []mcp.StdioOption {
+	// Validate has already checked the framing
+	framing, _ := mcp.ParseFraming(c.Framing)
+	options := []mcp.StdioOption{mcp.WithFraming(framing)}
+	if c.MaxMessageSize > 0 {
+		options = append(options, mcp.WithMaxMessageSize(c.MaxMessageSize))
+	}
+	return options
+}
+
+// Duration is a time.Duration written as "30s" in YAML, environment
+// variables and flags
+type Duration time.Duration
+
+// Duration returns d as a time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// String formats d like time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalYAML writes d as a duration string
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// UnmarshalYAML parses a duration string such as "1m30s"
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Flags collects configuration overrides given on the command line
+type Flags struct {
+	values map[string]string
+}
+
+// RegisterFlags defines a flag on fs for every configuration field with a
+// flag tag. Pass the result to Load once fs has been parsed.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{values: make(map[string]string)}
+	visitFields(reflect.ValueOf(Default()).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("flag")
+		if name == "" {
+			return
+		}
+		usage := fmt.Sprintf("override %s", path)
+		if env := field.Tag.Get("env"); env != "" {
+			usage += fmt.Sprintf(" (env %s)", env)
+		}
+		fs.Func(name, usage, func(v string) error {
+			// Parse now so mistakes are reported next to the flag
+			if err := setField(reflect.New(value.Type()).Elem(), v); err != nil {
+				return err
+			}
+			flags.values[path] = v
+			return nil
+		})
+	})
+	return flags
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, the YAML file at path (if path is not empty), environment
+// variables and flags. The result is validated.
+func Load(path string, flags *Flags) (*Config, error) {
+	config := Default()
+
+	if path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if flags != nil {
+		if err := config.applyFlags(flags); err != nil {
+			return nil, err
+		}
+	}
+
+	config.normalize()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// loadFile merges a YAML file over the configuration. Unknown keys are
+// errors, so typos don't silently fall back to defaults.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides fields from their environment variables. Empty
+// variables are ignored, like unset ones.
+func (c *Config) applyEnv() error {
+	var errs []error
+	visitFields(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("env")
+		if name == "" {
+			return
+		}
+		v := os.Getenv(name)
+		if v == "" {
+			return
+		}
+		if err := setField(value, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", name, path, err))
+		}
+	})
+	return joinErrors("invalid environment", errs)
+}
+
+// applyFlags overrides fields set on the command line
+func (c *Config) applyFlags(flags *Flags) error {
+	var errs []error
+	visitFields(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		v, ok := flags.values[path]
+		if !ok {
+			return
+		}
+		if err := setField(value, v); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", field.Tag.Get("flag"), err))
+		}
+	})
+	return joinErrors("invalid flags", errs)
+}
+
+// normalize fills in values that depend on other fields
+func (c *Config) normalize() {
+	if c.TLS.ClientAuth == "" {
+		c.TLS.ClientAuth = "none"
+		if c.TLS.ClientCAFile != "" {
+			c.TLS.ClientAuth = "require"
+		}
+	}
+	// The built-in authorization server issues tokens for this server
+	if as := &c.Auth.AuthorizationServer; as.Enabled {
+		as.Issuer = strings.TrimSuffix(as.Issuer, "/")
+		if c.Auth.OAuth.Issuer == "" {
+			c.Auth.OAuth.Issuer = as.Issuer
+		}
+		if c.Auth.OAuth.Resource == "" {
+			c.Auth.OAuth.Resource = as.Issuer
+		}
+	}
+	if o := &c.Auth.OAuth; o.Enabled() {
+		if o.Audience == "" {
+			o.Audience = o.Resource
+		}
+		if len(o.AuthorizationServers) == 0 {
+			o.AuthorizationServers = []string{o.Issuer}
+		}
+	}
+}
+
+// visitFields calls fn for every leaf field under v, with its dotted YAML path
+func visitFields(v reflect.Value, prefix string, fn func(path string, field reflect.StructField, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct {
+			visitFields(value, path, fn)
+			continue
+		}
+		fn(path, field, value)
+	}
+}
+
+// setField parses s into a field. Lists are comma-separated.
+func setField(value reflect.Value, s string) error {
+	if value.Type() == reflect.TypeOf(Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		value.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", value.Type())
+	}
+	return nil
+}
+
+// joinErrors combines errs under a heading, one per line
+func joinErrors(heading string, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "  " + err.Error()
+	}
+	return fmt.Errorf("%s:\n%s", heading, strings.Join(lines, "\n"))
+}
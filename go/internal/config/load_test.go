@@ -0,0 +1,76 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  port: 8081\nrate_limit:\n  requests_per_minute: 10\n  burst: 3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		args      []string
+		wantPort  int
+		wantRate  int
+		wantBurst int
+	}{
+		{"file", nil, nil, 8081, 10, 3},
+		{"env over file", map[string]string{"PORT": "8082", "RATE_LIMIT": "20"}, nil, 8082, 20, 3},
+		{"flags over env", map[string]string{"PORT": "8082", "RATE_LIMIT": "20"}, []string{"-port", "8083"}, 8083, 20, 3},
+		{"empty env ignored", map[string]string{"PORT": "", "RATE_LIMIT_BURST": ""}, nil, 8081, 10, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"PORT", "RATE_LIMIT", "RATE_LIMIT_BURST"} {
+				t.Setenv(name, tt.env[name])
+			}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags := RegisterFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path, flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Port != tt.wantPort || cfg.RateLimit.RequestsPerMinute != tt.wantRate || cfg.RateLimit.Burst != tt.wantBurst {
+				t.Errorf("port %d, rate %d, burst %d; want %d, %d, %d",
+					cfg.Server.Port, cfg.RateLimit.RequestsPerMinute, cfg.RateLimit.Burst,
+					tt.wantPort, tt.wantRate, tt.wantBurst)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  map[string]string
+	}{
+		{"unknown field", "sever:\n  port: 8080\n", nil},
+		{"invalid value", "server:\n  port: 70000\n", nil},
+		{"invalid env", "", map[string]string{"PORT": "eighty"}},
+		{"invalid duration", "server:\n  write_timeout: soon\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PORT", tt.env["PORT"])
+			path := filepath.Join(t.TempDir(), "server.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path, nil); err == nil {
+				t.Error("Load() succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+)
+
+// logger is the logger of the "config" component
+var logger = logging.Component("config")
+
+// Reloader holds the running configuration and replaces it when the config
+// file is reloaded. Only fields tagged reload are taken from the new file;
+// changes to the others are reported as needing a restart.
+type Reloader struct {
+	path  string
+	flags *Flags
+	check func(*Config) error
+
+	current atomic.Pointer[Config]
+
+	mu       sync.Mutex
+	modTime  time.Time
+	onReload []func(previous, current *Config)
+}
+
+// NewReloader creates a Reloader for the configuration loaded from path
+// with flags. check, if not nil, adds requirements of the caller that a
+// reloaded configuration must also meet.
+func NewReloader(path string, flags *Flags, initial *Config, check func(*Config) error) *Reloader {
+	r := &Reloader{path: path, flags: flags, check: check}
+	r.current.Store(initial)
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	return r
+}
+
+// Current returns the configuration in effect. It must not be modified.
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// OnReload registers fn to be called after a reload changed the configuration
+func (r *Reloader) OnReload(fn func(previous, current *Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onReload = append(r.onReload, fn)
+}
+
+// Reload reads the configuration again and swaps in its reloadable fields.
+// An invalid configuration is rejected and the running one is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if info, err := os.Stat(r.path); err == nil {
+		r.modTime = info.ModTime()
+	}
+
+	loaded, err := Load(r.path, r.flags)
+	if err != nil {
+		return err
+	}
+	if r.check != nil {
+		if err := r.check(loaded); err != nil {
+			return err
+		}
+	}
+
+	previous := r.current.Load()
+	next, reloaded, ignored := merge(previous, loaded)
+	for _, path := range ignored {
+		logger.Warn("Config reload: restart to apply", "field", path)
+	}
+	if len(reloaded) == 0 {
+		logger.Info("Config reload: no changes to apply")
+		return nil
+	}
+
+	r.current.Store(next)
+	logger.Info("Config reloaded", "fields", reloaded)
+	for _, fn := range r.onReload {
+		fn(previous, next)
+	}
+	return nil
+}
+
+// Watch reloads the configuration whenever the file's modification time
+// changes, checking every interval until ctx is cancelled
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	if r.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		info, err := os.Stat(r.path)
+		if err != nil {
+			continue
+		}
+		r.mu.Lock()
+		changed := !info.ModTime().Equal(r.modTime)
+		r.mu.Unlock()
+		if !changed {
+			continue
+		}
+
+		if err := r.Reload(); err != nil {
+			logger.Error("Config reload rejected, keeping the running config", "error", err)
+		}
+	}
+}
+
+// merge returns previous with the reloadable fields of loaded applied, the
+// paths of the fields that changed, and the paths of changed fields that
+// can't be reloaded
+func merge(previous, loaded *Config) (*Config, []string, []string) {
+	next := *previous
+	var reloaded, ignored []string
+
+	loadedFields := make(map[string]reflect.Value)
+	visitFields(reflect.ValueOf(loaded).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		loadedFields[path] = value
+	})
+
+	visitFields(reflect.ValueOf(&next).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		newValue := loadedFields[path]
+		if reflect.DeepEqual(value.Interface(), newValue.Interface()) {
+			return
+		}
+		if field.Tag.Get("reload") != "true" {
+			ignored = append(ignored, path)
+			return
+		}
+		value.Set(newValue)
+		if field.Tag.Get("secret") == "true" {
+			reloaded = append(reloaded, path+" (secret)")
+		} else {
+			reloaded = append(reloaded, fmt.Sprintf("%s=%v", path, newValue.Interface()))
+		}
+	})
+	return &next, reloaded, ignored
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name         string
+		change       func(c *Config)
+		want         func(c *Config) // applied to the previous config, nil if unchanged
+		wantReloaded []string
+		wantIgnored  []string
+	}{
+		{
+			name:   "no changes",
+			change: func(c *Config) {},
+		},
+		{
+			name:         "reloadable field",
+			change:       func(c *Config) { c.RateLimit.RequestsPerMinute = 10 },
+			want:         func(c *Config) { c.RateLimit.RequestsPerMinute = 10 },
+			wantReloaded: []string{"rate_limit.requests_per_minute=10"},
+		},
+		{
+			name:        "restart field",
+			change:      func(c *Config) { c.Server.Port = 9090 },
+			wantIgnored: []string{"server.port"},
+		},
+		{
+			name: "both",
+			change: func(c *Config) {
+				c.CORS.Origin = "https://app.example.com"
+				c.Audit.File = "/var/lib/mcp/audit.jsonl"
+			},
+			want:         func(c *Config) { c.CORS.Origin = "https://app.example.com" },
+			wantReloaded: []string{"cors.origin=https://app.example.com"},
+			wantIgnored:  []string{"audit.file"},
+		},
+		{
+			name:         "secret",
+			change:       func(c *Config) { c.Auth.APIKey = "new-key" },
+			want:         func(c *Config) { c.Auth.APIKey = "new-key" },
+			wantReloaded: []string{"auth.api_key (secret)"},
+		},
+		{
+			name:         "map",
+			change:       func(c *Config) { c.RateLimit.Tools = map[string]int{"*": 5} },
+			want:         func(c *Config) { c.RateLimit.Tools = map[string]int{"*": 5} },
+			wantReloaded: []string{"rate_limit.tools=map[*:5]"},
+		},
+		{
+			name: "nested restart fields",
+			change: func(c *Config) {
+				c.Auth.OAuth.Issuer = "https://issuer.example.com"
+				c.Auth.AuthorizationServer.Enabled = true
+			},
+			wantIgnored: []string{"auth.oauth.issuer", "auth.authorization_server.enabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := Default()
+			loaded := Default()
+			tt.change(loaded)
+
+			next, reloaded, ignored := merge(previous, loaded)
+
+			want := Default()
+			if tt.want != nil {
+				tt.want(want)
+			}
+			if !reflect.DeepEqual(next, want) {
+				t.Errorf("merge() config = %+v, want %+v", next, want)
+			}
+			if !slices.Equal(reloaded, tt.wantReloaded) {
+				t.Errorf("merge() reloaded = %v, want %v", reloaded, tt.wantReloaded)
+			}
+			if !slices.Equal(ignored, tt.wantIgnored) {
+				t.Errorf("merge() ignored = %v, want %v", ignored, tt.wantIgnored)
+			}
+			if !reflect.DeepEqual(previous, Default()) {
+				t.Error("merge() changed the previous config")
+			}
+		})
+	}
+}
+
+func TestReloader(t *testing.T) {
+	// Keep the environment from overriding the file
+	for _, name := range []string{"PORT", "RATE_LIMIT", "MCP_TOOLS", "MCP_API_KEY"} {
+		t.Setenv(name, "")
+	}
+
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	write := func(yaml string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("server:\n  port: 8080\nrate_limit:\n  requests_per_minute: 60\n")
+	initial, err := Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errRejected := errors.New("rejected by check")
+	r := NewReloader(path, nil, initial, func(c *Config) error {
+		if slices.Contains(c.Tools.Enabled, "forbidden") {
+			return errRejected
+		}
+		return nil
+	})
+	var reloads int
+	r.OnReload(func(previous, current *Config) { reloads++ })
+
+	tests := []struct {
+		name        string
+		yaml        string
+		wantErr     bool
+		wantPort    int
+		wantRate    int
+		wantKeys    int
+		wantReloads int
+	}{
+		{
+			name:        "reloadable change",
+			yaml:        "server:\n  port: 8080\nrate_limit:\n  requests_per_minute: 10\n",
+			wantPort:    8080,
+			wantRate:    10,
+			wantReloads: 1,
+		},
+		{
+			name:        "restart field kept",
+			yaml:        "server:\n  port: 9090\nrate_limit:\n  requests_per_minute: 10\n",
+			wantPort:    8080,
+			wantRate:    10,
+			wantReloads: 1,
+		},
+		{
+			name: "keys",
+			yaml: "server:\n  port: 8080\nrate_limit:\n  requests_per_minute: 10\nauth:\n  keys:\n" +
+				"    - name: ci\n      hash: " + auth.HashKey("ci-key") + "\n",
+			wantPort:    8080,
+			wantRate:    10,
+			wantKeys:    1,
+			wantReloads: 2,
+		},
+		{
+			name:        "invalid file",
+			yaml:        "server:\n  port: 8080\nrate_limit:\n  requests_per_minute: -1\n",
+			wantErr:     true,
+			wantPort:    8080,
+			wantRate:    10,
+			wantKeys:    1,
+			wantReloads: 2,
+		},
+		{
+			name:        "unknown field",
+			yaml:        "rate_limt:\n  requests_per_minute: 5\n",
+			wantErr:     true,
+			wantPort:    8080,
+			wantRate:    10,
+			wantKeys:    1,
+			wantReloads: 2,
+		},
+		{
+			name:        "rejected by check",
+			yaml:        "server:\n  port: 8080\ntools:\n  enabled: [forbidden]\n",
+			wantErr:     true,
+			wantPort:    8080,
+			wantRate:    10,
+			wantKeys:    1,
+			wantReloads: 2,
+		},
+	}
+	// The cases run in order, each reloading over the previous one
+	for _, tt := range tests {
+		write(tt.yaml)
+		err := r.Reload()
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: Reload() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		current := r.Current()
+		if current.Server.Port != tt.wantPort || current.RateLimit.RequestsPerMinute != tt.wantRate || len(current.Auth.Keys) != tt.wantKeys {
+			t.Errorf("%s: port %d, rate %d, %d keys; want %d, %d, %d", tt.name,
+				current.Server.Port, current.RateLimit.RequestsPerMinute, len(current.Auth.Keys),
+				tt.wantPort, tt.wantRate, tt.wantKeys)
+		}
+		if reloads != tt.wantReloads {
+			t.Errorf("%s: %d reloads, want %d", tt.name, reloads, tt.wantReloads)
+		}
+	}
+	if initial.RateLimit.RequestsPerMinute != 60 {
+		t.Error("Reload() changed the initial config")
+	}
+}
+
+func TestReloaderWatch(t *testing.T) {
+	t.Setenv("RATE_LIMIT", "")
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(path, []byte("rate_limit:\n  requests_per_minute: 60\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	initial, err := Load(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReloader(path, nil, initial, nil)
+	reloaded := make(chan *Config, 1)
+	r.OnReload(func(previous, current *Config) { reloaded <- current })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	if err := os.WriteFile(path, []byte("rate_limit:\n  requests_per_minute: 5\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// Make the change visible even on filesystems with coarse timestamps
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case current := <-reloaded:
+		if current.RateLimit.RequestsPerMinute != 5 {
+			t.Errorf("requests_per_minute = %d, want 5", current.RateLimit.RequestsPerMinute)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() didn't reload the changed file")
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/listener"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/oauth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/telemetry"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/tlsconfig"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/tools"
+)
+
+// Validate checks the configuration, reporting every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, path, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	// Server
+	check(c.Server.Listen != "" || (c.Server.Port > 0 && c.Server.Port < 65536),
+		"server.port", "must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.Mode == "http" || c.Server.Mode == "stream",
+		"server.mode", "must be http or stream, got %q", c.Server.Mode)
+	// The stream mode has no authentication of its own and relies on
+	// socket permissions, so it is not offered on plain TCP addresses.
+	// systemd sockets are checked once they are open.
+	check(c.Server.Mode != "stream" || !listener.IsTCPAddress(c.Server.ListenAddr()),
+		"server.mode", "stream requires a unix: or systemd listen address")
+	_, err := strconv.ParseUint(c.Server.SocketMode, 8, 32)
+	check(err == nil, "server.socket_mode", "must be an octal file mode, got %q", c.Server.SocketMode)
+	check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout", "must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout", "must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout", "must not be negative")
+	check(c.Server.ShutdownGracePeriod >= 0, "server.shutdown_grace_period", "must not be negative")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay", "must not be negative")
+	check(c.Server.HealthCheckTimeout > 0, "server.health_check_timeout", "must be positive")
+	check(c.Server.ConfigReloadInterval >= 0, "server.config_reload_interval", "must not be negative")
+
+	// TLS
+	if c.TLS.Enabled() {
+		check(c.Server.Mode == "http", "tls.cert_file", "requires server.mode http")
+		check(c.TLS.KeyFile != "", "tls.key_file", "is required with tls.cert_file")
+	} else {
+		check(c.TLS.KeyFile == "" && c.TLS.ClientCAFile == "", "tls.cert_file", "is required with the other tls settings")
+	}
+	_, err = tlsconfig.ParseClientAuth(c.TLS.ClientAuth)
+	check(err == nil, "tls.client_auth", "must be none, request or require, got %q", c.TLS.ClientAuth)
+	check(c.TLS.ClientAuth == "none" || c.TLS.ClientCAFile != "",
+		"tls.client_ca_file", "is required with tls.client_auth %s", c.TLS.ClientAuth)
+	check(c.TLS.ReloadInterval >= 0, "tls.reload_interval", "must not be negative")
+
+	// Auth
+	_, err = c.Auth.KeyStore()
+	check(err == nil, "auth.keys", "%v", err)
+	if o := c.Auth.OAuth; o.Enabled() {
+		resource, err := url.Parse(o.Resource)
+		check(err == nil && resource.IsAbs() && resource.Host != "", "auth.oauth.resource",
+			"must be the server's absolute URL, got %q", o.Resource)
+		if !c.Auth.AuthorizationServer.Enabled {
+			check((o.JWKSURL == "") != (o.JWKSFile == ""), "auth.oauth.jwks_url", "exactly one of jwks_url and jwks_file is required")
+		}
+		check(o.JWKSRefreshInterval >= 0, "auth.oauth.jwks_refresh_interval", "must not be negative")
+		check(o.NameClaim != "", "auth.oauth.name_claim", "must not be empty")
+		check(o.ClockSkew >= 0, "auth.oauth.clock_skew", "must not be negative")
+		for from, to := range o.ScopeMap {
+			check(to == auth.ScopeToolsRead || to == auth.ScopeToolsCall, "auth.oauth.scope_map",
+				"%s must map to %s or %s, got %q", from, auth.ScopeToolsRead, auth.ScopeToolsCall, to)
+		}
+	} else {
+		check(o.Resource == "" && o.JWKSURL == "" && o.JWKSFile == "", "auth.oauth.issuer", "is required with the other auth.oauth settings")
+	}
+
+	if as := c.Auth.AuthorizationServer; as.Enabled {
+		issuer, err := url.Parse(as.Issuer)
+		check(err == nil && issuer.IsAbs() && issuer.Host != "" && issuer.Path == "", "auth.authorization_server.issuer",
+			"must be the server's base URL without a path, got %q", as.Issuer)
+		check(c.Auth.OAuth.Issuer == as.Issuer, "auth.oauth.issuer",
+			"must be empty or match auth.authorization_server.issuer when the authorization server is enabled")
+		check(c.Auth.OAuth.JWKSURL == "" && c.Auth.OAuth.JWKSFile == "", "auth.oauth.jwks_url",
+			"must be empty when the authorization server is enabled")
+		check(as.AccessTokenTTL > 0, "auth.authorization_server.access_token_ttl", "must be positive")
+		check(as.RefreshTokenTTL > 0, "auth.authorization_server.refresh_token_ttl", "must be positive")
+		check(as.RegisterRateLimit > 0, "auth.authorization_server.register_rate_limit", "must be positive")
+		check(len(as.Users) > 0, "auth.authorization_server.users", "at least one user is required")
+		_, err = oauth.NewAuthorizationServer(as.ServerOptions(nil, ""))
+		check(err == nil, "auth.authorization_server.users", "%v", err)
+	}
+
+	// Rate limit
+	check(c.RateLimit.RequestsPerMinute > 0, "rate_limit.requests_per_minute", "must be positive, got %d", c.RateLimit.RequestsPerMinute)
+	check(c.RateLimit.Burst >= 0, "rate_limit.burst", "must not be negative, got %d", c.RateLimit.Burst)
+	for name, limit := range c.RateLimit.Tools {
+		check(limit > 0, "rate_limit.tools", "%s must be positive, got %d", name, limit)
+	}
+
+	// Usage
+	check(c.Usage.FlushInterval >= 0, "usage.flush_interval", "must not be negative")
+	check(c.Usage.RetentionDays >= 0, "usage.retention_days", "must not be negative, got %d", c.Usage.RetentionDays)
+	for name, quota := range c.Usage.Quotas {
+		err := quota.Validate()
+		check(err == nil, "usage.quotas", "%s: %v", name, err)
+	}
+
+	// Audit
+	check(c.Audit.MaxSizeMB >= 0, "audit.max_size_mb", "must not be negative, got %d", c.Audit.MaxSizeMB)
+	check(c.Audit.RotateInterval >= 0, "audit.rotate_interval", "must not be negative")
+	for _, rule := range c.Audit.Redact {
+		check(rule != "" && !strings.HasSuffix(rule, "."), "audit.redact", "invalid field %q", rule)
+	}
+
+	// Tracing
+	switch t := c.Tracing; t.Exporter {
+	case telemetry.ExporterNone, telemetry.ExporterOTLP, telemetry.ExporterStdout:
+	case telemetry.ExporterFile:
+		check(t.File != "", "tracing.file", "is required with the file exporter")
+	default:
+		check(false, "tracing.exporter", "must be none, otlp, stdout or file, got %q", t.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio",
+		"must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	check(c.Tracing.ServiceName != "", "tracing.service_name", "must not be empty")
+
+	// Logging
+	err = c.Logging.Options(nil).Validate()
+	check(err == nil, "logging", "%v", err)
+
+	// Tools
+	known := make(map[string]bool)
+	for _, name := range tools.Names() {
+		known[name] = true
+	}
+	for _, name := range c.Tools.Enabled {
+		check(known[name], "tools.enabled", "unknown tool %q (available: %v)", name, tools.Names())
+	}
+
+	// Storage
+	check(c.Storage.Backend == "memory" || c.Storage.Backend == "file", "storage.backend", "must be memory or file, got %q", c.Storage.Backend)
+	check(c.Storage.Backend != "file" || c.Storage.File != "", "storage.file", "is required with the file backend")
+
+	// Transports
+	check(c.SSE.HeartbeatInterval >= 0 && c.SSE.WriteTimeout >= 0 && c.SSE.IdleTimeout >= 0 && c.SSE.MaxLifetime >= 0,
+		"sse", "durations must not be negative")
+	check(c.SSE.MaxMessageSize > 0, "sse.max_message_size", "must be positive")
+	check(c.WebSocket.PingInterval > 0, "websocket.ping_interval", "must be positive")
+	check(c.WebSocket.PongTimeout > 0, "websocket.pong_timeout", "must be positive")
+	check(c.WebSocket.MaxMessageSize > 0, "websocket.max_message_size", "must be positive")
+	_, err = mcp.ParseFraming(c.Stdio.Framing)
+	check(err == nil, "stdio.framing", "must be auto, newline or content-length, got %q", c.Stdio.Framing)
+	check(c.Stdio.MaxMessageSize >= 0, "stdio.max_message_size", "must not be negative")
+
+	return joinErrors("invalid configuration", errs)
+}
+
+// redacted is printed in place of secrets
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the configuration with secrets replaced
+func (c *Config) Redacted() *Config {
+	copied := *c
+	copied.Tools.Enabled = append([]string(nil), c.Tools.Enabled...)
+
+	visitFields(reflect.ValueOf(&copied).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "" {
+			value.SetString(redacted)
+		}
+	})
+	return &copied
+}
+
+// Print writes the configuration as YAML with secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,99 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Config lists the upstream servers the gateway aggregates
+type Config struct {
+	Upstreams []UpstreamConfig `json:"upstreams"`
+}
+
+// UpstreamConfig describes one upstream server. Exactly one of Command and
+// URL must be set.
+type UpstreamConfig struct {
+	// Name prefixes the upstream's tools, e.g. "github" gives "github__search"
+	Name string `json:"name"`
+
+	// Command, Args, Env and Dir start a stdio server as a subprocess
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+
+	// URL, Transport and Headers connect to a remote server. Transport is
+	// "sse" or "http" (Streamable HTTP); by default URLs ending in /sse use SSE.
+	URL       string            `json:"url,omitempty"`
+	Transport string            `json:"transport,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// LoadConfig reads a gateway configuration file. ${VAR} references in
+// string values are expanded from the environment, so secrets can stay out
+// of the file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gateway config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse gateway config: %w", err)
+	}
+
+	for i := range config.Upstreams {
+		config.Upstreams[i].expandEnv()
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Validate checks that every upstream is usable and uniquely named
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	for i, upstream := range c.Upstreams {
+		if upstream.Name == "" {
+			return fmt.Errorf("upstream %d: name is required", i)
+		}
+		if strings.Contains(upstream.Name, Separator) {
+			return fmt.Errorf("upstream %s: name must not contain %q", upstream.Name, Separator)
+		}
+		if names[upstream.Name] {
+			return fmt.Errorf("upstream %s: duplicate name", upstream.Name)
+		}
+		names[upstream.Name] = true
+
+		if (upstream.Command == "") == (upstream.URL == "") {
+			return fmt.Errorf("upstream %s: exactly one of command and url is required", upstream.Name)
+		}
+		switch upstream.Transport {
+		case "", "sse", "http":
+		default:
+			return fmt.Errorf("upstream %s: invalid transport %q (expected sse or http)", upstream.Name, upstream.Transport)
+		}
+	}
+	return nil
+}
+
+// expandEnv expands ${VAR} references in the upstream's settings
+func (u *UpstreamConfig) expandEnv() {
+	u.Command = os.ExpandEnv(u.Command)
+	u.Dir = os.ExpandEnv(u.Dir)
+	u.URL = os.ExpandEnv(u.URL)
+	for i, arg := range u.Args {
+		u.Args[i] = os.ExpandEnv(arg)
+	}
+	for key, value := range u.Env {
+		u.Env[key] = os.ExpandEnv(value)
+	}
+	for key, value := range u.Headers {
+		u.Headers[key] = os.ExpandEnv(value)
+	}
+}
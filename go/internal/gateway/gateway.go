@@ -0,0 +1,420 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+// logger is the logger of the "gateway" component
+var logger = logging.Component("gateway")
+
+// Separator joins an upstream's name and its tool names
+const Separator = "__"
+
+// Gateway connects to upstream MCP servers and registers their tools on a
+// local mcp.Server under "<upstream>__<tool>" names. Calls are forwarded to
+// the owning upstream, including cancellation and progress notifications.
+type Gateway struct {
+	server    *mcp.Server
+	upstreams []*upstream
+	cancel    context.CancelFunc
+	running   sync.WaitGroup
+
+	nextToken  atomic.Int64
+	progressMu sync.Mutex
+	progress   map[string]progressRoute
+}
+
+// progressRoute maps a progress token sent upstream back to the client
+// and token that asked for progress
+type progressRoute struct {
+	session *mcp.Session
+	token   interface{}
+}
+
+// UpstreamStatus reports the health of one upstream
+type UpstreamStatus struct {
+	Name           string     `json:"name"`
+	Connected      bool       `json:"connected"`
+	Tools          int        `json:"tools"`
+	ConnectedSince *time.Time `json:"connectedSince,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+}
+
+// New creates a gateway that registers upstream tools on server
+func New(server *mcp.Server, config *Config) *Gateway {
+	g := &Gateway{
+		server:   server,
+		progress: make(map[string]progressRoute),
+	}
+	for _, upstreamConfig := range config.Upstreams {
+		g.upstreams = append(g.upstreams, &upstream{
+			gateway: g,
+			config:  upstreamConfig,
+			tools:   make(map[string]mcp.Tool),
+		})
+	}
+	return g
+}
+
+// Start connects to every upstream in the background. Upstreams that are
+// down are retried until Stop is called or ctx is cancelled.
+func (g *Gateway) Start(ctx context.Context) {
+	ctx, g.cancel = context.WithCancel(ctx)
+	for _, u := range g.upstreams {
+		g.running.Add(1)
+		go func(u *upstream) {
+			defer g.running.Done()
+			u.run(ctx)
+		}(u)
+	}
+}
+
+// Stop disconnects from every upstream and waits for stdio upstreams to exit
+func (g *Gateway) Stop() {
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.running.Wait()
+}
+
+// Status returns the health of every upstream
+func (g *Gateway) Status() []UpstreamStatus {
+	statuses := make([]UpstreamStatus, 0, len(g.upstreams))
+	for _, u := range g.upstreams {
+		statuses = append(statuses, u.status())
+	}
+	return statuses
+}
+
+// Healthy reports whether every upstream is connected
+func (g *Gateway) Healthy() bool {
+	for _, u := range g.upstreams {
+		if !u.status().Connected {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports an error naming the upstreams that are not connected
+func (g *Gateway) Check(ctx context.Context) error {
+	var down []string
+	for _, status := range g.Status() {
+		if !status.Connected {
+			down = append(down, status.Name)
+		}
+	}
+	if len(down) > 0 {
+		return fmt.Errorf("upstreams not connected: %s", strings.Join(down, ", "))
+	}
+	return nil
+}
+
+// Details returns the status of every upstream for the health report
+func (g *Gateway) Details() interface{} {
+	return g.Status()
+}
+
+// Owns reports whether the named tool is in an upstream's namespace, i.e.
+// whether its annotations come from the upstream and must not be trusted
+func (g *Gateway) Owns(name string) bool {
+	for _, u := range g.upstreams {
+		if strings.HasPrefix(name, u.config.Name+Separator) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeProgress allocates an upstream progress token for a call whose
+// client asked for progress. The returned function releases it.
+func (g *Gateway) routeProgress(ctx context.Context) (interface{}, func()) {
+	token := mcp.ProgressTokenFromContext(ctx)
+	session := mcp.SessionFromContext(ctx)
+	if token == nil || session == nil {
+		return nil, func() {}
+	}
+
+	upstreamToken := fmt.Sprintf("gateway-%d", g.nextToken.Add(1))
+	g.progressMu.Lock()
+	g.progress[upstreamToken] = progressRoute{session: session, token: token}
+	g.progressMu.Unlock()
+
+	return upstreamToken, func() {
+		g.progressMu.Lock()
+		delete(g.progress, upstreamToken)
+		g.progressMu.Unlock()
+	}
+}
+
+// forwardProgress relays an upstream progress notification to the client
+// that made the call
+func (g *Gateway) forwardProgress(method string, params json.RawMessage) {
+	var progress mcp.ProgressNotificationParams
+	if err := json.Unmarshal(params, &progress); err != nil {
+		return
+	}
+
+	token, _ := progress.ProgressToken.(string)
+	g.progressMu.Lock()
+	route, ok := g.progress[token]
+	g.progressMu.Unlock()
+	if !ok {
+		return
+	}
+
+	progress.ProgressToken = route.token
+	if err := route.session.Notify(context.Background(), method, progress); err != nil {
+		logger.Warn("Gateway: failed to forward progress", "error", err)
+	}
+}
+
+// upstream is one aggregated server
+type upstream struct {
+	gateway *Gateway
+	config  UpstreamConfig
+	client  *mcp.Client
+
+	// refreshMu serializes changes to the registered tools
+	refreshMu sync.Mutex
+
+	mu          sync.Mutex
+	tools       map[string]mcp.Tool
+	connected   bool
+	connectedAt time.Time
+	lastError   string
+}
+
+// run connects to the upstream and keeps its tools registered until ctx ends
+func (u *upstream) run(ctx context.Context) {
+	dial, err := u.dialer()
+	if err != nil {
+		u.setError(err)
+		logger.Error("Gateway: invalid upstream", "upstream", u.config.Name, "error", err)
+		return
+	}
+
+	u.client = mcp.NewClient(dial, mcp.ClientOptions{
+		ClientInfo: mcp.ClientInfo{Name: "go-mcp-gateway", Version: mcp.ServerVersion},
+		Reconnect:  true,
+		OnConnect: func(result mcp.InitializeResult) {
+			u.setConnected()
+			logger.Info("Gateway: upstream connected", "upstream", u.config.Name, "server", result.ServerInfo.Name, "version", result.ServerInfo.Version)
+			go u.refresh(ctx)
+		},
+		OnDisconnect: func(err error) {
+			u.setError(err)
+			logger.Warn("Gateway: upstream disconnected", "upstream", u.config.Name, "error", err)
+			u.unregisterTools()
+		},
+	})
+	u.client.OnNotification("notifications/tools/list_changed", func(string, json.RawMessage) {
+		go u.refresh(ctx)
+	})
+	u.client.OnNotification("notifications/progress", u.gateway.forwardProgress)
+
+	// The client reconnects by itself once connected; until then, retry here
+	delay := time.Second
+	for {
+		err := u.client.Connect(ctx)
+		if err == nil {
+			break
+		}
+		u.setError(err)
+		logger.Warn("Gateway: upstream connect failed", "upstream", u.config.Name, "error", err, "retry_in", delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			u.client.Close()
+			return
+		}
+		if delay *= 2; delay > 30*time.Second {
+			delay = 30 * time.Second
+		}
+	}
+
+	<-ctx.Done()
+	u.client.Close()
+	u.unregisterTools()
+}
+
+// dialer builds the Dialer for the upstream's configuration
+func (u *upstream) dialer() (mcp.Dialer, error) {
+	if u.config.Command != "" {
+		env := make([]string, 0, len(u.config.Env))
+		for key, value := range u.config.Env {
+			env = append(env, key+"="+value)
+		}
+		sort.Strings(env)
+
+		return mcp.CommandDialer(mcp.CommandSpec{
+			Command: u.config.Command,
+			Args:    u.config.Args,
+			Env:     env,
+			Dir:     u.config.Dir,
+		}), nil
+	}
+
+	header := http.Header{}
+	for key, value := range u.config.Headers {
+		header.Set(key, value)
+	}
+	options := mcp.HTTPOptions{Header: header}
+
+	transport := u.config.Transport
+	if transport == "" {
+		transport = "http"
+		if parsed, err := url.Parse(u.config.URL); err == nil && strings.HasSuffix(parsed.Path, "/sse") {
+			transport = "sse"
+		}
+	}
+
+	switch transport {
+	case "sse":
+		return mcp.SSEDialer(u.config.URL, options), nil
+	case "http":
+		return mcp.StreamableHTTPDialer(u.config.URL, options), nil
+	default:
+		return nil, fmt.Errorf("invalid transport %q", transport)
+	}
+}
+
+// refresh fetches the upstream's tools and brings the registered tools in
+// line with them. Unchanged tools are left alone so clients aren't told
+// about changes that didn't happen.
+func (u *upstream) refresh(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tools, err := u.client.ListTools(listCtx)
+	if err != nil {
+		if ctx.Err() == nil {
+			u.setLastError(fmt.Errorf("tools/list failed: %w", err))
+			logger.Warn("Gateway: upstream tools/list failed", "upstream", u.config.Name, "error", err)
+		}
+		return
+	}
+
+	// Only applying the list is serialized: holding the lock while waiting
+	// for tools/list would block the disconnect handler
+	u.refreshMu.Lock()
+	defer u.refreshMu.Unlock()
+
+	current := make(map[string]mcp.Tool, len(tools))
+	u.mu.Lock()
+	previous := u.tools
+	u.mu.Unlock()
+
+	for _, tool := range tools {
+		name := u.config.Name + Separator + tool.Name
+		prefixed := tool
+		prefixed.Name = name
+		current[name] = prefixed
+
+		if old, ok := previous[name]; ok && reflect.DeepEqual(old, prefixed) {
+			continue
+		}
+		u.gateway.server.RegisterToolContext(prefixed, u.callTool(tool.Name))
+	}
+
+	u.mu.Lock()
+	u.tools = current
+	u.mu.Unlock()
+
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			u.gateway.server.UnregisterTool(name)
+		}
+	}
+}
+
+// callTool returns the handler forwarding calls to the upstream tool
+func (u *upstream) callTool(name string) mcp.ContextToolHandler {
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		params := mcp.CallToolParams{Name: name, Arguments: args}
+
+		token, release := u.gateway.routeProgress(ctx)
+		defer release()
+		if token != nil {
+			params.Meta = &mcp.RequestMeta{ProgressToken: token}
+		}
+
+		// A cancelled ctx makes the client send notifications/cancelled upstream
+		var result mcp.CallToolResult
+		if err := u.client.Call(ctx, "tools/call", params, &result); err != nil {
+			return nil, fmt.Errorf("%s: %w", u.config.Name, err)
+		}
+		return &result, nil
+	}
+}
+
+// unregisterTools removes all of the upstream's tools from the server
+func (u *upstream) unregisterTools() {
+	u.refreshMu.Lock()
+	defer u.refreshMu.Unlock()
+
+	u.mu.Lock()
+	tools := u.tools
+	u.tools = make(map[string]mcp.Tool)
+	u.mu.Unlock()
+
+	for name := range tools {
+		u.gateway.server.UnregisterTool(name)
+	}
+}
+
+// setConnected marks the upstream as connected
+func (u *upstream) setConnected() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.connected = true
+	u.connectedAt = time.Now()
+	u.lastError = ""
+}
+
+// setLastError records an error that doesn't affect the connection
+func (u *upstream) setLastError(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.lastError = err.Error()
+}
+
+// setError marks the upstream as disconnected because of err
+func (u *upstream) setError(err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.connected = false
+	u.lastError = err.Error()
+}
+
+// status returns the upstream's health
+func (u *upstream) status() UpstreamStatus {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	status := UpstreamStatus{
+		Name:      u.config.Name,
+		Connected: u.connected,
+		Tools:     len(u.tools),
+		LastError: u.lastError,
+	}
+	if u.connected {
+		connectedAt := u.connectedAt
+		status.ConnectedSince = &connectedAt
+	}
+	return status
+}
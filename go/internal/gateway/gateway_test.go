@@ -0,0 +1,76 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+func TestOwns(t *testing.T) {
+	g := New(mcp.NewServer(), &Config{Upstreams: []UpstreamConfig{
+		{Name: "github", Command: "github-mcp"},
+		{Name: "docs", URL: "https://docs.example.com/mcp"},
+	}})
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"github__search", true},
+		{"docs__read_page", true},
+		{"github__", true},
+		// Local tools, however they are named, are not the upstreams'
+		{"github", false},
+		{"github_search", false},
+		{"storage_set", false},
+		{"other__search", false},
+	}
+	for _, tt := range tests {
+		if got := g.Owns(tt.name); got != tt.want {
+			t.Errorf("Owns(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		upstreams []UpstreamConfig
+		wantErr   bool
+	}{
+		{"command", []UpstreamConfig{{Name: "a", Command: "server"}}, false},
+		{"url", []UpstreamConfig{{Name: "a", URL: "https://example.com/sse", Transport: "sse"}}, false},
+		{"no name", []UpstreamConfig{{Command: "server"}}, true},
+		{"separator in name", []UpstreamConfig{{Name: "a" + Separator + "b", Command: "server"}}, true},
+		{"duplicate name", []UpstreamConfig{{Name: "a", Command: "server"}, {Name: "a", URL: "https://example.com"}}, true},
+		{"command and url", []UpstreamConfig{{Name: "a", Command: "server", URL: "https://example.com"}}, true},
+		{"neither", []UpstreamConfig{{Name: "a"}}, true},
+		{"unknown transport", []UpstreamConfig{{Name: "a", URL: "https://example.com", Transport: "ws"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Config{Upstreams: tt.upstreams}).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigExpandsEnv(t *testing.T) {
+	t.Setenv("GATEWAY_TEST_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), "gateway.json")
+	data := `{"upstreams":[{"name":"docs","url":"https://docs.example.com/mcp","headers":{"Authorization":"Bearer ${GATEWAY_TEST_TOKEN}"}}]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Upstreams[0].Headers["Authorization"]; got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+	}
+}
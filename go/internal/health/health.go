@@ -0,0 +1,239 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses of a Report and of its checks
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFail     = "fail"
+)
+
+// DefaultTimeout bounds each check when Options.Timeout is 0
+const DefaultTimeout = 2 * time.Second
+
+// Checker is implemented by components whose health affects readiness,
+// e.g. the storage backend or the audit log. Check returns nil when the
+// component works.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Detailer is implemented by checkers that add details to their result,
+// e.g. the state of each gateway upstream
+type Detailer interface {
+	Details() interface{}
+}
+
+// Options configures Health
+type Options struct {
+	// Timeout bounds each check; checks that run longer fail
+	Timeout time.Duration
+}
+
+// Health runs the registered checks for the readiness endpoint. It is not
+// ready until SetReady(true) is called at the end of startup, and should
+// be set unready again when shutdown begins.
+type Health struct {
+	timeout time.Duration
+	ready   atomic.Bool
+	// reason explains why the server is not ready
+	reason atomic.Pointer[string]
+
+	mu     sync.RWMutex
+	checks []check
+}
+
+// check is a registered checker
+type check struct {
+	name     string
+	checker  Checker
+	critical bool
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string      `json:"name"`
+	Status    string      `json:"status"`
+	Critical  bool        `json:"critical"`
+	LatencyMS float64     `json:"latency_ms"`
+	Error     string      `json:"error,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// Report is the outcome of all checks. Status is fail if the server is
+// not ready or a critical check failed, degraded if another check failed.
+type Report struct {
+	Status string   `json:"status"`
+	Ready  bool     `json:"ready"`
+	Reason string   `json:"reason,omitempty"`
+	Checks []Result `json:"checks"`
+}
+
+// New creates a Health that is not ready yet
+func New(options Options) *Health {
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	h := &Health{timeout: options.Timeout}
+	h.SetReady(false, "starting")
+	return h
+}
+
+// Register adds a critical check: the server is not ready while it fails
+func (h *Health) Register(name string, checker Checker) {
+	h.register(check{name: name, checker: checker, critical: true})
+}
+
+// RegisterOptional adds a check whose failure only degrades the server,
+// e.g. one gateway upstream being down
+func (h *Health) RegisterOptional(name string, checker Checker) {
+	h.register(check{name: name, checker: checker})
+}
+
+// register adds c, replacing a check of the same name
+func (h *Health) register(c check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := range h.checks {
+		if h.checks[i].name == c.name {
+			h.checks[i] = c
+			return
+		}
+	}
+	h.checks = append(h.checks, c)
+}
+
+// SetReady marks the server ready or not; reason says why it isn't
+func (h *Health) SetReady(ready bool, reason string) {
+	if ready {
+		reason = ""
+	}
+	h.reason.Store(&reason)
+	h.ready.Store(ready)
+}
+
+// Ready reports whether the server was marked ready
+func (h *Health) Ready() bool {
+	return h.ready.Load()
+}
+
+// Report runs every check concurrently, each bounded by the timeout
+func (h *Health) Report(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]check(nil), h.checks...)
+	h.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = h.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Ready: h.Ready(), Checks: results}
+	for _, r := range results {
+		if r.Status == StatusOK {
+			continue
+		}
+		if r.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	if !report.Ready {
+		report.Status = StatusFail
+		report.Reason = *h.reason.Load()
+	}
+	return report
+}
+
+// run runs one check. A check that ignores its context is abandoned at
+// the timeout.
+func (h *Health) run(ctx context.Context, c check) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.New("check timed out")
+	}
+
+	result := Result{
+		Name:      c.name,
+		Status:    StatusOK,
+		Critical:  c.critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	if d, ok := c.checker.(Detailer); ok {
+		result.Details = d.Details()
+	}
+	return result
+}
+
+// LivenessHandler serves /livez: 200 as long as the process can answer.
+// Dependencies are left to readiness, so a broken upstream doesn't get the
+// server restarted.
+func (h *Health) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+	}
+}
+
+// ReadinessHandler serves /readyz: the report, with 503 when its status
+// is fail
+func (h *Health) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := h.Report(r.Context())
+		writeJSON(w, report.HTTPStatus(), report)
+	}
+}
+
+// HTTPStatus returns 503 for a failed report and 200 otherwise
+func (r Report) HTTPStatus() int {
+	if r.Status == StatusFail {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// writeJSON writes v as a JSON response that must not be cached
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
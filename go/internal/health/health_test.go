@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// get serves a GET request with handler and decodes the JSON response
+func get(t *testing.T, handler http.HandlerFunc) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, report
+}
+
+func TestHealthLifecycle(t *testing.T) {
+	h := New(Options{})
+	h.Register("storage", CheckerFunc(func(context.Context) error { return nil }))
+	livez, readyz := h.LivenessHandler(), h.ReadinessHandler()
+
+	steps := []struct {
+		name       string
+		ready      bool
+		reason     string
+		wantStatus int
+	}{
+		{"starting", false, "", http.StatusServiceUnavailable},
+		{"started", true, "", http.StatusOK},
+		{"shutting down", false, "shutting down", http.StatusServiceUnavailable},
+	}
+	for i, step := range steps {
+		// New starts out unready with the reason "starting"
+		if i > 0 {
+			h.SetReady(step.ready, step.reason)
+		}
+		wantReason := step.reason
+		if i == 0 {
+			wantReason = "starting"
+		}
+
+		code, report := get(t, readyz)
+		if code != step.wantStatus {
+			t.Errorf("%s: /readyz status = %d, want %d", step.name, code, step.wantStatus)
+		}
+		if report.Ready != step.ready || report.Reason != wantReason {
+			t.Errorf("%s: /readyz ready = %v, reason = %q, want %v, %q", step.name, report.Ready, report.Reason, step.ready, wantReason)
+		}
+		// Liveness doesn't follow readiness, so draining isn't restarted
+		if code, _ := get(t, livez); code != http.StatusOK {
+			t.Errorf("%s: /livez status = %d, want 200", step.name, code)
+		}
+	}
+}
+
+// detailed is a checker with details
+type detailed struct{}
+
+func (detailed) Check(context.Context) error { return nil }
+func (detailed) Details() interface{}        { return "up" }
+
+func TestHealthChecks(t *testing.T) {
+	failing := CheckerFunc(func(context.Context) error { return errors.New("down") })
+	slow := CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	tests := []struct {
+		name       string
+		register   func(h *Health)
+		wantStatus string
+		wantError  string
+	}{
+		{"no checks", func(h *Health) {}, StatusOK, ""},
+		{"critical failure", func(h *Health) { h.Register("storage", failing) }, StatusFail, "down"},
+		{"optional failure", func(h *Health) { h.RegisterOptional("upstream", failing) }, StatusDegraded, "down"},
+		{"timeout", func(h *Health) { h.Register("storage", slow) }, StatusFail, "check timed out"},
+		{"replaced", func(h *Health) {
+			h.Register("storage", failing)
+			h.Register("storage", detailed{})
+		}, StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(Options{Timeout: 50 * time.Millisecond})
+			h.SetReady(true, "")
+			tt.register(h)
+
+			report := h.Report(context.Background())
+			if report.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			if tt.wantError != "" && (len(report.Checks) != 1 || report.Checks[0].Error != tt.wantError) {
+				t.Errorf("checks = %+v, want the error %q", report.Checks, tt.wantError)
+			}
+			if wantHTTP := map[string]int{StatusOK: 200, StatusDegraded: 200, StatusFail: 503}[tt.wantStatus]; report.HTTPStatus() != wantHTTP {
+				t.Errorf("HTTPStatus() = %d, want %d", report.HTTPStatus(), wantHTTP)
+			}
+		})
+	}
+
+	h := New(Options{})
+	h.Register("detailed", detailed{})
+	if report := h.Report(context.Background()); report.Checks[0].Details != "up" {
+		t.Errorf("details = %v, want up", report.Checks[0].Details)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package listener
+
+import "fmt"
+
+// chown is not supported on this platform
+func chown(path, owner string) error {
+	return fmt.Errorf("socket ownership is not supported on this platform")
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package listener
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// chown changes the owner of path to "user" or "user:group"
+func chown(path, owner string) error {
+	userName, groupName, _ := strings.Cut(owner, ":")
+
+	uid, gid := -1, -1
+	if userName != "" {
+		u, err := user.Lookup(userName)
+		if err != nil {
+			return err
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return fmt.Errorf("unexpected uid %q for %s", u.Uid, userName)
+		}
+	}
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			return err
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return fmt.Errorf("unexpected gid %q for %s", g.Gid, groupName)
+		}
+	}
+
+	return os.Chown(path, uid, gid)
+}
@@ -0,0 +1,124 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UnixSocketOptions controls the permissions of a Unix domain socket file
+type UnixSocketOptions struct {
+	// Mode is the file mode applied to the socket file (e.g. 0660)
+	Mode os.FileMode
+	// Owner is an optional "user" or "user:group" the socket file is chowned to
+	Owner string
+}
+
+// Listen opens a listener for address. Supported forms are:
+//
+//	:8080, host:8080       TCP
+//	unix:/path/to/mcp.sock Unix domain socket
+//	systemd                the single socket passed by systemd socket activation
+//	systemd:name           the systemd socket with FileDescriptorName=name
+func Listen(address string, options UnixSocketOptions) (net.Listener, error) {
+	switch {
+	case address == "systemd" || strings.HasPrefix(address, "systemd:"):
+		return systemdListener(strings.TrimPrefix(strings.TrimPrefix(address, "systemd"), ":"))
+	case strings.HasPrefix(address, "unix:"):
+		return listenUnix(strings.TrimPrefix(address, "unix:"), options)
+	default:
+		return net.Listen("tcp", address)
+	}
+}
+
+// IsTCPAddress reports whether address always refers to a TCP listener.
+// A systemd socket may be either, so use IsTCP once it is open.
+func IsTCPAddress(address string) bool {
+	return !strings.HasPrefix(address, "unix:") && address != "systemd" && !strings.HasPrefix(address, "systemd:")
+}
+
+// IsTCP reports whether ln accepts TCP connections
+func IsTCP(ln net.Listener) bool {
+	return strings.HasPrefix(ln.Addr().Network(), "tcp")
+}
+
+// listenUnix listens on a Unix domain socket, replacing a stale socket file
+func listenUnix(path string, options UnixSocketOptions) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("unix socket path is empty")
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		// Refuse to steal a socket another process is still serving
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is already in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	// The socket is created in a directory only we can enter and moved into
+	// place once it has its mode and owner, so nobody can connect before
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".sock-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a directory for %s: %w", path, err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// The socket won't be at tmp by the time it is closed
+	ln.SetUnlinkOnClose(false)
+
+	if options.Mode != 0 {
+		if err := os.Chmod(tmp, options.Mode); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to chmod %s: %w", path, err)
+		}
+	}
+
+	if options.Owner != "" {
+		if err := chown(tmp, options.Owner); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to chown %s: %w", path, err)
+		}
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to move socket to %s: %w", path, err)
+	}
+
+	return &unixListener{UnixListener: ln, path: path}, nil
+}
+
+// unixListener is a Unix socket listener whose socket file was moved to
+// path after it was created
+type unixListener struct {
+	*net.UnixListener
+	path string
+}
+
+// Addr returns the socket's final address
+func (l *unixListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+// Close stops listening and removes the socket file
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	if err == nil {
+		os.Remove(l.path)
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package listener
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsTCPAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{":8080", true},
+		{"127.0.0.1:8080", true},
+		{"[::1]:8080", true},
+		{"unix:/run/mcp.sock", false},
+		{"systemd", false},
+		{"systemd:mcp", false},
+	}
+	for _, tt := range tests {
+		if got := IsTCPAddress(tt.address); got != tt.want {
+			t.Errorf("IsTCPAddress(%q) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestIsTCP(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "mcp.sock")
+	tests := []struct {
+		name    string
+		address string
+		want    bool
+	}{
+		{"tcp", "127.0.0.1:0", true},
+		{"unix", "unix:" + socket, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := Listen(tt.address, UnixSocketOptions{Mode: 0o600})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			if got := IsTCP(ln); got != tt.want {
+				t.Errorf("IsTCP(%s) = %v, want %v", ln.Addr().Network(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		setup   func(path string)
+		wantErr bool
+	}{
+		{"new socket", func(path string) {}, false},
+		{"stale socket", func(path string) {
+			// A socket file nobody serves any more
+			ln, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ln.(*net.UnixListener).SetUnlinkOnClose(false)
+			ln.Close()
+		}, false},
+		{"socket in use", func(path string) {
+			ln, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { ln.Close() })
+		}, true},
+		{"regular file", func(path string) {
+			if err := os.WriteFile(path, nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, filepath.Base(t.Name())+".sock")
+			tt.setup(path)
+
+			ln, err := Listen("unix:"+path, UnixSocketOptions{Mode: 0o660})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Listen() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0o660 {
+				t.Errorf("socket mode = %o, want 660", info.Mode().Perm())
+			}
+			if got := ln.Addr().String(); got != path {
+				t.Errorf("Addr() = %s, want %s", got, path)
+			}
+			if conn, err := net.Dial("unix", path); err != nil {
+				t.Errorf("Dial() error = %v", err)
+			} else {
+				conn.Close()
+			}
+
+			ln.Close()
+			if _, err := os.Lstat(path); !os.IsNotExist(err) {
+				t.Errorf("socket file left after Close(): %v", err)
+			}
+		})
+	}
+
+	// The directory the socket was created in is gone
+	leftovers, err := filepath.Glob(filepath.Join(dir, ".sock-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) > 0 {
+		t.Errorf("temporary directories left behind: %v", leftovers)
+	}
+
+	if _, err := Listen("unix:", UnixSocketOptions{}); err == nil {
+		t.Error("Listen() accepted an empty socket path")
+	}
+}
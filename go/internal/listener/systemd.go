@@ -0,0 +1,101 @@
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd
+const listenFDsStart = 3
+
+// SystemdListeners returns the listeners passed by systemd socket activation
+// (LISTEN_PID/LISTEN_FDS/LISTEN_FDNAMES), keyed by their FileDescriptorName.
+// Unnamed sockets are keyed "unknown" as systemd does. The environment
+// variables are unset so child processes don't inherit them.
+func SystemdListeners() (map[string][]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, fmt.Errorf("no sockets passed by systemd (LISTEN_PID not set for this process)")
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("no sockets passed by systemd (LISTEN_FDS=%q)", os.Getenv("LISTEN_FDS"))
+	}
+
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	listeners := make(map[string][]net.Listener)
+	for i := 0; i < count; i++ {
+		fd := listenFDsStart + i
+
+		name := "unknown"
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		// FileListener dups the descriptor with close-on-exec set, so the
+		// inherited one can be closed right away
+		file := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("fd %d (%s) is not a listening socket: %w", fd, name, err)
+		}
+		listeners[name] = append(listeners[name], ln)
+	}
+
+	return listeners, nil
+}
+
+// systemdListener returns the single inherited listener, or the one named name
+func systemdListener(name string) (net.Listener, error) {
+	all, err := SystemdListeners()
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []net.Listener
+	if name != "" {
+		candidates = all[name]
+	} else {
+		for _, lns := range all {
+			candidates = append(candidates, lns...)
+		}
+	}
+
+	if len(candidates) != 1 {
+		for _, lns := range all {
+			for _, ln := range lns {
+				ln.Close()
+			}
+		}
+		if name != "" {
+			return nil, fmt.Errorf("expected one systemd socket named %q, got %d", name, len(candidates))
+		}
+		return nil, fmt.Errorf("expected one systemd socket, got %d; select one with systemd:<name>", len(candidates))
+	}
+
+	// Close any other inherited sockets we won't serve
+	for _, lns := range all {
+		for _, ln := range lns {
+			if ln != candidates[0] {
+				ln.Close()
+			}
+		}
+	}
+
+	return candidates[0], nil
+}
@@ -0,0 +1,288 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Formats accepted by Options.Format
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Components are the names of the loggers the server logs with. Levels can
+// be set per component.
+var Components = []string{"mcp", "http", "auth", "oauth", "gateway", "usage", "audit", "config", "tls", "admin", "server", "bridge"}
+
+// Options configures logging
+type Options struct {
+	// Format is "text" or "json"
+	Format string
+	// Level is "debug", "info", "warn" or "error"
+	Level string
+	// Levels overrides Level per component
+	Levels map[string]string
+	// Output is where records are written; nil uses os.Stderr
+	Output io.Writer
+}
+
+// Validate checks the options' format and levels
+func (o Options) Validate() error {
+	switch o.Format {
+	case "", FormatText, FormatJSON:
+	default:
+		return fmt.Errorf("unknown log format %q", o.Format)
+	}
+	_, _, err := o.levels()
+	return err
+}
+
+// levels parses the default level and the component levels
+func (o Options) levels() (slog.Level, map[string]slog.Level, error) {
+	level, err := ParseLevel(o.Level)
+	if err != nil {
+		return 0, nil, err
+	}
+	known := make(map[string]bool, len(Components))
+	for _, name := range Components {
+		known[name] = true
+	}
+	levels := make(map[string]slog.Level, len(o.Levels))
+	for name, s := range o.Levels {
+		if !known[name] {
+			return 0, nil, fmt.Errorf("unknown log component %q (available: %s)", name, strings.Join(Components, ", "))
+		}
+		if levels[name], err = ParseLevel(s); err != nil {
+			return 0, nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return level, levels, nil
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error"; empty is info
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// root is where every logger writes; Setup replaces it
+var root atomic.Pointer[slog.Handler]
+
+func init() {
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+	root.Store(&h)
+}
+
+// Setup installs the output format and levels, and makes the default slog
+// logger, and with it the standard log package, write to the same stream
+func Setup(options Options) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	out := options.Output
+	if out == nil {
+		out = os.Stderr
+	}
+
+	// Levels are checked by the component loggers before records get here
+	handlerOptions := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var h slog.Handler
+	if options.Format == FormatJSON {
+		h = slog.NewJSONHandler(out, handlerOptions)
+	} else {
+		h = slog.NewTextHandler(out, handlerOptions)
+	}
+	root.Store(&h)
+
+	SetLevels(options.Level, options.Levels)
+	slog.SetDefault(slog.New(&handler{level: levelVar("")}))
+	return nil
+}
+
+var (
+	levelsMu      sync.Mutex
+	defaultLevel  slog.Level
+	overrides     map[string]slog.Level
+	componentVars = make(map[string]*slog.LevelVar)
+)
+
+// SetLevels changes the default and per-component levels of a running
+// server. Invalid levels are ignored; check them with Options.Validate.
+func SetLevels(level string, levels map[string]string) {
+	parsed, parsedLevels, err := Options{Level: level, Levels: levels}.levels()
+	if err != nil {
+		return
+	}
+
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	defaultLevel, overrides = parsed, parsedLevels
+	for name, v := range componentVars {
+		v.Set(levelOf(name))
+	}
+}
+
+// levelOf returns the level of component; levelsMu must be held
+func levelOf(component string) slog.Level {
+	if level, ok := overrides[component]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+// levelVar returns the level of component, which SetLevels updates
+func levelVar(component string) *slog.LevelVar {
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	v, ok := componentVars[component]
+	if !ok {
+		v = new(slog.LevelVar)
+		v.Set(levelOf(component))
+		componentVars[component] = v
+	}
+	return v
+}
+
+// Component returns the logger of a component. Records carry a component
+// attribute and the attributes of their context (see With). Loggers can be
+// created before Setup, e.g. in package variables.
+func Component(name string) *slog.Logger {
+	return slog.New(&handler{component: name, level: levelVar(name)})
+}
+
+// handler filters records by its component's level and passes them, with
+// the component and context attributes, to the root handler
+type handler struct {
+	component string
+	level     *slog.LevelVar
+	// ops are the WithAttrs and WithGroup calls made on the logger, applied
+	// in order to the root handler in effect when a record is written
+	ops []func(slog.Handler) slog.Handler
+}
+
+// Enabled implements slog.Handler
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	var attrs []slog.Attr
+	if h.component != "" {
+		attrs = append(attrs, slog.String("component", h.component))
+	}
+	attrs = append(attrs, Attrs(ctx)...)
+
+	out := *root.Load()
+	if len(attrs) > 0 {
+		out = out.WithAttrs(attrs)
+	}
+	for _, op := range h.ops {
+		out = op(out)
+	}
+	return out.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithAttrs(attrs) })
+}
+
+// WithGroup implements slog.Handler
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(out slog.Handler) slog.Handler { return out.WithGroup(name) })
+}
+
+// with returns a copy of h with op appended
+func (h *handler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{component: h.component, level: h.level, ops: append(ops, op)}
+}
+
+type attrsKey struct{}
+
+// With returns ctx with attributes, given as slog key-value pairs or
+// slog.Attr, that are added to every record logged with it. An attribute
+// replaces one of ctx with the same key.
+func With(ctx context.Context, args ...any) context.Context {
+	r := slog.Record{}
+	r.Add(args...)
+	added := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		added = append(added, a)
+		return true
+	})
+	return WithAttrs(ctx, added...)
+}
+
+// WithAttrs is With for attributes
+func WithAttrs(ctx context.Context, added ...slog.Attr) context.Context {
+	if len(added) == 0 {
+		return ctx
+	}
+	existing := Attrs(ctx)
+	attrs := make([]slog.Attr, 0, len(existing)+len(added))
+	for _, a := range existing {
+		replaced := false
+		for _, b := range added {
+			if a.Key == b.Key {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			attrs = append(attrs, a)
+		}
+	}
+	return context.WithValue(ctx, attrsKey{}, append(attrs, added...))
+}
+
+// Attrs returns the attributes added to ctx with With
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// Writer returns a writer logging each line written to it with logger at
+// level, for libraries that only log to an io.Writer
+func Writer(logger *slog.Logger, level slog.Level) io.Writer {
+	return &lineWriter{logger: logger, level: level}
+}
+
+type lineWriter struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.logger.Log(context.Background(), w.level, line)
+		}
+	}
+	return len(p), nil
+}
+
+// Fatal logs msg at the error level and exits
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,571 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClientClosed is returned by calls on a closed client
+var ErrClientClosed = errors.New("client closed")
+
+// ErrConnectionLost is returned for requests that were in flight when the connection dropped
+var ErrConnectionLost = errors.New("connection lost")
+
+// ErrAlreadyConnected is returned by Connect on a client that is connected
+var ErrAlreadyConnected = errors.New("client already connected")
+
+// Dialer opens a new connection to an MCP server
+type Dialer func(ctx context.Context) (Connection, error)
+
+// NotificationHandler handles a notification sent by the server
+type NotificationHandler func(method string, params json.RawMessage)
+
+// retryHinter is implemented by connections whose server suggested a
+// reconnection delay, such as the SSE retry field
+type retryHinter interface {
+	RetryDelay() time.Duration
+}
+
+// ClientOptions configures a Client
+type ClientOptions struct {
+	// ClientInfo is sent to the server in initialize
+	ClientInfo ClientInfo
+	// Capabilities are the client capabilities sent in initialize
+	Capabilities map[string]interface{}
+	// Reconnect redials and re-initializes automatically after the connection drops
+	Reconnect bool
+	// MinReconnectDelay and MaxReconnectDelay bound the reconnect backoff
+	MinReconnectDelay time.Duration
+	MaxReconnectDelay time.Duration
+	// OnConnect is called after every successful initialize handshake
+	OnConnect func(result InitializeResult)
+	// OnDisconnect is called when an established connection drops
+	OnDisconnect func(err error)
+}
+
+// Client is an MCP client. It is safe for concurrent use.
+type Client struct {
+	dial    Dialer
+	options ClientOptions
+	nextID  atomic.Int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	conn       Connection
+	ready      chan struct{}
+	lastErr    error
+	closed     bool
+	pending    map[int64]chan *clientMessage
+	initResult InitializeResult
+	// reconnecting is set while reconnect runs, which is only after an
+	// established connection dropped
+	reconnecting bool
+
+	handlersMu sync.RWMutex
+	handlers   map[string][]NotificationHandler
+}
+
+// clientMessage is any JSON-RPC message received by the client
+type clientMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+// clientRequest is a JSON-RPC request or notification sent by the client
+type clientRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// NewClient creates a client that connects with dial. Call Connect before use.
+func NewClient(dial Dialer, options ClientOptions) *Client {
+	if options.ClientInfo.Name == "" {
+		options.ClientInfo = ClientInfo{Name: "go-mcp-client", Version: ServerVersion}
+	}
+	if options.MinReconnectDelay <= 0 {
+		options.MinReconnectDelay = 500 * time.Millisecond
+	}
+	if options.MaxReconnectDelay <= 0 {
+		options.MaxReconnectDelay = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		dial:     dial,
+		options:  options,
+		ctx:      ctx,
+		cancel:   cancel,
+		ready:    make(chan struct{}),
+		pending:  make(map[int64]chan *clientMessage),
+		handlers: make(map[string][]NotificationHandler),
+	}
+}
+
+// Connect dials the server and performs the initialize handshake
+func (c *Client) Connect(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		c.mu.Lock()
+		c.lastErr = err
+		c.mu.Unlock()
+		return err
+	}
+	return c.attach(ctx, conn)
+}
+
+// attach starts reading from conn and initializes the session on it
+func (c *Client) attach(ctx context.Context, conn Connection) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		conn.Close()
+		return ErrClientClosed
+	}
+	// A connection that is set is either initializing or ready, whose
+	// channel must only be closed once
+	if c.conn != nil {
+		c.mu.Unlock()
+		conn.Close()
+		return ErrAlreadyConnected
+	}
+	c.conn = conn
+	c.lastErr = nil
+	c.mu.Unlock()
+
+	go c.readLoop(conn)
+
+	var result InitializeResult
+	err := c.request(ctx, conn, "initialize", InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    c.capabilities(),
+		ClientInfo:      c.options.ClientInfo,
+	}, &result)
+	if err != nil {
+		return c.attachFailed(conn, fmt.Errorf("initialize failed: %w", err))
+	}
+
+	if err := c.notify(ctx, conn, "notifications/initialized", nil); err != nil {
+		return c.attachFailed(conn, fmt.Errorf("initialized notification failed: %w", err))
+	}
+
+	c.mu.Lock()
+	c.initResult = result
+	close(c.ready)
+	c.mu.Unlock()
+
+	if c.options.OnConnect != nil {
+		c.options.OnConnect(result)
+	}
+	return nil
+}
+
+// attachFailed drops conn after a failed handshake and records err, so
+// waitReady returns it instead of waiting for a connection
+func (c *Client) attachFailed(conn Connection, err error) error {
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.lastErr = err
+	c.mu.Unlock()
+	conn.Close()
+	return err
+}
+
+// capabilities returns the client capabilities to advertise
+func (c *Client) capabilities() map[string]interface{} {
+	if c.options.Capabilities != nil {
+		return c.options.Capabilities
+	}
+	return map[string]interface{}{}
+}
+
+// ServerInfo returns the result of the most recent initialize handshake
+func (c *Client) ServerInfo() InitializeResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.initResult
+}
+
+// OnNotification registers a handler for a server notification method.
+// The method "*" matches every notification. Handlers run on the read loop
+// and should return quickly.
+func (c *Client) OnNotification(method string, handler NotificationHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.handlers[method] = append(c.handlers[method], handler)
+}
+
+// Call sends a request and decodes its result into result, which may be nil.
+// If ctx ends first, a notifications/cancelled is sent to the server.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	conn, err := c.waitReady(ctx)
+	if err != nil {
+		return err
+	}
+	return c.request(ctx, conn, method, params, result)
+}
+
+// Notify sends a notification to the server
+func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	conn, err := c.waitReady(ctx)
+	if err != nil {
+		return err
+	}
+	return c.notify(ctx, conn, method, params)
+}
+
+// Ping checks that the server is responsive
+func (c *Client) Ping(ctx context.Context) error {
+	return c.Call(ctx, "ping", nil, nil)
+}
+
+// ListTools returns all tools offered by the server
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+
+		var result ListToolsResult
+		if err := c.Call(ctx, "tools/list", params, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool calls a tool with the given arguments
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*CallToolResult, error) {
+	var result CallToolResult
+	err := c.Call(ctx, "tools/call", CallToolParams{Name: name, Arguments: arguments}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListResources returns all resources offered by the server
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	cursor := ""
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+
+		var result ListResourcesResult
+		if err := c.Call(ctx, "resources/list", params, &result); err != nil {
+			return nil, err
+		}
+		resources = append(resources, result.Resources...)
+
+		if result.NextCursor == "" {
+			return resources, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// ReadResource reads the contents of a resource
+func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	var result ReadResourceResult
+	if err := c.Call(ctx, "resources/read", ReadResourceParams{URI: uri}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close closes the connection and stops reconnecting
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	conn := c.conn
+	c.conn = nil
+	c.failPendingLocked()
+	c.mu.Unlock()
+
+	c.cancel()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// waitReady waits until an initialized connection is available
+func (c *Client) waitReady(ctx context.Context) (Connection, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, ErrClientClosed
+		}
+		// Without a reconnect running, nothing will clear the error
+		if c.lastErr != nil && !c.reconnecting {
+			err := c.lastErr
+			c.mu.Unlock()
+			return nil, err
+		}
+		ready := c.ready
+		conn := c.conn
+		c.mu.Unlock()
+
+		select {
+		case <-ready:
+			if conn != nil {
+				return conn, nil
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.ctx.Done():
+			return nil, ErrClientClosed
+		}
+	}
+}
+
+// request sends a request on conn and waits for its response
+func (c *Client) request(ctx context.Context, conn Connection, method string, params interface{}, result interface{}) error {
+	id := c.nextID.Add(1)
+	ch := make(chan *clientMessage, 1)
+
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(clientRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	if err := conn.Write(ctx, data); err != nil {
+		if ctx.Err() == nil {
+			c.connectionLost(conn, err)
+		}
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg == nil {
+			return ErrConnectionLost
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	case <-ctx.Done():
+		c.notify(context.Background(), conn, "notifications/cancelled", map[string]interface{}{
+			"requestId": id,
+			"reason":    ctx.Err().Error(),
+		})
+		return ctx.Err()
+	case <-c.ctx.Done():
+		return ErrClientClosed
+	}
+}
+
+// notify sends a notification on conn
+func (c *Client) notify(ctx context.Context, conn Connection, method string, params interface{}) error {
+	data, err := json.Marshal(clientRequest{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	return conn.Write(ctx, data)
+}
+
+// readLoop dispatches messages from conn until it fails
+func (c *Client) readLoop(conn Connection) {
+	for {
+		data, err := conn.Read(c.ctx)
+		if err != nil {
+			c.connectionLost(conn, err)
+			return
+		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			logger.Warn("MCP client: ignoring malformed message", "error", err)
+			continue
+		}
+
+		switch {
+		case msg.Method != "" && len(msg.ID) == 0:
+			c.dispatchNotification(msg.Method, msg.Params)
+		case msg.Method != "":
+			go c.handleServerRequest(conn, &msg)
+		default:
+			c.deliverResponse(&msg)
+		}
+	}
+}
+
+// dispatchNotification runs the handlers registered for method
+func (c *Client) dispatchNotification(method string, params json.RawMessage) {
+	c.handlersMu.RLock()
+	handlers := append(append([]NotificationHandler(nil), c.handlers[method]...), c.handlers["*"]...)
+	c.handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(method, params)
+	}
+}
+
+// handleServerRequest answers requests sent by the server. Only ping is supported.
+func (c *Client) handleServerRequest(conn Connection, msg *clientMessage) {
+	response := JSONRPCResponse{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		response.Result = map[string]interface{}{}
+	} else {
+		response.Error = &JSONRPCError{Code: -32601, Message: fmt.Sprintf("Method not found: %s", msg.Method)}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	conn.Write(c.ctx, data)
+}
+
+// deliverResponse hands a response to the request waiting for it
+func (c *Client) deliverResponse(msg *clientMessage) {
+	id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+	if err != nil {
+		logger.Warn("MCP client: ignoring response with unexpected id", "jsonrpc_id", msg.ID)
+		return
+	}
+
+	c.mu.Lock()
+	ch, ok := c.pending[id]
+	c.mu.Unlock()
+
+	// The channel holds one response; a duplicate must not block the read loop
+	if ok {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// failPendingLocked fails every in-flight request. c.mu must be held.
+func (c *Client) failPendingLocked() {
+	for id, ch := range c.pending {
+		select {
+		case ch <- nil:
+		default:
+		}
+		delete(c.pending, id)
+	}
+}
+
+// connectionLost tears down conn and starts reconnecting if enabled
+func (c *Client) connectionLost(conn Connection, err error) {
+	c.mu.Lock()
+	if c.conn != conn {
+		c.mu.Unlock()
+		return
+	}
+
+	wasReady := false
+	select {
+	case <-c.ready:
+		wasReady = true
+		c.ready = make(chan struct{})
+	default:
+	}
+
+	c.conn = nil
+	c.lastErr = fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	c.failPendingLocked()
+	closed := c.closed
+	reconnect := c.options.Reconnect && wasReady && !closed
+	c.reconnecting = c.reconnecting || reconnect
+	c.mu.Unlock()
+
+	conn.Close()
+
+	if closed {
+		return
+	}
+
+	if wasReady && c.options.OnDisconnect != nil {
+		c.options.OnDisconnect(err)
+	}
+
+	if reconnect {
+		delay := c.options.MinReconnectDelay
+		if hinter, ok := conn.(retryHinter); ok && hinter.RetryDelay() > delay {
+			delay = hinter.RetryDelay()
+		}
+		go c.reconnect(delay)
+	}
+}
+
+// reconnect redials with exponential backoff until it succeeds or the client is closed
+func (c *Client) reconnect(delay time.Duration) {
+	defer func() {
+		c.mu.Lock()
+		c.reconnecting = false
+		c.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-time.After(delay):
+		case <-c.ctx.Done():
+			return
+		}
+
+		conn, err := c.dial(c.ctx)
+		if err == nil {
+			if err = c.attach(c.ctx, conn); err == nil {
+				return
+			}
+		}
+		if errors.Is(err, ErrClientClosed) || c.ctx.Err() != nil {
+			return
+		}
+
+		logger.Warn("MCP client: reconnect failed", "error", err, "retry_in", delay)
+		delay *= 2
+		if delay > c.options.MaxReconnectDelay {
+			delay = c.options.MaxReconnectDelay
+		}
+	}
+}
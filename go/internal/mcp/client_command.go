@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// CommandSpec describes an MCP server started as a subprocess
+type CommandSpec struct {
+	// Command is the executable to run
+	Command string
+	// Args are the command-line arguments
+	Args []string
+	// Env is added to the current environment, as "KEY=value" entries
+	Env []string
+	// Dir is the working directory; empty means the current directory
+	Dir string
+	// Stderr receives the server's stderr; nil forwards it to os.Stderr
+	Stderr io.Writer
+	// Framing selects the stdio framing; the default is newline-delimited JSON
+	Framing Framing
+}
+
+// CommandDialer returns a Dialer that starts the server as a subprocess and
+// talks to it over its stdin and stdout. Each dial starts a new process.
+func CommandDialer(spec CommandSpec) Dialer {
+	return func(ctx context.Context) (Connection, error) {
+		cmd := exec.Command(spec.Command, spec.Args...)
+		cmd.Env = append(os.Environ(), spec.Env...)
+		cmd.Dir = spec.Dir
+		cmd.Stderr = spec.Stderr
+		if cmd.Stderr == nil {
+			cmd.Stderr = os.Stderr
+		}
+
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start %s: %w", spec.Command, err)
+		}
+
+		framing := spec.Framing
+		if framing == FramingAuto {
+			framing = FramingNewline
+		}
+
+		return &commandConnection{
+			StreamConnection: NewStreamConnection(stdout, stdin, WithFraming(framing)),
+			cmd:              cmd,
+		}, nil
+	}
+}
+
+// commandConnection is a stream connection to a subprocess
+type commandConnection struct {
+	*StreamConnection
+	cmd       *exec.Cmd
+	closeOnce sync.Once
+}
+
+// Read returns the next message, reporting io.EOF once the process exits
+func (c *commandConnection) Read(ctx context.Context) ([]byte, error) {
+	data, err := c.StreamConnection.Read(ctx)
+	if err != nil && !errors.Is(err, ErrConnectionClosed) && !errors.Is(err, ErrMessageTooLarge) {
+		// Reads from a pipe after the process exits fail with os.ErrClosed or EOF
+		return nil, io.EOF
+	}
+	return data, err
+}
+
+// Close closes stdin so the server can exit cleanly, killing it if it doesn't
+func (c *commandConnection) Close() error {
+	c.closeOnce.Do(func() {
+		c.StreamConnection.Close()
+
+		exited := make(chan struct{})
+		go func() {
+			c.cmd.Wait()
+			close(exited)
+		}()
+
+		select {
+		case <-exited:
+		case <-time.After(3 * time.Second):
+			c.cmd.Process.Kill()
+			<-exited
+		}
+	})
+	return nil
+}
@@ -0,0 +1,256 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionIDHeader carries the session id in the Streamable HTTP transport
+const SessionIDHeader = "Mcp-Session-Id"
+
+// StreamableHTTPDialer returns a Dialer for servers using the Streamable
+// HTTP transport: every message is POSTed to endpoint and answered with
+// either a JSON body or an event stream. Once the server assigns a session,
+// a GET stream is opened for server-initiated messages if it supports one.
+func StreamableHTTPDialer(endpoint string, options HTTPOptions) Dialer {
+	return func(ctx context.Context) (Connection, error) {
+		streamCtx, cancel := context.WithCancel(context.Background())
+		return &streamableHTTPConnection{
+			endpoint: endpoint,
+			options:  options,
+			incoming: make(chan []byte, 16),
+			done:     make(chan struct{}),
+			ctx:      streamCtx,
+			cancel:   cancel,
+		}, nil
+	}
+}
+
+// streamableHTTPConnection is a Connection over the Streamable HTTP transport
+type streamableHTTPConnection struct {
+	endpoint string
+	options  HTTPOptions
+	incoming chan []byte
+	done     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu        sync.Mutex
+	sessionID string
+	err       error
+	retry     time.Duration
+	closeOnce sync.Once
+}
+
+// Read returns the next message from a response or the server stream
+func (c *streamableHTTPConnection) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-c.incoming:
+		return data, nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.err != nil {
+			return nil, c.err
+		}
+		return nil, ErrConnectionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Write posts a message and forwards whatever the server answers with
+func (c *streamableHTTPConnection) Write(ctx context.Context, data []byte) error {
+	select {
+	case <-c.done:
+		return ErrConnectionClosed
+	default:
+	}
+
+	// Only sending the request is bound to ctx; a streamed response keeps
+	// being read after Write returns, until the connection is closed
+	reqCtx, cancel := context.WithCancel(c.ctx)
+	stop := context.AfterFunc(ctx, cancel)
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, c.endpoint, bytes.NewReader(data))
+	if err != nil {
+		cancel()
+		return err
+	}
+	c.prepare(req)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	resp, err := c.options.httpClient().Do(req)
+	stop()
+	if err != nil {
+		cancel()
+		return err
+	}
+	defer func() {
+		if resp.Body != nil {
+			cancel()
+		}
+	}()
+
+	if id := resp.Header.Get(SessionIDHeader); id != "" {
+		c.mu.Lock()
+		first := c.sessionID == ""
+		c.sessionID = id
+		c.mu.Unlock()
+		if first {
+			go c.listen()
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent:
+		resp.Body.Close()
+		return nil
+	case resp.StatusCode == http.StatusNotFound && c.hasSession():
+		resp.Body.Close()
+		err := fmt.Errorf("session expired")
+		c.fail(err)
+		return err
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return fmt.Errorf("post failed: %s: %s", resp.Status, bytes.TrimSpace(body))
+	case mediaType == "text/event-stream":
+		body := resp.Body
+		resp.Body = nil
+		go func() {
+			defer cancel()
+			c.forwardEvents(body)
+		}()
+		return nil
+	default:
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(body)) > 0 {
+			c.deliver(body)
+		}
+		return nil
+	}
+}
+
+// prepare applies the configured headers and the session id to req
+func (c *streamableHTTPConnection) prepare(req *http.Request) {
+	c.options.applyHeaders(req)
+	req.Header.Set("MCP-Protocol-Version", ProtocolVersion)
+	if id := c.currentSessionID(); id != "" {
+		req.Header.Set(SessionIDHeader, id)
+	}
+}
+
+// currentSessionID returns the session id assigned by the server
+func (c *streamableHTTPConnection) currentSessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// hasSession reports whether the server assigned a session id
+func (c *streamableHTTPConnection) hasSession() bool {
+	return c.currentSessionID() != ""
+}
+
+// listen opens the GET stream for server-initiated messages. Servers that
+// don't offer one answer 405, which is not an error.
+func (c *streamableHTTPConnection) listen() {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, c.endpoint, nil)
+	if err != nil {
+		return
+	}
+	c.prepare(req)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.options.httpClient().Do(req)
+	if err != nil {
+		return
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return
+	}
+	c.forwardEvents(resp.Body)
+}
+
+// forwardEvents delivers each "message" event of an event stream
+func (c *streamableHTTPConnection) forwardEvents(body io.ReadCloser) {
+	defer body.Close()
+
+	events := NewSSEReader(body)
+	for {
+		event, err := events.Next()
+		if err != nil {
+			return
+		}
+
+		c.mu.Lock()
+		c.retry = event.Retry
+		c.mu.Unlock()
+
+		if event.Event == "message" {
+			c.deliver([]byte(event.Data))
+		}
+	}
+}
+
+// deliver queues a received message for Read
+func (c *streamableHTTPConnection) deliver(data []byte) {
+	select {
+	case c.incoming <- data:
+	case <-c.done:
+	}
+}
+
+// fail records a fatal error and closes the connection
+func (c *streamableHTTPConnection) fail(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	c.Close()
+}
+
+// RetryDelay returns the reconnection delay requested by the server
+func (c *streamableHTTPConnection) RetryDelay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retry
+}
+
+// Close ends all streams and asks the server to drop the session
+func (c *streamableHTTPConnection) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.cancel()
+
+		if id := c.currentSessionID(); id != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.endpoint, nil)
+			if err == nil {
+				c.options.applyHeaders(req)
+				req.Header.Set(SessionIDHeader, id)
+				if resp, err := c.options.httpClient().Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}
+	})
+	return nil
+}
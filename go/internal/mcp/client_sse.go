@@ -0,0 +1,239 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HTTPOptions configures the HTTP-based client transports
+type HTTPOptions struct {
+	// Header is added to every request, e.g. Authorization
+	Header http.Header
+	// Client is the HTTP client to use; nil uses a client without a timeout
+	Client *http.Client
+}
+
+// httpClient returns the configured HTTP client or a default one
+func (o HTTPOptions) httpClient() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	return &http.Client{}
+}
+
+// applyHeaders copies the configured headers onto req
+func (o HTTPOptions) applyHeaders(req *http.Request) {
+	for name, values := range o.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+}
+
+// SSEDialer returns a Dialer for servers using the HTTP+SSE transport: the
+// client opens an event stream at endpoint, waits for the "endpoint" event
+// and posts its messages to the URL it names.
+func SSEDialer(endpoint string, options HTTPOptions) Dialer {
+	return func(ctx context.Context) (Connection, error) {
+		base, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSE endpoint: %w", err)
+		}
+
+		// The stream outlives the dial context, so it gets its own
+		streamCtx, cancel := context.WithCancel(context.Background())
+		stop := context.AfterFunc(ctx, cancel)
+
+		req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		options.applyHeaders(req)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Cache-Control", "no-cache")
+
+		resp, err := options.httpClient().Do(req)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			resp.Body.Close()
+			cancel()
+			return nil, fmt.Errorf("SSE connect failed: %s: %s", resp.Status, bytes.TrimSpace(body))
+		}
+
+		events := NewSSEReader(resp.Body)
+
+		// The first event must announce where to post messages
+		event, err := events.Next()
+		if err != nil || event.Event != "endpoint" {
+			resp.Body.Close()
+			cancel()
+			if err == nil {
+				err = fmt.Errorf("expected endpoint event, got %q", event.Event)
+			}
+			return nil, fmt.Errorf("SSE handshake failed: %w", err)
+		}
+
+		// From here on only Close ends the stream
+		if !stop() {
+			resp.Body.Close()
+			return nil, ctx.Err()
+		}
+
+		messageURL, err := base.Parse(event.Data)
+		if err != nil {
+			resp.Body.Close()
+			cancel()
+			return nil, fmt.Errorf("invalid message endpoint %q: %w", event.Data, err)
+		}
+
+		conn := &sseClientConnection{
+			messageURL: messageURL.String(),
+			options:    options,
+			incoming:   make(chan []byte, 16),
+			done:       make(chan struct{}),
+			cancel:     cancel,
+			body:       resp.Body,
+		}
+		go conn.readEvents(events)
+		return conn, nil
+	}
+}
+
+// sseClientConnection is a Connection over the HTTP+SSE transport
+type sseClientConnection struct {
+	messageURL string
+	options    HTTPOptions
+	incoming   chan []byte
+	done       chan struct{}
+	cancel     context.CancelFunc
+	body       io.Closer
+
+	mu        sync.Mutex
+	err       error
+	retry     time.Duration
+	closeOnce sync.Once
+}
+
+// readEvents forwards "message" events until the stream ends
+func (c *sseClientConnection) readEvents(events *SSEReader) {
+	for {
+		event, err := events.Next()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		c.mu.Lock()
+		c.retry = event.Retry
+		c.mu.Unlock()
+
+		if event.Event != "message" {
+			continue
+		}
+
+		select {
+		case c.incoming <- []byte(event.Data):
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// fail records the stream error and closes the connection
+func (c *sseClientConnection) fail(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+	c.Close()
+}
+
+// Read returns the next message received on the event stream
+func (c *sseClientConnection) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-c.incoming:
+		return data, nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.err != nil {
+			return nil, c.err
+		}
+		return nil, ErrConnectionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Write posts a message to the message endpoint. Servers that answer in
+// the POST body instead of the event stream are supported too.
+func (c *sseClientConnection) Write(ctx context.Context, data []byte) error {
+	select {
+	case <-c.done:
+		return ErrConnectionClosed
+	default:
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.messageURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	c.options.applyHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.options.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent:
+		return nil
+	case resp.StatusCode == http.StatusOK:
+		if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/json" && len(bytes.TrimSpace(body)) > 0 {
+			select {
+			case c.incoming <- body:
+			case <-c.done:
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("message post failed: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+}
+
+// RetryDelay returns the reconnection delay requested by the server
+func (c *sseClientConnection) RetryDelay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.retry
+}
+
+// Close closes the event stream
+func (c *sseClientConnection) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.cancel()
+		c.body.Close()
+	})
+	return nil
+}
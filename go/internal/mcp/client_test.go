@@ -0,0 +1,279 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serverDialer returns a dialer connecting to server in memory
+func serverDialer(t *testing.T, server *Server) Dialer {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return func(context.Context) (Connection, error) {
+		client, conn := NewInMemoryConnectionPair()
+		go server.ServeConnection(ctx, conn)
+		return client, nil
+	}
+}
+
+// scriptedDialer returns a dialer whose server answers each request with
+// the messages respond returns for it
+func scriptedDialer(respond func(req clientMessage) []string) Dialer {
+	return func(ctx context.Context) (Connection, error) {
+		client, conn := NewInMemoryConnectionPair()
+		go func() {
+			for {
+				data, err := conn.Read(context.Background())
+				if err != nil {
+					return
+				}
+				var req clientMessage
+				json.Unmarshal(data, &req)
+				for _, msg := range respond(req) {
+					conn.Write(context.Background(), []byte(msg))
+				}
+			}
+		}()
+		return client, nil
+	}
+}
+
+// initializeResponse answers initialize like a server would
+func initializeResponse(req clientMessage) string {
+	return `{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":{"protocolVersion":"` + ProtocolVersion + `","capabilities":{},"serverInfo":{"name":"test","version":"1"}}}`
+}
+
+func TestClientConnect(t *testing.T) {
+	errDial := errors.New("dial failed")
+	tests := []struct {
+		name string
+		// connect connects the client and returns the error to check
+		connect func(ctx context.Context, c *Client) error
+		dial    func(t *testing.T) Dialer
+		// reconnect enables ClientOptions.Reconnect
+		reconnect bool
+		wantErr   error
+		// wantCall is whether Call succeeds afterwards
+		wantCall bool
+	}{
+		{
+			name:     "connect",
+			connect:  func(ctx context.Context, c *Client) error { return c.Connect(ctx) },
+			dial:     func(t *testing.T) Dialer { return serverDialer(t, NewServer()) },
+			wantCall: true,
+		},
+		{
+			name: "connect twice",
+			connect: func(ctx context.Context, c *Client) error {
+				if err := c.Connect(ctx); err != nil {
+					return err
+				}
+				return c.Connect(ctx)
+			},
+			dial:     func(t *testing.T) Dialer { return serverDialer(t, NewServer()) },
+			wantErr:  ErrAlreadyConnected,
+			wantCall: true,
+		},
+		{
+			name: "connect after close",
+			connect: func(ctx context.Context, c *Client) error {
+				c.Close()
+				return c.Connect(ctx)
+			},
+			dial:    func(t *testing.T) Dialer { return serverDialer(t, NewServer()) },
+			wantErr: ErrClientClosed,
+		},
+		{
+			name:    "dial fails",
+			connect: func(ctx context.Context, c *Client) error { return c.Connect(ctx) },
+			dial: func(t *testing.T) Dialer {
+				return func(context.Context) (Connection, error) { return nil, errDial }
+			},
+			wantErr: errDial,
+		},
+		{
+			name:    "handshake fails",
+			connect: func(ctx context.Context, c *Client) error { return c.Connect(ctx) },
+			dial: func(t *testing.T) Dialer {
+				return scriptedDialer(func(req clientMessage) []string {
+					return []string{`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"error":{"code":-32600,"message":"go away"}}`}
+				})
+			},
+			wantErr: &JSONRPCError{},
+		},
+		{
+			name:    "dial fails with reconnect",
+			connect: func(ctx context.Context, c *Client) error { return c.Connect(ctx) },
+			dial: func(t *testing.T) Dialer {
+				return func(context.Context) (Connection, error) { return nil, errDial }
+			},
+			reconnect: true,
+			wantErr:   errDial,
+		},
+		{
+			name:    "handshake fails with reconnect",
+			connect: func(ctx context.Context, c *Client) error { return c.Connect(ctx) },
+			dial: func(t *testing.T) Dialer {
+				return scriptedDialer(func(req clientMessage) []string {
+					return []string{`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"error":{"code":-32600,"message":"go away"}}`}
+				})
+			},
+			reconnect: true,
+			wantErr:   &JSONRPCError{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			c := NewClient(tt.dial(t), ClientOptions{Reconnect: tt.reconnect})
+			defer c.Close()
+
+			err := tt.connect(ctx, c)
+			var rpcErr *JSONRPCError
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Connect() error = %v", err)
+			case tt.wantErr != nil && errors.As(tt.wantErr, &rpcErr):
+				if !errors.As(err, &rpcErr) {
+					t.Fatalf("Connect() error = %v, want a JSON-RPC error", err)
+				}
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Connect() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// A call must return rather than wait for a connection that
+			// isn't coming
+			err = c.Ping(ctx)
+			if tt.wantCall && err != nil {
+				t.Errorf("Ping() error = %v", err)
+			}
+			if !tt.wantCall && (err == nil || errors.Is(err, context.DeadlineExceeded)) {
+				t.Errorf("Ping() error = %v, want the connection error", err)
+			}
+		})
+	}
+}
+
+func TestClientReconnectAfterFailure(t *testing.T) {
+	fail := true
+	server := serverDialer(t, NewServer())
+	c := NewClient(func(ctx context.Context) (Connection, error) {
+		if fail {
+			return nil, errors.New("dial failed")
+		}
+		return server(ctx)
+	}, ClientOptions{})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err == nil {
+		t.Fatal("Connect() succeeded")
+	}
+	fail = false
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect() after a failure error = %v", err)
+	}
+	if err := c.Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	server := serverDialer(t, NewServer())
+	var dials atomic.Int32
+	var conn atomic.Value
+	c := NewClient(func(ctx context.Context) (Connection, error) {
+		dials.Add(1)
+		client, err := server(ctx)
+		if err == nil {
+			conn.Store(client)
+		}
+		return client, err
+	}, ClientOptions{Reconnect: true, MinReconnectDelay: 10 * time.Millisecond})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A call racing the drop may fail, but the next one waits for the
+	// new connection rather than returning the connection error
+	conn.Load().(Connection).Close()
+	c.Ping(ctx)
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping() after reconnecting error = %v", err)
+	}
+	if n := dials.Load(); n != 2 {
+		t.Errorf("dials = %d, want 2", n)
+	}
+}
+
+func TestClientDuplicateResponse(t *testing.T) {
+	c := NewClient(scriptedDialer(func(req clientMessage) []string {
+		switch req.Method {
+		case "initialize":
+			return []string{initializeResponse(req)}
+		case "ping":
+			// The same response twice, and one for a request never sent
+			response := `{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":{}}`
+			return []string{response, response, `{"jsonrpc":"2.0","id":999,"result":{}}`}
+		}
+		return nil
+	}), ClientOptions{})
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Each ping leaves extra responses behind, which must not stall the
+	// read loop for the pings that follow
+	for i := 0; i < 50; i++ {
+		if err := c.Ping(ctx); err != nil {
+			t.Fatalf("Ping() %d error = %v", i, err)
+		}
+	}
+}
+
+func TestClientCallTool(t *testing.T) {
+	server := NewServer()
+	server.RegisterTool(Tool{Name: "echo", InputSchema: map[string]interface{}{"type": "object"}},
+		func(args map[string]interface{}) (interface{}, error) {
+			return CallToolResult{Content: []Content{{Type: "text", Text: args["message"].(string)}}}, nil
+		})
+
+	c := NewClient(serverDialer(t, server), ClientOptions{})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tools, err := c.ListTools(ctx)
+	if err != nil || len(tools) != 1 || tools[0].Name != "echo" {
+		t.Fatalf("ListTools() = %v, %v", tools, err)
+	}
+	result, err := c.CallTool(ctx, "echo", map[string]interface{}{"message": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "hello" {
+		t.Errorf("CallTool() = %+v", result)
+	}
+
+	c.Close()
+	if err := c.Ping(ctx); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Ping() after Close error = %v, want %v", err, ErrClientClosed)
+	}
+}
@@ -0,0 +1,233 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxMessageSize is the largest message accepted by stream transports
+const DefaultMaxMessageSize = 4 << 20
+
+// ErrMessageTooLarge is returned when an incoming message exceeds the size limit.
+// The oversized message is discarded and the stream can continue to be read.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// Framing selects how JSON-RPC messages are delimited on a byte stream
+type Framing int
+
+const (
+	// FramingAuto detects the framing from the first bytes received
+	FramingAuto Framing = iota
+	// FramingNewline delimits messages with a newline
+	FramingNewline
+	// FramingContentLength prefixes messages with LSP-style Content-Length headers
+	FramingContentLength
+)
+
+// String returns the configuration name of the framing
+func (f Framing) String() string {
+	switch f {
+	case FramingNewline:
+		return "newline"
+	case FramingContentLength:
+		return "content-length"
+	default:
+		return "auto"
+	}
+}
+
+// ParseFraming parses a framing name: auto, newline or content-length
+func ParseFraming(name string) (Framing, error) {
+	switch strings.ToLower(name) {
+	case "", "auto":
+		return FramingAuto, nil
+	case "newline", "ndjson":
+		return FramingNewline, nil
+	case "content-length", "lsp":
+		return FramingContentLength, nil
+	default:
+		return FramingAuto, fmt.Errorf("unknown framing %q (expected auto, newline or content-length)", name)
+	}
+}
+
+// framedStream reads and writes JSON-RPC messages with a given framing
+type framedStream struct {
+	reader         *bufio.Reader
+	writer         io.Writer
+	framing        Framing
+	maxMessageSize int
+}
+
+// newFramedStream creates a framed stream over r and w
+func newFramedStream(r io.Reader, w io.Writer, framing Framing, maxMessageSize int) *framedStream {
+	if maxMessageSize <= 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+	return &framedStream{
+		reader:         bufio.NewReader(r),
+		writer:         w,
+		framing:        framing,
+		maxMessageSize: maxMessageSize,
+	}
+}
+
+// ReadMessage reads the next message. It returns io.EOF at the end of the stream
+// and ErrMessageTooLarge for a message that was skipped because of its size.
+func (s *framedStream) ReadMessage() ([]byte, error) {
+	if s.framing == FramingAuto {
+		framing, err := s.detect()
+		if err != nil {
+			return nil, err
+		}
+		s.framing = framing
+	}
+
+	if s.framing == FramingContentLength {
+		return s.readContentLength()
+	}
+	return s.readLine()
+}
+
+// WriteMessage writes a message using the stream's framing
+func (s *framedStream) WriteMessage(data []byte) error {
+	if s.framing == FramingContentLength {
+		if _, err := fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+		if _, err := s.writer.Write(data); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := s.writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write response: %w", err)
+	}
+	if _, err := s.writer.Write([]byte("\n")); err != nil {
+		return fmt.Errorf("failed to write newline: %w", err)
+	}
+	return nil
+}
+
+// detect skips leading whitespace and picks the framing from the first byte:
+// JSON starts with '{' or '[', anything else is taken to be a header
+func (s *framedStream) detect() (Framing, error) {
+	for {
+		b, err := s.reader.Peek(1)
+		if err != nil {
+			return FramingAuto, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			s.reader.Discard(1)
+		case '{', '[':
+			return FramingNewline, nil
+		default:
+			return FramingContentLength, nil
+		}
+	}
+}
+
+// readLine reads one newline-delimited message, skipping blank lines
+func (s *framedStream) readLine() ([]byte, error) {
+	for {
+		var line []byte
+		tooLarge := false
+
+		for {
+			chunk, err := s.reader.ReadSlice('\n')
+			if !tooLarge {
+				if len(line)+len(chunk) > s.maxMessageSize+2 {
+					tooLarge = true
+					line = nil
+				} else {
+					line = append(line, chunk...)
+				}
+			}
+
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err != nil {
+				if err == io.EOF && len(line) > 0 && !tooLarge {
+					// Final message without a trailing newline
+					return bytes.TrimSpace(line), nil
+				}
+				return nil, err
+			}
+			break
+		}
+
+		if tooLarge {
+			return nil, ErrMessageTooLarge
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		return line, nil
+	}
+}
+
+// readContentLength reads a message framed by Content-Length headers
+func (s *framedStream) readContentLength() ([]byte, error) {
+	length := -1
+	sawHeader := false
+
+	for {
+		line, err := s.reader.ReadSlice('\n')
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				return nil, fmt.Errorf("header line too long")
+			}
+			if err == io.EOF && !sawHeader && len(bytes.TrimSpace(line)) == 0 {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+
+		header := strings.TrimSpace(string(line))
+		if header == "" {
+			if !sawHeader {
+				// Tolerate blank lines between messages
+				continue
+			}
+			if length < 0 {
+				return nil, fmt.Errorf("missing Content-Length header")
+			}
+			break
+		}
+		sawHeader = true
+
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header %q", header)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+
+	if length > s.maxMessageSize {
+		if _, err := io.CopyN(io.Discard, s.reader, int64(length)); err != nil {
+			return nil, err
+		}
+		return nil, ErrMessageTooLarge
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseFraming(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Framing
+		wantErr bool
+	}{
+		{"", FramingAuto, false},
+		{"auto", FramingAuto, false},
+		{"newline", FramingNewline, false},
+		{"NDJSON", FramingNewline, false},
+		{"content-length", FramingContentLength, false},
+		{"lsp", FramingContentLength, false},
+		{"xml", FramingAuto, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFraming(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseFraming(%q) = %v, %v, want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFramedStreamRead(t *testing.T) {
+	big := `{"x":"` + strings.Repeat("a", 100) + `"}`
+	tests := []struct {
+		name    string
+		framing Framing
+		max     int
+		input   string
+		// want lists the messages read, or "error: <text>" for errors
+		want []string
+	}{
+		{
+			name:    "newline",
+			framing: FramingNewline,
+			input:   "{\"id\":1}\n{\"id\":2}\n",
+			want:    []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			name:    "blank lines and CRLF",
+			framing: FramingNewline,
+			input:   "\n{\"id\":1}\r\n\r\n  \n{\"id\":2}\r\n",
+			want:    []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			name:    "no trailing newline",
+			framing: FramingNewline,
+			input:   "{\"id\":1}\n{\"id\":2}",
+			want:    []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			name:    "newline too large",
+			framing: FramingNewline,
+			max:     64,
+			input:   big + "\n{\"id\":2}\n",
+			want:    []string{"error: " + ErrMessageTooLarge.Error(), `{"id":2}`},
+		},
+		{
+			name:    "content-length",
+			framing: FramingContentLength,
+			input:   "Content-Length: 8\r\n\r\n{\"id\":1}Content-Length: 8\r\n\r\n{\"id\":2}",
+			want:    []string{`{"id":1}`, `{"id":2}`},
+		},
+		{
+			name:    "content-length with other headers",
+			framing: FramingContentLength,
+			input:   "content-length: 8\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n{\"id\":1}",
+			want:    []string{`{"id":1}`},
+		},
+		{
+			name:    "content-length body with newlines",
+			framing: FramingContentLength,
+			input:   "Content-Length: 10\n\n{\n\"id\":1\n}",
+			want:    []string{"{\n\"id\":1\n}"},
+		},
+		{
+			name:    "content-length too large",
+			framing: FramingContentLength,
+			max:     64,
+			input:   "Content-Length: 108\r\n\r\n" + big + "Content-Length: 8\r\n\r\n{\"id\":2}",
+			want:    []string{"error: " + ErrMessageTooLarge.Error(), `{"id":2}`},
+		},
+		{
+			name:    "missing Content-Length",
+			framing: FramingContentLength,
+			input:   "Content-Type: application/json\r\n\r\n{}",
+			want:    []string{"error: missing Content-Length header"},
+		},
+		{
+			name:    "invalid Content-Length",
+			framing: FramingContentLength,
+			input:   "Content-Length: -1\r\n\r\n",
+			want:    []string{`error: invalid Content-Length " -1"`},
+		},
+		{
+			name:    "malformed header",
+			framing: FramingContentLength,
+			input:   "hello\r\n\r\n",
+			want:    []string{`error: malformed header "hello"`},
+		},
+		{
+			name:    "auto detects newline",
+			framing: FramingAuto,
+			input:   "  \n[{\"id\":1}]\n",
+			want:    []string{`[{"id":1}]`},
+		},
+		{
+			name:    "auto detects content-length",
+			framing: FramingAuto,
+			input:   "\r\nContent-Length: 8\r\n\r\n{\"id\":1}",
+			want:    []string{`{"id":1}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFramedStream(strings.NewReader(tt.input), io.Discard, tt.framing, tt.max)
+			for _, want := range tt.want {
+				msg, err := s.ReadMessage()
+				got := string(msg)
+				if err != nil {
+					got = "error: " + err.Error()
+				}
+				if got != want {
+					t.Fatalf("ReadMessage() = %q, want %q", got, want)
+				}
+			}
+			if msg, err := s.ReadMessage(); err != io.EOF {
+				t.Errorf("ReadMessage() at the end = %q, %v, want EOF", msg, err)
+			}
+		})
+	}
+}
+
+func TestFramedStreamWrite(t *testing.T) {
+	tests := []struct {
+		framing Framing
+		want    string
+	}{
+		{FramingNewline, "{\"id\":1}\n"},
+		{FramingAuto, "{\"id\":1}\n"},
+		{FramingContentLength, "Content-Length: 8\r\n\r\n{\"id\":1}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.framing.String(), func(t *testing.T) {
+			var out bytes.Buffer
+			s := newFramedStream(&out, &out, tt.framing, 0)
+			if err := s.WriteMessage([]byte(`{"id":1}`)); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("WriteMessage() wrote %q, want %q", out.String(), tt.want)
+			}
+
+			// What was written reads back
+			msg, err := s.ReadMessage()
+			if err != nil || string(msg) != `{"id":1}` {
+				t.Errorf("ReadMessage() = %q, %v", msg, err)
+			}
+		})
+	}
+}
+
+func TestFramedStreamWriteError(t *testing.T) {
+	s := newFramedStream(strings.NewReader(""), failingWriter{}, FramingContentLength, 0)
+	if err := s.WriteMessage([]byte("{}")); !errors.Is(err, errWriteFailed) {
+		t.Errorf("WriteMessage() error = %v, want %v", err, errWriteFailed)
+	}
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWriteFailed
+}
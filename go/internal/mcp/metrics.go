@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Tool error kinds recorded by Metrics. Middleware may report its own by
+// setting JSONRPCError.Kind.
+const (
+	ErrorKindInvalidParams  = "invalid_params"
+	ErrorKindNotFound       = "not_found"
+	ErrorKindNotAllowed     = "not_allowed"
+	ErrorKindNotInitialized = "not_initialized"
+	ErrorKindRejected       = "rejected"
+	ErrorKindExecution      = "execution"
+	ErrorKindCancelled      = "cancelled"
+	ErrorKindToolResult     = "tool_result"
+)
+
+// knownMethods are the methods metrics are labelled with; others are
+// counted as "unknown" so clients can't create new series at will
+var knownMethods = map[string]bool{
+	"initialize":                true,
+	"initialized":               true,
+	"notifications/initialized": true,
+	"notifications/cancelled":   true,
+	"tools/list":                true,
+	"tools/call":                true,
+	"ping":                      true,
+}
+
+// Metrics are the Prometheus metrics of a Server. It is a
+// prometheus.Collector; register it with the application's registry.
+type Metrics struct {
+	server *Server
+
+	requests   *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	toolErrors *prometheus.CounterVec
+	sessions   *prometheus.Desc
+}
+
+// newMetrics creates the metrics of server
+func newMetrics(server *Server) *Metrics {
+	return &Metrics{
+		server: server,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_requests_total",
+			Help: "JSON-RPC messages handled, by method, tool and status (ok or error).",
+		}, []string{"method", "tool", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mcp_request_duration_seconds",
+			Help:    "Time to handle a JSON-RPC message, by method and tool.",
+			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"method", "tool"}),
+		toolErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_tool_errors_total",
+			Help: "Failed tool calls, by tool and error kind.",
+		}, []string{"tool", "kind"}),
+		sessions: prometheus.NewDesc(
+			"mcp_sessions_active",
+			"Connected sessions, by transport.",
+			[]string{"transport"}, nil,
+		),
+	}
+}
+
+// Metrics returns the server's metrics collector
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+	m.toolErrors.Describe(ch)
+	ch <- m.sessions
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.duration.Collect(ch)
+	m.toolErrors.Collect(ch)
+
+	counts := map[string]int{"sse": 0, "websocket": 0, "stream": 0}
+	for _, session := range m.server.Sessions() {
+		counts[transportName(session.conn)]++
+	}
+	for transport, n := range counts {
+		ch <- prometheus.MustNewConstMetric(m.sessions, prometheus.GaugeValue, float64(n), transport)
+	}
+}
+
+// transportName returns the label of a connection's transport
+func transportName(conn Connection) string {
+	switch conn.(type) {
+	case nil:
+		return "stateless"
+	case *SSEConnection:
+		return "sse"
+	case *WebSocketConnection:
+		return "websocket"
+	case *StreamConnection:
+		return "stream"
+	case *inMemoryConnection:
+		return "memory"
+	default:
+		return "other"
+	}
+}
+
+// requestInfo collects what a message's metrics and span are labelled with
+// while it is handled
+type requestInfo struct {
+	tool         string
+	errorKind    string
+	failed       bool
+	errorCode    int
+	errorMessage string
+}
+
+type requestInfoKey struct{}
+
+// requestInfoFrom returns the info of the message ctx belongs to
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info
+	}
+	// Not tracked; writes are discarded
+	return &requestInfo{}
+}
+
+// observe records a handled message
+func (m *Metrics) observe(method string, request *requestInfo, elapsed time.Duration) {
+	if !knownMethods[method] {
+		method = "unknown"
+	}
+	status := "ok"
+	if request.failed {
+		status = "error"
+	}
+
+	m.requests.WithLabelValues(method, request.tool, status).Inc()
+	m.duration.WithLabelValues(method, request.tool).Observe(elapsed.Seconds())
+	if method == "tools/call" && request.errorKind != "" {
+		m.toolErrors.WithLabelValues(request.tool, request.errorKind).Inc()
+	}
+}
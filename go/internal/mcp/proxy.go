@@ -0,0 +1,372 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProxyOptions configures a Proxy
+type ProxyOptions struct {
+	// MinReconnectDelay and MaxReconnectDelay bound the reconnect backoff
+	MinReconnectDelay time.Duration
+	MaxReconnectDelay time.Duration
+	// HandshakeTimeout bounds the replayed initialize after a reconnect
+	HandshakeTimeout time.Duration
+}
+
+// Proxy relays MCP messages between a downstream connection and an upstream
+// server reached through a Dialer. Messages are forwarded unchanged in both
+// directions, so notifications and server-initiated requests pass through.
+//
+// When the upstream connection drops, requests still waiting for a response
+// fail with an error, the proxy redials with backoff and replays the
+// client's initialize handshake, so the downstream client keeps its session.
+type Proxy struct {
+	dial    Dialer
+	options ProxyOptions
+	replays atomic.Int64
+}
+
+// NewProxy creates a proxy that opens one upstream connection per
+// downstream connection with dial
+func NewProxy(dial Dialer, options ProxyOptions) *Proxy {
+	if options.MinReconnectDelay <= 0 {
+		options.MinReconnectDelay = 500 * time.Millisecond
+	}
+	if options.MaxReconnectDelay <= 0 {
+		options.MaxReconnectDelay = 30 * time.Second
+	}
+	if options.HandshakeTimeout <= 0 {
+		options.HandshakeTimeout = 30 * time.Second
+	}
+	return &Proxy{dial: dial, options: options}
+}
+
+// Serve accepts connections from the transport and proxies each of them
+func (p *Proxy) Serve(ctx context.Context, transport Transport) error {
+	return ServeTransport(ctx, transport, p)
+}
+
+// ServeConnection proxies a single downstream connection until it ends or
+// ctx is cancelled
+func (p *Proxy) ServeConnection(ctx context.Context, downstream Connection) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &proxySession{
+		proxy:      p,
+		ctx:        ctx,
+		cancel:     cancel,
+		downstream: downstream,
+		ready:      make(chan struct{}),
+		pending:    make(map[string]struct{}),
+	}
+
+	go func() {
+		<-ctx.Done()
+		downstream.Close()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.runUpstream()
+	}()
+
+	err := s.forwardDownstream()
+	cancel()
+	<-done
+	return err
+}
+
+// proxyEnvelope holds the fields used to classify a JSON-RPC message
+type proxyEnvelope struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// isRequest reports whether the message expects a response
+func (e proxyEnvelope) isRequest() bool {
+	return e.Method != "" && len(e.ID) > 0 && string(e.ID) != "null"
+}
+
+// isResponse reports whether the message answers a request
+func (e proxyEnvelope) isResponse() bool {
+	return e.Method == "" && len(e.ID) > 0
+}
+
+// proxySession is the state of one proxied connection
+type proxySession struct {
+	proxy      *Proxy
+	ctx        context.Context
+	cancel     context.CancelFunc
+	downstream Connection
+
+	mu          sync.Mutex
+	upstream    Connection
+	ready       chan struct{}
+	pending     map[string]struct{}
+	initialize  []byte
+	initialized []byte
+}
+
+// forwardDownstream relays messages from the downstream client upstream,
+// waiting for a connection while the upstream reconnects
+func (s *proxySession) forwardDownstream() error {
+	for {
+		data, err := s.downstream.Read(s.ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, ErrConnectionClosed) || s.ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var envelope proxyEnvelope
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			// Let the upstream server answer with a parse error
+			envelope = proxyEnvelope{}
+		}
+
+		upstream, err := s.waitUpstream(envelope)
+		if err != nil {
+			return nil
+		}
+
+		if err := upstream.Write(s.ctx, data); err != nil {
+			if s.ctx.Err() != nil {
+				return nil
+			}
+			logger.Warn("Proxy: upstream write failed", "error", err)
+			if envelope.isRequest() && s.removePending(envelope.ID) {
+				s.replyError(envelope.ID, fmt.Sprintf("Upstream unavailable: %v", err))
+			}
+			// Closing the connection makes runUpstream reconnect
+			upstream.Close()
+			continue
+		}
+
+		// The handshake is kept for reconnects once an upstream has seen
+		// it, so a connection never gets it both replayed and forwarded
+		s.mu.Lock()
+		switch {
+		case envelope.Method == "initialize" && envelope.isRequest():
+			s.initialize = data
+		case envelope.Method == "notifications/initialized" || envelope.Method == "initialized":
+			s.initialized = data
+		}
+		s.mu.Unlock()
+	}
+}
+
+// waitUpstream blocks until an upstream connection is available. Requests
+// are recorded as pending on that connection, so they fail if it drops.
+func (s *proxySession) waitUpstream(envelope proxyEnvelope) (Connection, error) {
+	for {
+		s.mu.Lock()
+		upstream, ready := s.upstream, s.ready
+		if upstream != nil && envelope.isRequest() {
+			s.pending[string(envelope.ID)] = struct{}{}
+		}
+		s.mu.Unlock()
+		if upstream != nil {
+			return upstream, nil
+		}
+
+		select {
+		case <-ready:
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		}
+	}
+}
+
+// runUpstream keeps an upstream connection open for as long as the session
+// lasts, reconnecting with backoff whenever it drops
+func (s *proxySession) runUpstream() {
+	delay := s.proxy.options.MinReconnectDelay
+	first := true
+
+	for s.ctx.Err() == nil {
+		// The client's own handshake reaches the first connection
+		upstream, err := s.connect(!first)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			logger.Warn("Proxy: upstream connection failed", "error", err, "retry_in", delay)
+			select {
+			case <-time.After(delay):
+			case <-s.ctx.Done():
+				return
+			}
+			delay *= 2
+			if delay > s.proxy.options.MaxReconnectDelay {
+				delay = s.proxy.options.MaxReconnectDelay
+			}
+			continue
+		}
+
+		if !first {
+			logger.Info("Proxy: upstream reconnected")
+		}
+		first = false
+		delay = s.proxy.options.MinReconnectDelay
+
+		s.mu.Lock()
+		s.upstream = upstream
+		close(s.ready)
+		s.mu.Unlock()
+
+		err = s.forwardUpstream(upstream)
+		upstream.Close()
+
+		s.mu.Lock()
+		s.upstream = nil
+		s.ready = make(chan struct{})
+		lost := s.pending
+		s.pending = make(map[string]struct{})
+		s.mu.Unlock()
+
+		if s.ctx.Err() != nil {
+			return
+		}
+		logger.Warn("Proxy: upstream connection lost", "error", err)
+
+		// The new connection won't answer requests sent on the old one
+		for id := range lost {
+			s.replyError(json.RawMessage(id), "Upstream connection lost")
+		}
+	}
+}
+
+// connect dials the upstream server. On a reconnect, if the client already
+// initialized its session, the handshake is replayed so the new connection
+// can serve it.
+func (s *proxySession) connect(reconnect bool) (Connection, error) {
+	upstream, err := s.proxy.dial(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	initialize, initialized := s.initialize, s.initialized
+	s.mu.Unlock()
+
+	if !reconnect || initialize == nil {
+		return upstream, nil
+	}
+
+	if err := s.replayHandshake(upstream, initialize, initialized); err != nil {
+		upstream.Close()
+		return nil, fmt.Errorf("initialize replay failed: %w", err)
+	}
+	return upstream, nil
+}
+
+// replayHandshake re-sends the client's initialize request under a new id,
+// swallows its response and re-sends the initialized notification
+func (s *proxySession) replayHandshake(upstream Connection, initialize, initialized []byte) error {
+	ctx, cancel := context.WithTimeout(s.ctx, s.proxy.options.HandshakeTimeout)
+	defer cancel()
+
+	var request map[string]json.RawMessage
+	if err := json.Unmarshal(initialize, &request); err != nil {
+		return err
+	}
+	id, _ := json.Marshal(fmt.Sprintf("proxy-initialize-%d", s.proxy.replays.Add(1)))
+	request["id"] = id
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	if err := upstream.Write(ctx, data); err != nil {
+		return err
+	}
+
+	for {
+		data, err := upstream.Read(ctx)
+		if err != nil {
+			return err
+		}
+
+		var envelope proxyEnvelope
+		if json.Unmarshal(data, &envelope) == nil && envelope.isResponse() && string(envelope.ID) == string(id) {
+			var response struct {
+				Error *JSONRPCError `json:"error"`
+			}
+			if err := json.Unmarshal(data, &response); err != nil {
+				return err
+			}
+			if response.Error != nil {
+				return response.Error
+			}
+			break
+		}
+
+		// Anything else the server sends meanwhile still goes downstream
+		if err := s.downstream.Write(ctx, data); err != nil {
+			return err
+		}
+	}
+
+	if initialized != nil {
+		return upstream.Write(ctx, initialized)
+	}
+	return nil
+}
+
+// forwardUpstream relays messages from upstream to the downstream client
+// until the upstream connection fails
+func (s *proxySession) forwardUpstream(upstream Connection) error {
+	for {
+		data, err := upstream.Read(s.ctx)
+		if err != nil {
+			return err
+		}
+
+		var envelope proxyEnvelope
+		if json.Unmarshal(data, &envelope) == nil && envelope.isResponse() {
+			s.removePending(envelope.ID)
+		}
+
+		if err := s.downstream.Write(s.ctx, data); err != nil {
+			// The client is gone; end the session
+			s.cancel()
+			return err
+		}
+	}
+}
+
+// removePending forgets an answered request, reporting whether it was pending
+func (s *proxySession) removePending(id json.RawMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[string(id)]; !ok {
+		return false
+	}
+	delete(s.pending, string(id))
+	return true
+}
+
+// replyError answers a downstream request with an error
+func (s *proxySession) replyError(id json.RawMessage, message string) {
+	data, err := json.Marshal(JSONRPCResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &JSONRPCError{Code: -32000, Message: message},
+	})
+	if err != nil {
+		return
+	}
+	if err := s.downstream.Write(s.ctx, data); err != nil {
+		logger.Warn("Proxy: failed to write error response", "error", err)
+	}
+}
@@ -0,0 +1,101 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingConnection counts the initialize requests read through it
+type countingConnection struct {
+	Connection
+	initializes atomic.Int32
+}
+
+func (c *countingConnection) Read(ctx context.Context) ([]byte, error) {
+	data, err := c.Connection.Read(ctx)
+	if err == nil {
+		var msg clientMessage
+		if json.Unmarshal(data, &msg) == nil && msg.Method == "initialize" {
+			c.initializes.Add(1)
+		}
+	}
+	return data, err
+}
+
+// proxyUpstream serves upstream connections in memory and keeps the server
+// end of each one
+type proxyUpstream struct {
+	mu    sync.Mutex
+	conns []*countingConnection
+}
+
+func (u *proxyUpstream) dialer(t *testing.T, server *Server) Dialer {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return func(context.Context) (Connection, error) {
+		// The client's initialize arrives while the first dial is in flight
+		time.Sleep(20 * time.Millisecond)
+		client, conn := NewInMemoryConnectionPair()
+		counted := &countingConnection{Connection: conn}
+		u.mu.Lock()
+		u.conns = append(u.conns, counted)
+		u.mu.Unlock()
+		go server.ServeConnection(ctx, counted)
+		return client, nil
+	}
+}
+
+func (u *proxyUpstream) connections() []*countingConnection {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]*countingConnection(nil), u.conns...)
+}
+
+func TestProxyInitializesUpstreamOnce(t *testing.T) {
+	upstream := &proxyUpstream{}
+	proxy := NewProxy(upstream.dialer(t, NewServer()), ProxyOptions{MinReconnectDelay: 10 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := NewClient(func(context.Context) (Connection, error) {
+		client, conn := NewInMemoryConnectionPair()
+		go proxy.ServeConnection(ctx, conn)
+		return client, nil
+	}, ClientOptions{})
+	defer c.Close()
+
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+	conns := upstream.connections()
+	if len(conns) != 1 {
+		t.Fatalf("upstream connections = %d, want 1", len(conns))
+	}
+	if n := conns[0].initializes.Load(); n != 1 {
+		t.Errorf("initialize requests on the first connection = %d, want 1", n)
+	}
+
+	// Dropping the upstream makes the proxy redial and replay the handshake
+	conns[0].Close()
+	for len(upstream.connections()) < 2 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("proxy did not reconnect")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if _, err := c.ListTools(ctx); err != nil {
+		t.Fatalf("ListTools() after reconnect error = %v", err)
+	}
+	conns = upstream.connections()
+	if n := conns[1].initializes.Load(); n != 1 {
+		t.Errorf("initialize requests after the reconnect = %d, want 1", n)
+	}
+}
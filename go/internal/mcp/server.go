@@ -1,9 +1,18 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
 )
 
 const (
@@ -12,59 +21,483 @@ const (
 	ServerVersion   = "1.0.0"
 )
 
+// logger is the logger of the "mcp" component
+var logger = logging.Component("mcp")
+
 // Server represents an MCP server
 type Server struct {
+	toolsMu      sync.RWMutex
 	tools        map[string]Tool
-	toolHandlers map[string]ToolHandler
-	initialized  bool
+	toolHandlers map[string]ContextToolHandler
+	// disabledTools are hidden from tools/list and refused by tools/call;
+	// they stay disabled if they are registered again
+	disabledTools map[string]bool
+
+	// toolsChangedPending coalesces bursts of tool list changes into one notification
+	toolsChangedPending atomic.Bool
+
+	// toolFilter decides which tools the caller of a request may see and call
+	toolFilter ToolFilter
+	// toolMiddleware wraps every tool call, outermost first
+	toolMiddleware []ToolMiddleware
+
+	sessionsMu sync.RWMutex
+	sessions   map[string]*Session
+
+	// inflight counts requests being handled; once shuttingDown is set no
+	// new ones are accepted
+	shutdownMu    sync.Mutex
+	shuttingDown  bool
+	inflight      sync.WaitGroup
+	shutdownHooks []func()
+
+	metrics *Metrics
 }
 
+// ErrServerShutdown is the cancellation cause of requests still running
+// when the shutdown grace period ends
+var ErrServerShutdown = errors.New("server shutting down")
+
 // NewServer creates a new MCP server
 func NewServer() *Server {
-	return &Server{
-		tools:        make(map[string]Tool),
-		toolHandlers: make(map[string]ToolHandler),
-		initialized:  false,
+	s := &Server{
+		tools:         make(map[string]Tool),
+		toolHandlers:  make(map[string]ContextToolHandler),
+		disabledTools: make(map[string]bool),
+		sessions:      make(map[string]*Session),
 	}
+	s.metrics = newMetrics(s)
+	return s
+}
+
+// ToolFilter reports whether the caller of the request ctx belongs to may
+// use tool
+type ToolFilter func(ctx context.Context, tool Tool) bool
+
+// SetToolFilter restricts tools per caller, e.g. by the scopes of the API
+// key the session authenticated with. Filtered tools are left out of
+// tools/list and refused by tools/call. It must be called before serving.
+func (s *Server) SetToolFilter(filter ToolFilter) {
+	s.toolFilter = filter
+}
+
+// ToolCallFunc calls a tool with its arguments
+type ToolCallFunc func(ctx context.Context, tool Tool, args map[string]interface{}) (interface{}, error)
+
+// ToolMiddleware wraps tool calls, e.g. to limit, meter or log them. A
+// middleware may reject a call by returning a *JSONRPCError, which is sent
+// to the client as is; other errors become tool execution errors.
+type ToolMiddleware func(next ToolCallFunc) ToolCallFunc
+
+// UseToolMiddleware adds middleware around every tool call. Middleware
+// added first runs first. It must be called before serving.
+func (s *Server) UseToolMiddleware(middleware ...ToolMiddleware) {
+	s.toolMiddleware = append(s.toolMiddleware, middleware...)
 }
 
 // RegisterTool registers a new tool with the server
 func (s *Server) RegisterTool(tool Tool, handler ToolHandler) {
+	s.RegisterToolContext(tool, func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return handler(args)
+	})
+}
+
+// RegisterToolContext registers a tool whose handler receives the request
+// context, which is cancelled when the client cancels the call. Registering
+// a name again replaces the tool. Connected clients are notified.
+func (s *Server) RegisterToolContext(tool Tool, handler ContextToolHandler) {
+	s.toolsMu.Lock()
 	s.tools[tool.Name] = tool
 	s.toolHandlers[tool.Name] = handler
-	log.Printf("Registered tool: %s", tool.Name)
+	s.toolsMu.Unlock()
+
+	logger.Info("Registered tool", "tool", tool.Name)
+	s.notifyToolsChanged()
+}
+
+// UnregisterTool removes a tool and notifies connected clients
+func (s *Server) UnregisterTool(name string) {
+	s.toolsMu.Lock()
+	_, exists := s.tools[name]
+	delete(s.tools, name)
+	delete(s.toolHandlers, name)
+	s.toolsMu.Unlock()
+
+	if exists {
+		logger.Info("Unregistered tool", "tool", name)
+		s.notifyToolsChanged()
+	}
+}
+
+// ErrToolNotFound is returned for operations on tools that are not registered
+var ErrToolNotFound = errors.New("tool not found")
+
+// Tools returns the registered tools, including disabled ones, by name
+func (s *Server) Tools() []Tool {
+	s.toolsMu.RLock()
+	tools := make([]Tool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		tools = append(tools, tool)
+	}
+	s.toolsMu.RUnlock()
+
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// ToolEnabled reports whether a tool has not been disabled
+func (s *Server) ToolEnabled(name string) bool {
+	s.toolsMu.RLock()
+	defer s.toolsMu.RUnlock()
+	return !s.disabledTools[name]
+}
+
+// SetToolEnabled enables or disables a registered tool at runtime without
+// unregistering it. Connected clients are notified.
+func (s *Server) SetToolEnabled(name string, enabled bool) error {
+	s.toolsMu.Lock()
+	if _, exists := s.tools[name]; !exists {
+		s.toolsMu.Unlock()
+		return fmt.Errorf("%w: %s", ErrToolNotFound, name)
+	}
+	changed := s.disabledTools[name] == enabled
+	if enabled {
+		delete(s.disabledTools, name)
+	} else {
+		s.disabledTools[name] = true
+	}
+	s.toolsMu.Unlock()
+
+	if changed {
+		s.notifyToolsChanged()
+	}
+	return nil
+}
+
+// notifyToolsChanged sends notifications/tools/list_changed to every
+// initialized session. Changes made in quick succession share one notification.
+func (s *Server) notifyToolsChanged() {
+	if !s.toolsChangedPending.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		s.toolsChangedPending.Store(false)
+		for _, session := range s.Sessions() {
+			// One-off requests have no connection to notify
+			if !session.Initialized() || session.conn == nil {
+				continue
+			}
+			if err := session.Notify(context.Background(), "notifications/tools/list_changed", nil); err != nil {
+				logger.Warn("Failed to notify session", "session_id", session.id, "error", err)
+			}
+		}
+	}()
+}
+
+// Serve accepts connections from the transport and serves each of them until
+// the transport is exhausted or ctx is cancelled. It waits for all
+// connections to finish before returning.
+func (s *Server) Serve(ctx context.Context, transport Transport) error {
+	return ServeTransport(ctx, transport, s)
+}
+
+// OnShutdown registers a function to run at the end of Shutdown, after all
+// sessions are closed, e.g. to flush state to disk
+func (s *Server) OnShutdown(hook func()) {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// Shutdown gracefully stops the server: new requests are refused, requests
+// in flight may finish until ctx ends, after which they are cancelled. All
+// sessions are then closed and the OnShutdown hooks run. Callers should stop
+// accepting new connections first, e.g. with http.Server.Shutdown.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownMu.Lock()
+	s.shuttingDown = true
+	hooks := s.shutdownHooks
+	s.shutdownMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		logger.Warn("Shutdown grace period ended, cancelling in-flight requests")
+		for _, session := range s.Sessions() {
+			session.cancelAllRequests(ErrServerShutdown)
+		}
+
+		// Give cancelled requests a moment to send their error responses
+		select {
+		case <-drained:
+		case <-time.After(time.Second):
+		}
+	}
+
+	for _, session := range s.Sessions() {
+		session.Close()
+	}
+
+	for _, hook := range hooks {
+		hook()
+	}
+	return err
+}
+
+// beginCall records a new in-flight request, refusing it once shutdown has begun
+func (s *Server) beginCall() bool {
+	s.shutdownMu.Lock()
+	defer s.shutdownMu.Unlock()
+	if s.shuttingDown {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// ServeConnection serves a single connection until the peer goes away or ctx
+// is cancelled. Requests are handled concurrently; notifications are handled
+// in order so that e.g. "notifications/initialized" takes effect before any
+// request that follows it.
+func (s *Server) ServeConnection(ctx context.Context, conn Connection) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	session := newSession(ctx, conn)
+	s.addSession(session)
+	defer s.removeSession(session)
+	ctx = logging.With(ctx, "session_id", session.id)
+
+	// Unblock a pending Read when the context ends
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer func() {
+		// Let in-flight requests finish writing their responses first
+		wg.Wait()
+		conn.Close()
+	}()
+
+	for {
+		// Trace context and log attributes that came with the message, e.g.
+		// in HTTP headers
+		data, msgCtx, err := readMessage(ctx, conn)
+		if err != nil {
+			if errors.Is(err, ErrMessageTooLarge) {
+				logger.WarnContext(ctx, "Dropped oversized message")
+				response, _ := s.errorResponse(nil, -32600, "Message too large", nil)
+				if err := conn.Write(ctx, response); err != nil {
+					return err
+				}
+				continue
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, ErrConnectionClosed) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		id, ok := requestID(data)
+		if !ok {
+			s.respond(msgCtx, session, data)
+			continue
+		}
+
+		if !s.beginCall() {
+			response, _ := s.errorResponse(json.RawMessage(id), -32000, "Server is shutting down", nil)
+			if err := conn.Write(ctx, response); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Each request gets its own context so notifications/cancelled can stop it
+		reqCtx, done := session.beginRequest(msgCtx, id)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.inflight.Done()
+			defer done()
+			s.respond(reqCtx, session, data)
+		}()
+	}
+}
+
+// respond handles one message and writes the response, if any, to the session
+func (s *Server) respond(ctx context.Context, session *Session, data []byte) {
+	response, err := s.handleMessage(ctx, session, data)
+	if err != nil {
+		logger.ErrorContext(ctx, "Error handling request", "error", err)
+		return
+	}
+
+	// Skip if no response (notification)
+	if response == nil {
+		return
+	}
+
+	// Cancelled requests are not answered
+	if errors.Is(context.Cause(ctx), errRequestCancelled) {
+		return
+	}
+
+	if err := session.conn.Write(ctx, response); err != nil {
+		logger.WarnContext(ctx, "Error writing response", "error", err)
+	}
 }
 
-// HandleRequest processes a JSON-RPC request and returns a response
+// Sessions returns the currently connected sessions
+func (s *Server) Sessions() []*Session {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// Session returns the connected session with id, or nil
+func (s *Server) Session(id string) *Session {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+	return s.sessions[id]
+}
+
+// CloseSession cancels the in-flight requests of a connected session and
+// closes its connection, reporting whether it was found
+func (s *Server) CloseSession(id string) bool {
+	session := s.Session(id)
+	if session == nil {
+		return false
+	}
+	session.cancelAllRequests(ErrSessionClosed)
+	session.Close()
+	return true
+}
+
+// addSession registers a connected session
+func (s *Server) addSession(session *Session) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	s.sessions[session.id] = session
+}
+
+// removeSession unregisters a disconnected session
+func (s *Server) removeSession(session *Session) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	delete(s.sessions, session.id)
+}
+
+// HandleRequest processes a JSON-RPC request that isn't tied to a connection
+// and returns a response
 func (s *Server) HandleRequest(reqData []byte) ([]byte, error) {
+	return s.HandleRequestContext(context.Background(), reqData)
+}
+
+// HandleRequestContext is HandleRequest with a context, whose trace context
+// becomes the parent of the request's span. Each request gets a session of
+// its own, listed with the connected ones while it runs, so one-off callers
+// don't share client state and their requests can be seen and cancelled.
+// There is no handshake to wait for, so the session counts as initialized.
+func (s *Server) HandleRequestContext(ctx context.Context, reqData []byte) ([]byte, error) {
+	session := newSession(ctx, nil)
+	session.initialized.Store(true)
+
+	id, ok := requestID(reqData)
+	if !ok {
+		return s.handleMessage(ctx, session, reqData)
+	}
+	if !s.beginCall() {
+		return s.errorResponse(json.RawMessage(id), -32000, "Server is shutting down", nil)
+	}
+	defer s.inflight.Done()
+
+	s.addSession(session)
+	defer s.removeSession(session)
+	ctx = logging.With(ctx, "session_id", session.id)
+
+	ctx, done := session.beginRequest(ctx, id)
+	defer done()
+	return s.handleMessage(ctx, session, reqData)
+}
+
+// handleMessage processes a JSON-RPC message for a session and returns a response
+func (s *Server) handleMessage(ctx context.Context, session *Session, reqData []byte) ([]byte, error) {
 	var req JSONRPCRequest
+	start := time.Now()
+	request := &requestInfo{}
+	ctx = context.WithValue(ctx, requestInfoKey{}, request)
+	defer func() {
+		elapsed := time.Since(start)
+		s.metrics.observe(req.Method, request, elapsed)
+		logRequest(ctx, req, request, elapsed)
+	}()
+
 	if err := json.Unmarshal(reqData, &req); err != nil {
-		return s.errorResponse(nil, -32700, "Parse error", nil)
+		return s.fail(ctx, "", nil, -32700, "Parse error", nil)
+	}
+	if req.ID != nil {
+		ctx = logging.With(ctx, "jsonrpc_id", req.ID)
 	}
 
+	session.touch()
+	session.describeRequest(ctx, req.Method, "")
+	ctx = contextWithSession(ctx, session)
+
+	ctx, span := startRequestSpan(ctx, session, req)
+	defer endRequestSpan(span, request)
+
 	// Handle different methods
 	switch req.Method {
 	case "initialize":
-		return s.handleInitialize(req)
-	case "initialized":
-		return s.handleInitialized(req)
+		return s.handleInitialize(ctx, session, req)
+	case "initialized", "notifications/initialized":
+		return s.handleInitialized(ctx, session, req)
+	case "notifications/cancelled":
+		return s.handleCancelled(ctx, session, req)
 	case "tools/list":
-		return s.handleListTools(req)
+		return s.handleListTools(ctx, session, req)
 	case "tools/call":
-		return s.handleCallTool(req)
+		return s.handleCallTool(ctx, session, req)
 	case "ping":
-		return s.handlePing(req)
+		return s.handlePing(ctx, session, req)
 	default:
-		return s.errorResponse(req.ID, -32601, fmt.Sprintf("Method not found: %s", req.Method), nil)
+		// Unknown notifications are ignored; they never get a response
+		if req.ID == nil {
+			return nil, nil
+		}
+		return s.fail(ctx, "", req.ID, -32601, fmt.Sprintf("Method not found: %s", req.Method), nil)
 	}
 }
 
 // handleInitialize handles the initialize request
-func (s *Server) handleInitialize(req JSONRPCRequest) ([]byte, error) {
+func (s *Server) handleInitialize(ctx context.Context, session *Session, req JSONRPCRequest) ([]byte, error) {
+	var params InitializeParams
+	if err := decodeParams(req.Params, &params); err == nil {
+		session.setClientInfo(params.ClientInfo)
+	}
+
 	result := InitializeResult{
 		ProtocolVersion: ProtocolVersion,
 		Capabilities: map[string]interface{}{
-			"tools": map[string]interface{}{},
+			"tools": map[string]interface{}{
+				"listChanged": true,
+			},
 		},
 		ServerInfo: ServerInfo{
 			Name:    ServerName,
@@ -76,23 +509,44 @@ func (s *Server) handleInitialize(req JSONRPCRequest) ([]byte, error) {
 }
 
 // handleInitialized handles the initialized notification
-func (s *Server) handleInitialized(req JSONRPCRequest) ([]byte, error) {
-	s.initialized = true
-	log.Println("Server initialized")
+func (s *Server) handleInitialized(ctx context.Context, session *Session, req JSONRPCRequest) ([]byte, error) {
+	session.initialized.Store(true)
+	logger.InfoContext(ctx, "Session initialized", "client", session.ClientInfo().Name)
 	// Notifications don't need a response
 	return nil, nil
 }
 
+// handleCancelled handles the cancelled notification by cancelling the
+// request it names
+func (s *Server) handleCancelled(ctx context.Context, session *Session, req JSONRPCRequest) ([]byte, error) {
+	id, err := json.Marshal(req.Params["requestId"])
+	if err == nil && session.cancelRequest(string(id)) {
+		reason, _ := req.Params["reason"].(string)
+		logger.InfoContext(ctx, "Request cancelled", "cancelled_id", json.RawMessage(id), "reason", reason)
+	}
+	return nil, nil
+}
+
 // handleListTools handles the tools/list request
-func (s *Server) handleListTools(req JSONRPCRequest) ([]byte, error) {
-	if !s.initialized {
-		return s.errorResponse(req.ID, -32002, "Server not initialized", nil)
+func (s *Server) handleListTools(ctx context.Context, session *Session, req JSONRPCRequest) ([]byte, error) {
+	if !session.Initialized() {
+		return s.fail(ctx, ErrorKindNotInitialized, req.ID, -32002, "Server not initialized", nil)
 	}
 
+	s.toolsMu.RLock()
 	tools := make([]Tool, 0, len(s.tools))
 	for _, tool := range s.tools {
-		tools = append(tools, tool)
+		if s.disabledTools[tool.Name] {
+			continue
+		}
+		if s.toolFilter == nil || s.toolFilter(ctx, tool) {
+			tools = append(tools, tool)
+		}
 	}
+	s.toolsMu.RUnlock()
+
+	// Keep the order stable across calls
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
 
 	result := ListToolsResult{
 		Tools: tools,
@@ -102,38 +556,83 @@ func (s *Server) handleListTools(req JSONRPCRequest) ([]byte, error) {
 }
 
 // handleCallTool handles the tools/call request
-func (s *Server) handleCallTool(req JSONRPCRequest) ([]byte, error) {
-	if !s.initialized {
-		return s.errorResponse(req.ID, -32002, "Server not initialized", nil)
+func (s *Server) handleCallTool(ctx context.Context, session *Session, req JSONRPCRequest) ([]byte, error) {
+	if !session.Initialized() {
+		return s.fail(ctx, ErrorKindNotInitialized, req.ID, -32002, "Server not initialized", nil)
 	}
 
 	// Parse params
-	paramsBytes, err := json.Marshal(req.Params)
-	if err != nil {
-		return s.errorResponse(req.ID, -32603, "Internal error", err.Error())
-	}
-
 	var params CallToolParams
-	if err := json.Unmarshal(paramsBytes, &params); err != nil {
-		return s.errorResponse(req.ID, -32602, "Invalid params", err.Error())
+	if err := decodeParams(req.Params, &params); err != nil {
+		return s.fail(ctx, ErrorKindInvalidParams, req.ID, -32602, "Invalid params", err.Error())
 	}
 
 	// Find tool handler
+	s.toolsMu.RLock()
+	tool := s.tools[params.Name]
 	handler, exists := s.toolHandlers[params.Name]
+	disabled := s.disabledTools[params.Name]
+	s.toolsMu.RUnlock()
 	if !exists {
-		return s.errorResponse(req.ID, -32602, fmt.Sprintf("Tool not found: %s", params.Name), nil)
+		return s.fail(ctx, ErrorKindNotFound, req.ID, -32602, fmt.Sprintf("Tool not found: %s", params.Name), nil)
+	}
+	// Only registered names are used as labels
+	requestInfoFrom(ctx).tool = tool.Name
+	session.describeRequest(ctx, "", tool.Name)
+	ctx = logging.With(ctx, "tool", tool.Name)
+	if disabled {
+		return s.fail(ctx, ErrorKindNotAllowed, req.ID, -32001, fmt.Sprintf("Tool disabled: %s", params.Name), nil)
+	}
+	if s.toolFilter != nil && !s.toolFilter(ctx, tool) {
+		return s.fail(ctx, ErrorKindNotAllowed, req.ID, -32001, fmt.Sprintf("Tool not allowed: %s", params.Name), nil)
+	}
+
+	if params.Meta != nil && params.Meta.ProgressToken != nil {
+		ctx = contextWithProgressToken(ctx, params.Meta.ProgressToken)
 	}
 
-	// Execute tool
-	result, err := handler(params.Arguments)
+	// Execute tool through the middleware, in a span of its own
+	call := traceToolCall(func(ctx context.Context, tool Tool, args map[string]interface{}) (interface{}, error) {
+		return handler(ctx, args)
+	})
+	for i := len(s.toolMiddleware) - 1; i >= 0; i-- {
+		call = s.toolMiddleware[i](call)
+	}
+	result, err := call(ctx, tool, params.Arguments)
 	if err != nil {
-		return s.errorResponse(req.ID, -32603, fmt.Sprintf("Tool execution error: %s", err.Error()), nil)
+		if rpcErr, ok := err.(*JSONRPCError); ok {
+			kind := rpcErr.Kind
+			if kind == "" {
+				kind = ErrorKindRejected
+			}
+			return s.fail(ctx, kind, req.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+		}
+		kind := ErrorKindExecution
+		if ctx.Err() != nil {
+			// Tell the client why, e.g. that the server cancelled the call
+			kind, err = ErrorKindCancelled, context.Cause(ctx)
+		}
+		return s.fail(ctx, kind, req.ID, -32603, fmt.Sprintf("Tool execution error: %s", err.Error()), nil)
+	}
+
+	// Handlers that build their own result, e.g. proxied tools, are passed through
+	switch r := result.(type) {
+	case *CallToolResult:
+		if r.IsError {
+			requestInfoFrom(ctx).errorKind = ErrorKindToolResult
+		}
+		return s.successResponse(req.ID, r)
+	case CallToolResult:
+		if r.IsError {
+			requestInfoFrom(ctx).errorKind = ErrorKindToolResult
+		}
+		return s.successResponse(req.ID, r)
 	}
 
 	// Format result
 	resultJSON, err := json.Marshal(result)
 	if err != nil {
-		return s.errorResponse(req.ID, -32603, "Failed to marshal result", err.Error())
+		return s.fail(ctx, ErrorKindExecution, req.ID, -32603, "Failed to marshal result", err.Error())
 	}
 
 	toolResult := CallToolResult{
@@ -149,12 +648,63 @@ func (s *Server) handleCallTool(req JSONRPCRequest) ([]byte, error) {
 }
 
 // handlePing handles the ping request
-func (s *Server) handlePing(req JSONRPCRequest) ([]byte, error) {
+func (s *Server) handlePing(ctx context.Context, session *Session, req JSONRPCRequest) ([]byte, error) {
 	return s.successResponse(req.ID, map[string]interface{}{
 		"status": "ok",
 	})
 }
 
+// logRequest logs a handled message: tool calls and failures at the info
+// level, everything else at debug
+func logRequest(ctx context.Context, req JSONRPCRequest, request *requestInfo, elapsed time.Duration) {
+	level := slog.LevelDebug
+	if req.Method == "tools/call" || request.failed {
+		level = slog.LevelInfo
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if request.tool != "" {
+		attrs = append(attrs, slog.String("tool", request.tool))
+	}
+	if request.failed {
+		attrs = append(attrs, slog.Int("error_code", request.errorCode), slog.String("error", request.errorMessage))
+	}
+	if request.errorKind != "" {
+		attrs = append(attrs, slog.String("error_kind", request.errorKind))
+	}
+	logger.LogAttrs(ctx, level, "Handled request", attrs...)
+}
+
+// decodeParams converts generic request params into a typed struct
+func decodeParams(params map[string]interface{}, v interface{}) error {
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(paramsBytes, v)
+}
+
+// requestID returns the raw id of a JSON-RPC request. Notifications, and
+// messages that don't parse, report false.
+func requestID(data []byte) (string, bool) {
+	var msg struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return "", false
+	}
+	if len(msg.ID) == 0 || string(msg.ID) == "null" {
+		return "", false
+	}
+	return string(msg.ID), true
+}
+
 // successResponse creates a success JSON-RPC response
 func (s *Server) successResponse(id interface{}, result interface{}) ([]byte, error) {
 	resp := JSONRPCResponse{
@@ -165,6 +715,17 @@ func (s *Server) successResponse(id interface{}, result interface{}) ([]byte, er
 	return json.Marshal(resp)
 }
 
+// fail creates an error JSON-RPC response for the message ctx belongs to,
+// recording the failure and, for tool calls, its kind
+func (s *Server) fail(ctx context.Context, kind string, id interface{}, code int, message string, data interface{}) ([]byte, error) {
+	request := requestInfoFrom(ctx)
+	request.failed = true
+	request.errorKind = kind
+	request.errorCode = code
+	request.errorMessage = message
+	return s.errorResponse(id, code, message, data)
+}
+
 // errorResponse creates an error JSON-RPC response
 func (s *Server) errorResponse(id interface{}, code int, message string, data interface{}) ([]byte, error) {
 	resp := JSONRPCResponse{
@@ -0,0 +1,174 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a server with an echo tool
+func newTestServer() *Server {
+	server := NewServer()
+	server.RegisterTool(Tool{Name: "echo", InputSchema: map[string]interface{}{"type": "object"}},
+		func(args map[string]interface{}) (interface{}, error) {
+			message, _ := args["message"].(string)
+			return CallToolResult{Content: []Content{{Type: "text", Text: message}}}, nil
+		})
+	return server
+}
+
+func TestHandleRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  string
+		wantNil  bool
+		wantCode int
+		wantText string
+	}{
+		{name: "ping", request: `{"jsonrpc":"2.0","id":1,"method":"ping"}`},
+		{name: "tools/list", request: `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`},
+		{
+			name:     "tools/call",
+			request:  `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`,
+			wantText: "hi",
+		},
+		{
+			name:     "unknown tool",
+			request:  `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"missing"}}`,
+			wantCode: -32602,
+		},
+		{name: "unknown method", request: `{"jsonrpc":"2.0","id":1,"method":"nope"}`, wantCode: -32601},
+		{name: "parse error", request: `{"jsonrpc":`, wantCode: -32700},
+		{name: "notification", request: `{"jsonrpc":"2.0","method":"notifications/initialized"}`, wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer()
+			data, err := server.HandleRequest([]byte(tt.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if data != nil {
+					t.Errorf("HandleRequest() = %s, want no response", data)
+				}
+				return
+			}
+
+			var response struct {
+				Result *CallToolResult `json:"result"`
+				Error  *JSONRPCError   `json:"error"`
+			}
+			if err := json.Unmarshal(data, &response); err != nil {
+				t.Fatalf("invalid response %s: %v", data, err)
+			}
+			switch {
+			case tt.wantCode != 0:
+				if response.Error == nil || response.Error.Code != tt.wantCode {
+					t.Errorf("HandleRequest() = %s, want error %d", data, tt.wantCode)
+				}
+			case response.Error != nil:
+				t.Errorf("HandleRequest() error = %v", response.Error)
+			case tt.wantText != "":
+				if len(response.Result.Content) != 1 || response.Result.Content[0].Text != tt.wantText {
+					t.Errorf("HandleRequest() = %s, want %q", data, tt.wantText)
+				}
+			}
+			if sessions := server.Sessions(); len(sessions) != 0 {
+				t.Errorf("%d sessions left after the request", len(sessions))
+			}
+		})
+	}
+}
+
+func TestHandleRequestSessions(t *testing.T) {
+	server := NewServer()
+	started := make(chan *Session, 2)
+	release := make(chan struct{})
+	server.RegisterToolContext(Tool{Name: "wait", InputSchema: map[string]interface{}{"type": "object"}},
+		func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			started <- SessionFromContext(ctx)
+			select {
+			case <-release:
+				return CallToolResult{Content: []Content{{Type: "text", Text: "done"}}}, nil
+			case <-ctx.Done():
+				return nil, context.Cause(ctx)
+			}
+		})
+
+	responses := make(chan []byte, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			data, _ := server.HandleRequest([]byte(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`))
+			responses <- data
+		}()
+	}
+	first, second := <-started, <-started
+
+	// Each request has a session of its own, listed while it runs
+	if first == nil || second == nil || first.ID() == second.ID() {
+		t.Fatalf("requests share a session: %v, %v", first, second)
+	}
+	if got := len(server.Sessions()); got != 2 {
+		t.Errorf("%d sessions listed, want 2", got)
+	}
+	if requests := server.Session(first.ID()).Requests(); len(requests) != 1 || requests[0].Tool != "wait" {
+		t.Errorf("Requests() = %+v", requests)
+	}
+
+	// ... and can be cancelled on its own, although both use id 7
+	if !server.Session(first.ID()).CancelRequest("7") {
+		t.Fatal("CancelRequest() didn't find the request")
+	}
+	cancelled := <-responses
+	if !containsError(cancelled) {
+		t.Errorf("cancelled request answered %s, want an error", cancelled)
+	}
+	close(release)
+	if done := <-responses; containsError(done) {
+		t.Errorf("other request answered %s", done)
+	}
+
+	if sessions := server.Sessions(); len(sessions) != 0 {
+		t.Errorf("%d sessions left after the requests", len(sessions))
+	}
+}
+
+func TestHandleRequestShutdown(t *testing.T) {
+	server := newTestServer()
+	hooked := false
+	server.OnShutdown(func() { hooked = true })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !hooked {
+		t.Error("OnShutdown hook didn't run")
+	}
+
+	data, err := server.HandleRequest([]byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response struct {
+		Error *JSONRPCError `json:"error"`
+	}
+	json.Unmarshal(data, &response)
+	if response.Error == nil || response.Error.Code != -32000 {
+		t.Errorf("request after shutdown answered %s", data)
+	}
+}
+
+// containsError reports whether a JSON-RPC response is an error, or a tool
+// result with isError set
+func containsError(data []byte) bool {
+	var response struct {
+		Result *CallToolResult `json:"result"`
+		Error  *JSONRPCError   `json:"error"`
+	}
+	json.Unmarshal(data, &response)
+	return response.Error != nil || (response.Result != nil && response.Result.IsError)
+}
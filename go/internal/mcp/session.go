@@ -0,0 +1,285 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Session holds the state of one client connected to the server
+type Session struct {
+	id           string
+	conn         Connection
+	ctx          context.Context
+	createdAt    time.Time
+	lastActivity atomic.Int64
+	initialized  atomic.Bool
+
+	mu         sync.RWMutex
+	clientInfo ClientInfo
+
+	requestsMu sync.Mutex
+	requests   map[string]*inflightRequest
+}
+
+// inflightRequest is a request being handled; method and tool are filled
+// in once the message is decoded
+type inflightRequest struct {
+	cancel  context.CancelCauseFunc
+	started time.Time
+	method  string
+	tool    string
+}
+
+// Request describes an in-flight request of a session
+type Request struct {
+	// ID is the JSON-RPC id as sent by the client, e.g. 3 or "abc"
+	ID        json.RawMessage `json:"id"`
+	Method    string          `json:"method,omitempty"`
+	Tool      string          `json:"tool,omitempty"`
+	StartedAt time.Time       `json:"started_at"`
+}
+
+// errRequestCancelled is the cancellation cause of requests cancelled by the client
+var errRequestCancelled = errors.New("request cancelled by client")
+
+// ErrRequestCancelled is the cancellation cause of requests cancelled with
+// Session.CancelRequest. Unlike requests the client cancels, they are
+// answered with an error.
+var ErrRequestCancelled = errors.New("request cancelled by the server")
+
+// ErrSessionClosed is the cancellation cause of the requests of a session
+// closed with Server.CloseSession
+var ErrSessionClosed = errors.New("session closed by the server")
+
+// newSession creates a session for conn, which may be nil for one-off
+// requests. ctx is the context the connection is served with.
+func newSession(ctx context.Context, conn Connection) *Session {
+	id := ""
+	if c, ok := conn.(sessionIDer); ok {
+		id = c.SessionID()
+	}
+	if id == "" {
+		id, _ = newSessionID()
+	}
+
+	now := time.Now()
+	s := &Session{
+		id:        id,
+		conn:      conn,
+		ctx:       ctx,
+		createdAt: now,
+		requests:  make(map[string]*inflightRequest),
+	}
+	s.lastActivity.Store(now.UnixNano())
+	return s
+}
+
+// ID returns the session id
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Context returns the context the session's connection is served with,
+// which carries e.g. the caller's identity
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// CreatedAt returns when the session was opened
+func (s *Session) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// LastActivity returns when the client last sent a message
+func (s *Session) LastActivity() time.Time {
+	return time.Unix(0, s.lastActivity.Load())
+}
+
+// Initialized reports whether the client has completed the initialize handshake
+func (s *Session) Initialized() bool {
+	return s.initialized.Load()
+}
+
+// ClientInfo returns the client information sent in initialize
+func (s *Session) ClientInfo() ClientInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clientInfo
+}
+
+// setClientInfo records the client information sent in initialize
+func (s *Session) setClientInfo(info ClientInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientInfo = info
+}
+
+// touch records client activity
+func (s *Session) touch() {
+	s.lastActivity.Store(time.Now().UnixNano())
+}
+
+type requestKeyContextKey struct{}
+
+// beginRequest tracks an in-flight request so it can be cancelled by id.
+// The returned function must be called once the request is done.
+func (s *Session) beginRequest(ctx context.Context, id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	ctx = context.WithValue(ctx, requestKeyContextKey{}, id)
+
+	s.requestsMu.Lock()
+	s.requests[id] = &inflightRequest{cancel: cancel, started: time.Now()}
+	s.requestsMu.Unlock()
+
+	return ctx, func() {
+		s.requestsMu.Lock()
+		delete(s.requests, id)
+		s.requestsMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// describeRequest records the method and, for tools/call, the tool of the
+// in-flight request ctx belongs to; empty values are left unchanged
+func (s *Session) describeRequest(ctx context.Context, method, tool string) {
+	id, ok := ctx.Value(requestKeyContextKey{}).(string)
+	if !ok {
+		return
+	}
+
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	if r, ok := s.requests[id]; ok {
+		if method != "" {
+			r.method = method
+		}
+		if tool != "" {
+			r.tool = tool
+		}
+	}
+}
+
+// Requests returns the session's in-flight requests, oldest first
+func (s *Session) Requests() []Request {
+	s.requestsMu.Lock()
+	requests := make([]Request, 0, len(s.requests))
+	for id, r := range s.requests {
+		requests = append(requests, Request{
+			ID:        json.RawMessage(id),
+			Method:    r.method,
+			Tool:      r.tool,
+			StartedAt: r.started,
+		})
+	}
+	s.requestsMu.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool { return requests[i].StartedAt.Before(requests[j].StartedAt) })
+	return requests
+}
+
+// CancelRequest cancels an in-flight request by its JSON-RPC id, reporting
+// whether it was found. The client gets an error response.
+func (s *Session) CancelRequest(id string) bool {
+	return s.cancelRequestWith(id, ErrRequestCancelled)
+}
+
+// cancelRequest cancels an in-flight request the client cancelled,
+// reporting whether it was found
+func (s *Session) cancelRequest(id string) bool {
+	return s.cancelRequestWith(id, errRequestCancelled)
+}
+
+// cancelRequestWith cancels an in-flight request with cause
+func (s *Session) cancelRequestWith(id string, cause error) bool {
+	s.requestsMu.Lock()
+	r, ok := s.requests[id]
+	s.requestsMu.Unlock()
+
+	if ok {
+		r.cancel(cause)
+	}
+	return ok
+}
+
+// cancelAllRequests cancels every in-flight request with cause
+func (s *Session) cancelAllRequests(cause error) {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	for _, r := range s.requests {
+		r.cancel(cause)
+	}
+}
+
+// Notify sends a JSON-RPC notification to the client
+func (s *Session) Notify(ctx context.Context, method string, params interface{}) error {
+	if s.conn == nil {
+		return fmt.Errorf("session %s has no connection", s.id)
+	}
+
+	data, err := json.Marshal(JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	return s.conn.Write(ctx, data)
+}
+
+// Close closes the session's connection
+func (s *Session) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+type sessionContextKey struct{}
+
+// contextWithSession returns a context carrying the session
+func contextWithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the session handling the current request, if any
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return session
+}
+
+type progressTokenContextKey struct{}
+
+// contextWithProgressToken returns a context carrying the request's progress token
+func contextWithProgressToken(ctx context.Context, token interface{}) context.Context {
+	return context.WithValue(ctx, progressTokenContextKey{}, token)
+}
+
+// ProgressTokenFromContext returns the progress token the client attached to
+// the current request, or nil if it didn't ask for progress
+func ProgressTokenFromContext(ctx context.Context) interface{} {
+	return ctx.Value(progressTokenContextKey{})
+}
+
+// SendProgress reports progress on the current request to the client. It is
+// a no-op when the client didn't ask for progress; total may be 0 if unknown.
+func SendProgress(ctx context.Context, progress, total float64, message string) error {
+	token := ProgressTokenFromContext(ctx)
+	session := SessionFromContext(ctx)
+	if token == nil || session == nil {
+		return nil
+	}
+
+	return session.Notify(ctx, "notifications/progress", ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
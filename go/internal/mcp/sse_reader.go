@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEEvent is a single event read from a text/event-stream
+type SSEEvent struct {
+	// Event is the event type; it defaults to "message"
+	Event string
+	// Data is the event payload, with multiple data lines joined by "\n"
+	Data string
+	// ID is the last event id seen on the stream
+	ID string
+	// Retry is the reconnection delay requested by the server, if any
+	Retry time.Duration
+}
+
+// SSEReader parses events from a text/event-stream as specified by the
+// HTML living standard. It accepts LF, CRLF and CR line endings and
+// ignores comment lines.
+type SSEReader struct {
+	reader *bufio.Reader
+	lastID string
+	retry  time.Duration
+}
+
+// NewSSEReader creates an SSE parser reading from r
+func NewSSEReader(r io.Reader) *SSEReader {
+	return &SSEReader{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next event. It returns io.EOF when the stream ends;
+// an incomplete event at the end of the stream is discarded.
+func (r *SSEReader) Next() (SSEEvent, error) {
+	var data bytes.Buffer
+	var eventType string
+	hasData := false
+
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return SSEEvent{}, err
+		}
+
+		// A blank line dispatches the event
+		if line == "" {
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+			return SSEEvent{
+				Event: eventType,
+				Data:  data.String(),
+				ID:    r.lastID,
+				Retry: r.retry,
+			}, nil
+		}
+
+		// Comment
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				r.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				r.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// LastEventID returns the id of the last event seen, for Last-Event-ID on reconnect
+func (r *SSEReader) LastEventID() string {
+	return r.lastID
+}
+
+// readLine reads one line terminated by LF, CRLF or CR
+func (r *SSEReader) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := r.reader.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				// An unterminated final line can't complete an event
+				return "", io.EOF
+			}
+			return "", err
+		}
+
+		switch b {
+		case '\n':
+			return string(line), nil
+		case '\r':
+			if next, err := r.reader.Peek(1); err == nil && next[0] == '\n' {
+				r.reader.Discard(1)
+			}
+			return string(line), nil
+		default:
+			line = append(line, b)
+		}
+	}
+}
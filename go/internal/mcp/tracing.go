@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans of this package
+const tracerName = "github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+
+// tracer returns the tracer of the global provider, so an embedding
+// application configures tracing with otel.SetTracerProvider
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName, trace.WithInstrumentationVersion(ServerVersion))
+}
+
+// contextReader is implemented by connections whose messages arrive with a
+// context of their own, such as SSE sessions whose messages are posted over
+// HTTP. The trace context and log attributes of that context apply to the
+// message; its cancellation does not.
+type contextReader interface {
+	ReadContext(ctx context.Context) ([]byte, context.Context, error)
+}
+
+// readMessage reads the next message from conn and returns msgCtx, ctx
+// with the trace context and log attributes the message came with
+func readMessage(ctx context.Context, conn Connection) (data []byte, msgCtx context.Context, err error) {
+	r, ok := conn.(contextReader)
+	if !ok {
+		data, err = conn.Read(ctx)
+		return data, ctx, err
+	}
+
+	data, posted, err := r.ReadContext(ctx)
+	if err != nil || posted == nil {
+		return data, ctx, err
+	}
+	msgCtx = logging.WithAttrs(ctx, logging.Attrs(posted)...)
+	if parent := trace.SpanContextFromContext(posted); parent.IsValid() {
+		msgCtx = trace.ContextWithRemoteSpanContext(msgCtx, parent)
+	}
+	return data, msgCtx, nil
+}
+
+// extractHeaders returns the request's context with the W3C trace context
+// of its headers
+func extractHeaders(r *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+}
+
+// extractMeta returns ctx with the trace context carried in a request's
+// params._meta (traceparent, tracestate, baggage), if any. It takes
+// precedence over trace context from the transport.
+func extractMeta(ctx context.Context, params map[string]interface{}) context.Context {
+	meta, ok := params["_meta"].(map[string]interface{})
+	if !ok {
+		return ctx
+	}
+	carrier := propagation.MapCarrier{}
+	for key, value := range meta {
+		if s, ok := value.(string); ok {
+			carrier[key] = s
+		}
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// startRequestSpan starts the server span of a JSON-RPC message
+func startRequestSpan(ctx context.Context, session *Session, req JSONRPCRequest) (context.Context, trace.Span) {
+	ctx = extractMeta(ctx, req.Params)
+
+	method := req.Method
+	if !knownMethods[method] {
+		method = "unknown"
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("mcp.method.name", method),
+	}
+	if session.id != "" {
+		attrs = append(attrs, attribute.String("mcp.session.id", session.id))
+	}
+	if req.ID != nil {
+		attrs = append(attrs, attribute.String("jsonrpc.request.id", fmt.Sprint(req.ID)))
+	}
+	return tracer().Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// endRequestSpan records the outcome of a message on its span and ends it
+func endRequestSpan(span trace.Span, request *requestInfo) {
+	if request.tool != "" {
+		span.SetName("tools/call " + request.tool)
+		span.SetAttributes(attribute.String("gen_ai.tool.name", request.tool))
+	}
+	if request.failed {
+		span.SetAttributes(attribute.Int("rpc.jsonrpc.error_code", request.errorCode))
+		span.SetStatus(codes.Error, request.errorMessage)
+	}
+	if request.errorKind != "" {
+		span.SetAttributes(attribute.String("error.type", request.errorKind))
+	}
+	span.End()
+}
+
+// traceToolCall wraps a tool handler in a span. Handlers receive its
+// context, so spans they start are its children.
+func traceToolCall(next ToolCallFunc) ToolCallFunc {
+	return func(ctx context.Context, tool Tool, args map[string]interface{}) (interface{}, error) {
+		ctx, span := tracer().Start(ctx, "execute_tool "+tool.Name,
+			trace.WithSpanKind(trace.SpanKindInternal),
+			trace.WithAttributes(attribute.String("gen_ai.tool.name", tool.Name)))
+		defer span.End()
+
+		result, err := next(ctx, tool, args)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else if r, ok := result.(*CallToolResult); ok && r.IsError {
+			span.SetStatus(codes.Error, "tool returned an error result")
+		}
+		return result, err
+	}
+}
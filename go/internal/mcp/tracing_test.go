@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a global tracer provider that keeps ended spans in
+// memory, and restores the previous provider when the test ends
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(propagator)
+	})
+	return exporter
+}
+
+func TestRequestSpans(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name    string
+		request string
+		// wantSpans are the span names in the order they end
+		wantSpans []string
+		wantError bool
+		// wantTraceID is the trace the spans join; empty means a new trace
+		wantTraceID string
+	}{
+		{
+			name:      "tool call",
+			request:   `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`,
+			wantSpans: []string{"execute_tool echo", "tools/call echo"},
+		},
+		{
+			name:        "trace context in _meta",
+			request:     `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"_meta":{"traceparent":"00-` + traceID + `-00f067aa0ba902b7-01"}}}`,
+			wantSpans:   []string{"ping"},
+			wantTraceID: traceID,
+		},
+		{
+			name:      "unknown method",
+			request:   `{"jsonrpc":"2.0","id":1,"method":"nope"}`,
+			wantSpans: []string{"unknown"},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := recordSpans(t)
+			if _, err := newTestServer().HandleRequest([]byte(tt.request)); err != nil {
+				t.Fatal(err)
+			}
+
+			spans := exporter.GetSpans()
+			if len(spans) != len(tt.wantSpans) {
+				t.Fatalf("%d spans, want %d", len(spans), len(tt.wantSpans))
+			}
+			for i, name := range tt.wantSpans {
+				if spans[i].Name != name {
+					t.Errorf("span %d = %q, want %q", i, spans[i].Name, name)
+				}
+			}
+
+			// The last span to end is the request's; tool spans are its children
+			request := spans[len(spans)-1]
+			for _, span := range spans[:len(spans)-1] {
+				if span.Parent.SpanID() != request.SpanContext.SpanID() {
+					t.Errorf("span %q is not a child of %q", span.Name, request.Name)
+				}
+			}
+			if got := request.Status.Code == codes.Error; got != tt.wantError {
+				t.Errorf("request span error = %v, want %v", got, tt.wantError)
+			}
+			if tt.wantTraceID != "" {
+				if got := request.SpanContext.TraceID().String(); got != tt.wantTraceID {
+					t.Errorf("trace ID = %s, want %s", got, tt.wantTraceID)
+				}
+				if !request.Parent.IsRemote() {
+					t.Error("request span parent is not the remote caller")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTransportClosed is returned by Transport.Accept once no more connections will arrive
+var ErrTransportClosed = errors.New("transport closed")
+
+// ErrConnectionClosed is returned when reading from or writing to a closed connection
+var ErrConnectionClosed = errors.New("connection closed")
+
+// Connection is a bidirectional stream of JSON-RPC messages between a
+// server and a single peer. Write must be safe for concurrent use.
+type Connection interface {
+	// Read returns the next message from the peer. It returns io.EOF or
+	// ErrConnectionClosed when the peer is gone.
+	Read(ctx context.Context) ([]byte, error)
+	// Write sends a message to the peer
+	Write(ctx context.Context, data []byte) error
+	// Close closes the connection and unblocks pending reads
+	Close() error
+}
+
+// Transport yields connections for a server to serve
+type Transport interface {
+	// Accept blocks until a new connection is available. It returns
+	// ErrTransportClosed once the transport will yield no more connections.
+	Accept(ctx context.Context) (Connection, error)
+	// Close stops accepting connections
+	Close() error
+}
+
+// ConnectionHandler serves a single connection until it ends. Server and
+// Proxy both implement it, so the HTTP transports can front either.
+type ConnectionHandler interface {
+	ServeConnection(ctx context.Context, conn Connection) error
+}
+
+// ServeTransport accepts connections from the transport and serves each of
+// them with handler until the transport is exhausted or ctx is cancelled.
+// It waits for all connections to finish before returning.
+func ServeTransport(ctx context.Context, transport Transport, handler ConnectionHandler) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := transport.Accept(ctx)
+		if err != nil {
+			if errors.Is(err, ErrTransportClosed) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := handler.ServeConnection(ctx, conn); err != nil {
+				logger.Warn("Connection error", "error", err)
+			}
+		}()
+	}
+}
+
+// sessionIDer is implemented by connections that already carry a session id,
+// such as SSE connections, so the MCP session reuses it
+type sessionIDer interface {
+	SessionID() string
+}
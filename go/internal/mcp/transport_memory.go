@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+)
+
+// InMemoryTransport is a Transport whose connections are created in-process.
+// It lets an application embed the server, and tests drive it, without any I/O.
+type InMemoryTransport struct {
+	pending   chan Connection
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewInMemoryTransport creates a new in-memory transport
+func NewInMemoryTransport() *InMemoryTransport {
+	return &InMemoryTransport{
+		pending: make(chan Connection),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Connect creates a connected pair of connections, hands the server end to
+// Accept and returns the client end
+func (t *InMemoryTransport) Connect(ctx context.Context) (Connection, error) {
+	client, server := NewInMemoryConnectionPair()
+
+	select {
+	case t.pending <- server:
+		return client, nil
+	case <-t.closed:
+		return nil, ErrTransportClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Accept waits for the next call to Connect
+func (t *InMemoryTransport) Accept(ctx context.Context) (Connection, error) {
+	select {
+	case conn := <-t.pending:
+		return conn, nil
+	case <-t.closed:
+		return nil, ErrTransportClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close stops accepting connections. Connections already accepted stay open.
+func (t *InMemoryTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+	return nil
+}
+
+// inMemoryConnection is one end of an in-memory connection pair
+type inMemoryConnection struct {
+	incoming chan []byte
+	outgoing chan []byte
+	pipe     *inMemoryPipe
+}
+
+// inMemoryPipe holds the state shared by both ends of a pair
+type inMemoryPipe struct {
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewInMemoryConnectionPair returns two connections wired to each other:
+// messages written to one are read from the other. Closing either end
+// closes both.
+func NewInMemoryConnectionPair() (Connection, Connection) {
+	pipe := &inMemoryPipe{done: make(chan struct{})}
+	aToB := make(chan []byte, 16)
+	bToA := make(chan []byte, 16)
+
+	a := &inMemoryConnection{incoming: bToA, outgoing: aToB, pipe: pipe}
+	b := &inMemoryConnection{incoming: aToB, outgoing: bToA, pipe: pipe}
+	return a, b
+}
+
+// Read returns the next message written by the other end
+func (c *inMemoryConnection) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-c.incoming:
+		return data, nil
+	case <-c.pipe.done:
+		return nil, ErrConnectionClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Write delivers a message to the other end
+func (c *inMemoryConnection) Write(ctx context.Context, data []byte) error {
+	// Copy so the caller may reuse its buffer
+	msg := append([]byte(nil), data...)
+
+	select {
+	case <-c.pipe.done:
+		return ErrConnectionClosed
+	default:
+	}
+
+	select {
+	case c.outgoing <- msg:
+		return nil
+	case <-c.pipe.done:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes both ends of the pair
+func (c *inMemoryConnection) Close() error {
+	c.pipe.closeOnce.Do(func() {
+		close(c.pipe.done)
+	})
+	return nil
+}
@@ -2,35 +2,98 @@ package mcp
 
 import (
 	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// SSEConnection represents a single SSE connection
+// SSEOptions configures keepalive, lifetime and message size limits for SSE
+// sessions. A zero value disables the corresponding behaviour.
+type SSEOptions struct {
+	// HeartbeatInterval is how often a ": ping" comment is written to keep
+	// proxies and load balancers from closing an idle stream
+	HeartbeatInterval time.Duration
+	// WriteTimeout bounds every write to the client so dead peers are detected
+	WriteTimeout time.Duration
+	// IdleTimeout closes sessions that have not posted a message for this long
+	IdleTimeout time.Duration
+	// MaxLifetime closes sessions after this long regardless of activity
+	MaxLifetime time.Duration
+	// MaxMessageSize is the largest message body accepted in bytes
+	MaxMessageSize int64
+	// AuthorizeMessage, if set, checks that a message posted to a session
+	// comes from the caller that opened its stream, given the contexts of
+	// the two HTTP requests
+	AuthorizeMessage func(stream, message context.Context) bool
+}
+
+// DefaultSSEOptions returns the SSE options used when none are configured
+func DefaultSSEOptions() SSEOptions {
+	return SSEOptions{
+		HeartbeatInterval: 15 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       30 * time.Minute,
+		MaxLifetime:       24 * time.Hour,
+		MaxMessageSize:    4 << 20,
+	}
+}
+
+// SSEConnection represents a single SSE connection. It implements
+// Connection: messages posted to the message endpoint are read from it
+// and responses are written to the event stream.
 type SSEConnection struct {
-	server   *Server
-	writer   http.ResponseWriter
-	flusher  http.Flusher
-	done     chan struct{}
-	writeMux sync.Mutex
+	id           string
+	streamCtx    context.Context
+	writer       http.ResponseWriter
+	flusher      http.Flusher
+	controller   *http.ResponseController
+	options      SSEOptions
+	createdAt    time.Time
+	lastActivity atomic.Int64
+	incoming     chan postedMessage
+	done         chan struct{}
+	closeOnce    sync.Once
+	closed       bool
+	writeMux     sync.Mutex
 }
 
 // NewSSEConnection creates a new SSE connection
-func NewSSEConnection(server *Server, w http.ResponseWriter) (*SSEConnection, error) {
+func NewSSEConnection(w http.ResponseWriter, options SSEOptions) (*SSEConnection, error) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		return nil, fmt.Errorf("streaming unsupported")
 	}
 
-	return &SSEConnection{
-		server:  server,
-		writer:  w,
-		flusher: flusher,
-		done:    make(chan struct{}),
-	}, nil
+	id, err := newSessionID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	now := time.Now()
+	c := &SSEConnection{
+		id:         id,
+		writer:     w,
+		flusher:    flusher,
+		controller: http.NewResponseController(w),
+		options:    options,
+		createdAt:  now,
+		incoming:   make(chan postedMessage),
+		done:       make(chan struct{}),
+	}
+	c.lastActivity.Store(now.UnixNano())
+	return c, nil
+}
+
+// SessionID returns the session id so the MCP session shares it
+func (c *SSEConnection) SessionID() string {
+	return c.id
 }
 
 // Start initializes the SSE connection
@@ -41,53 +104,130 @@ func (c *SSEConnection) Start() {
 	c.writer.Header().Set("Connection", "keep-alive")
 	c.writer.Header().Set("X-Accel-Buffering", "no")
 
+	// Without a per-write deadline the stream must not inherit the HTTP
+	// server's WriteTimeout, which would cut it off mid-session
+	if c.options.WriteTimeout <= 0 {
+		c.controller.SetWriteDeadline(time.Time{})
+	}
+
 	c.flusher.Flush()
 }
 
-// HandleMessage processes an incoming message from the client
-func (c *SSEConnection) HandleMessage(data []byte) error {
-	response, err := c.server.HandleRequest(data)
-	if err != nil {
-		log.Printf("Error handling request: %v", err)
-		return err
-	}
+// postedMessage is a message posted to the message endpoint, with the
+// context of the POST request
+type postedMessage struct {
+	data []byte
+	ctx  context.Context
+}
 
-	// Skip if no response (notification)
-	if response == nil {
+// HandleMessage queues a message posted by the client for the server to
+// read. Trace context in ctx becomes the parent of the message's span, and
+// its log attributes, such as the request id, are logged with the message.
+func (c *SSEConnection) HandleMessage(ctx context.Context, data []byte) error {
+	c.touch()
+
+	select {
+	case c.incoming <- postedMessage{data: data, ctx: context.WithoutCancel(ctx)}:
 		return nil
+	case <-c.done:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Read returns the next message posted by the client
+func (c *SSEConnection) Read(ctx context.Context) ([]byte, error) {
+	data, _, err := c.ReadContext(ctx)
+	return data, err
+}
+
+// ReadContext returns the next message posted by the client and the
+// context of the request that posted it, without its cancellation
+func (c *SSEConnection) ReadContext(ctx context.Context) ([]byte, context.Context, error) {
+	select {
+	case msg := <-c.incoming:
+		return msg.data, msg.ctx, nil
+	case <-c.done:
+		return nil, nil, ErrConnectionClosed
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
 	}
+}
 
-	// Send response as SSE event
-	return c.SendEvent("message", string(response))
+// Write sends a JSON-RPC message to the client as a "message" event
+func (c *SSEConnection) Write(ctx context.Context, data []byte) error {
+	return c.SendEvent("message", string(data))
 }
 
 // SendEvent sends an SSE event to the client
 func (c *SSEConnection) SendEvent(event, data string) error {
+	return c.write(func(w io.Writer) error {
+		if event != "" {
+			if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "data: %s\n\n", data)
+		return err
+	})
+}
+
+// SendComment sends an SSE comment line, which clients ignore
+func (c *SSEConnection) SendComment(comment string) error {
+	return c.write(func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, ": %s\n\n", comment)
+		return err
+	})
+}
+
+// SendEndpoint sends the endpoint event to establish the message endpoint
+func (c *SSEConnection) SendEndpoint(endpoint string) error {
+	return c.SendEvent("endpoint", endpoint)
+}
+
+// write runs fn against the response writer under the write deadline and flushes
+func (c *SSEConnection) write(fn func(w io.Writer) error) error {
 	c.writeMux.Lock()
 	defer c.writeMux.Unlock()
 
-	if event != "" {
-		if _, err := fmt.Fprintf(c.writer, "event: %s\n", event); err != nil {
+	if c.closed {
+		return ErrConnectionClosed
+	}
+
+	if c.options.WriteTimeout > 0 {
+		err := c.controller.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
 			return err
 		}
 	}
 
-	if _, err := fmt.Fprintf(c.writer, "data: %s\n\n", data); err != nil {
+	if err := fn(c.writer); err != nil {
 		return err
 	}
 
-	c.flusher.Flush()
-	return nil
+	return c.controller.Flush()
 }
 
-// SendEndpoint sends the endpoint event to establish the message endpoint
-func (c *SSEConnection) SendEndpoint(endpoint string) error {
-	return c.SendEvent("endpoint", endpoint)
+// touch records client activity for idle timeout purposes
+func (c *SSEConnection) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// LastActivity returns the time the client last sent a message
+func (c *SSEConnection) LastActivity() time.Time {
+	return time.Unix(0, c.lastActivity.Load())
 }
 
 // Close closes the SSE connection
-func (c *SSEConnection) Close() {
-	close(c.done)
+func (c *SSEConnection) Close() error {
+	c.closeOnce.Do(func() {
+		c.writeMux.Lock()
+		c.closed = true
+		c.writeMux.Unlock()
+		close(c.done)
+	})
+	return nil
 }
 
 // Done returns a channel that's closed when the connection is done
@@ -95,41 +235,191 @@ func (c *SSEConnection) Done() <-chan struct{} {
 	return c.done
 }
 
-// HandleSSERequest handles an SSE connection request
-func HandleSSERequest(server *Server, w http.ResponseWriter, r *http.Request, messageEndpoint string) error {
-	conn, err := NewSSEConnection(server, w)
+// keepAlive writes heartbeats until the connection ends, the client goes
+// away, or an idle or lifetime limit is reached
+func (c *SSEConnection) keepAlive(clientGone <-chan struct{}) string {
+	var heartbeat, idle, lifetime <-chan time.Time
+
+	if c.options.HeartbeatInterval > 0 {
+		ticker := time.NewTicker(c.options.HeartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	var idleTimer *time.Timer
+	if c.options.IdleTimeout > 0 {
+		idleTimer = time.NewTimer(c.options.IdleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+
+	if c.options.MaxLifetime > 0 {
+		timer := time.NewTimer(c.options.MaxLifetime)
+		defer timer.Stop()
+		lifetime = timer.C
+	}
+
+	for {
+		select {
+		case <-clientGone:
+			return "client disconnected"
+		case <-c.done:
+			return "closed by server"
+		case <-heartbeat:
+			if err := c.SendComment("ping"); err != nil {
+				return fmt.Sprintf("heartbeat failed: %v", err)
+			}
+		case <-idle:
+			remaining := c.options.IdleTimeout - time.Since(c.LastActivity())
+			if remaining <= 0 {
+				return "idle timeout"
+			}
+			idleTimer.Reset(remaining)
+		case <-lifetime:
+			return "max lifetime reached"
+		}
+	}
+}
+
+// SSEHandler serves SSE sessions and routes posted messages to them
+type SSEHandler struct {
+	handler  ConnectionHandler
+	options  SSEOptions
+	mu       sync.RWMutex
+	sessions map[string]*SSEConnection
+}
+
+// NewSSEHandler creates a new SSE handler that serves each session with
+// handler, usually a *Server
+func NewSSEHandler(handler ConnectionHandler, options SSEOptions) *SSEHandler {
+	return &SSEHandler{
+		handler:  handler,
+		options:  options,
+		sessions: make(map[string]*SSEConnection),
+	}
+}
+
+// SessionCount returns the number of open SSE sessions
+func (h *SSEHandler) SessionCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.sessions)
+}
+
+// HandleSSE handles an SSE connection request. It blocks until the session ends.
+func (h *SSEHandler) HandleSSE(w http.ResponseWriter, r *http.Request, messageEndpoint string) error {
+	conn, err := NewSSEConnection(w, h.options)
 	if err != nil {
 		return err
 	}
+	conn.streamCtx = r.Context()
+
+	h.mu.Lock()
+	h.sessions[conn.id] = conn
+	h.mu.Unlock()
+
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, conn.id)
+		h.mu.Unlock()
+		conn.Close()
+	}()
 
 	conn.Start()
 
 	// Send the message endpoint
-	if err := conn.SendEndpoint(messageEndpoint); err != nil {
+	if err := conn.SendEndpoint(fmt.Sprintf("%s?sessionId=%s", messageEndpoint, conn.id)); err != nil {
 		return err
 	}
 
-	log.Println("SSE connection established")
+	logger.InfoContext(r.Context(), "SSE connection established", "session_id", conn.id)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	served := make(chan error, 1)
+	go func() {
+		served <- h.handler.ServeConnection(ctx, conn)
+	}()
+
+	reason := conn.keepAlive(r.Context().Done())
+	logger.InfoContext(r.Context(), "SSE connection closed", "session_id", conn.id, "reason", reason)
+
+	cancel()
+	conn.Close()
+	return <-served
+}
+
+// HandleMessage handles a POST request to the message endpoint. Requests
+// carrying a sessionId are answered over that session's event stream;
+// requests without one are answered in the response body when the handler
+// is a *Server.
+func (h *SSEHandler) HandleMessage(w http.ResponseWriter, r *http.Request) error {
+	if h.options.MaxMessageSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.options.MaxMessageSize)
+	}
+
+	sessionID := r.URL.Query().Get("sessionId")
+	if sessionID == "" {
+		server, ok := h.handler.(*Server)
+		if !ok {
+			http.Error(w, "sessionId is required", http.StatusBadRequest)
+			return fmt.Errorf("message without sessionId")
+		}
+		return HandleMessagePost(server, w, r)
+	}
+
+	h.mu.RLock()
+	conn, exists := h.sessions[sessionID]
+	h.mu.RUnlock()
+	if !exists {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	if h.options.AuthorizeMessage != nil && !h.options.AuthorizeMessage(conn.streamCtx, r.Context()) {
+		http.Error(w, "Session belongs to another caller", http.StatusForbidden)
+		return fmt.Errorf("message from another caller for session %s", sessionID)
+	}
+
+	body, err := readBody(w, r)
+	if err != nil {
+		return err
+	}
 
-	// Keep connection alive
-	<-r.Context().Done()
-	log.Println("SSE connection closed")
+	if err := conn.HandleMessage(extractHeaders(r), body); err != nil {
+		http.Error(w, "Session closed", http.StatusGone)
+		return err
+	}
 
+	w.WriteHeader(http.StatusAccepted)
 	return nil
 }
 
+// readBody reads a message body, answering with an error if it can't
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Message too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
 // HandleMessagePost handles a POST request to the message endpoint
 func HandleMessagePost(server *Server, w http.ResponseWriter, r *http.Request) error {
 	// Read the request body
-	body, err := io.ReadAll(r.Body)
+	body, err := readBody(w, r)
 	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return err
 	}
-	defer r.Body.Close()
 
 	// Handle the request
-	response, err := server.HandleRequest(body)
+	response, err := server.HandleRequestContext(extractHeaders(r), body)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return err
@@ -151,45 +441,36 @@ func HandleMessagePost(server *Server, w http.ResponseWriter, r *http.Request) e
 	return nil
 }
 
-// ReadSSEMessages reads messages from an SSE stream (client-side)
+// newSessionID returns a random hex session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ReadSSEMessages reads the data of each event from an SSE stream (client-side)
 func ReadSSEMessages(reader *bufio.Reader) (chan string, chan error) {
 	messages := make(chan string)
-	errors := make(chan error)
+	errs := make(chan error, 1)
 
 	go func() {
 		defer close(messages)
-		defer close(errors)
-
-		var currentData string
+		defer close(errs)
 
+		events := NewSSEReader(reader)
 		for {
-			line, err := reader.ReadString('\n')
+			event, err := events.Next()
 			if err != nil {
 				if err != io.EOF {
-					errors <- err
+					errs <- err
 				}
 				return
 			}
-
-			line = line[:len(line)-1] // Remove trailing \n
-
-			if line == "" {
-				// Empty line means end of event
-				if currentData != "" {
-					messages <- currentData
-					currentData = ""
-				}
-				continue
-			}
-
-			if len(line) > 5 && line[:5] == "data:" {
-				if currentData != "" {
-					currentData += "\n"
-				}
-				currentData += line[6:]
-			}
+			messages <- event.Data
 		}
 	}()
 
-	return messages, errors
+	return messages, errs
 }
@@ -0,0 +1,245 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// callerKey holds the X-Caller header of a test request in its context
+type callerKey struct{}
+
+// newSSETestServer serves handler's /sse and /message endpoints
+func newSSETestServer(t *testing.T, handler *SSEHandler) *httptest.Server {
+	t.Helper()
+	withCaller := func(r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), callerKey{}, r.Header.Get("X-Caller")))
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		handler.HandleSSE(w, withCaller(r), "/message")
+	})
+	mux.HandleFunc("/message", func(w http.ResponseWriter, r *http.Request) {
+		handler.HandleMessage(w, withCaller(r))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// sseStream is an open event stream read one event at a time
+type sseStream struct {
+	body     io.ReadCloser
+	reader   *bufio.Reader
+	endpoint string
+}
+
+// openSSEStream connects to srv's /sse and reads the endpoint event
+func openSSEStream(t *testing.T, ctx context.Context, srv *httptest.Server) *sseStream {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/sse", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	s := &sseStream{body: resp.Body, reader: bufio.NewReader(resp.Body)}
+	event, err := s.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint, ok := strings.CutPrefix(event, "event: endpoint\ndata: ")
+	if !ok {
+		t.Fatalf("first event = %q, want the endpoint", event)
+	}
+	s.endpoint = srv.URL + endpoint
+	return s
+}
+
+// next returns the next event or comment, without its trailing blank line
+func (s *sseStream) next() (string, error) {
+	var lines []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// post sends body to the stream's message endpoint as caller and returns
+// the status
+func (s *sseStream) post(t *testing.T, ctx context.Context, caller, body string) int {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Caller", caller)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSSEHandleMessageSize(t *testing.T) {
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"within the limit", ping, http.StatusAccepted},
+		{"too large", `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"padding":"` + strings.Repeat("x", 100) + `"}}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultSSEOptions()
+			options.MaxMessageSize = int64(len(ping))
+			srv := newSSETestServer(t, NewSSEHandler(newTestServer(), options))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stream := openSSEStream(t, ctx, srv)
+
+			if got := stream.post(t, ctx, "", tt.body); got != tt.want {
+				t.Errorf("POST status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// Stateless messages without a session are limited too
+	options := DefaultSSEOptions()
+	options.MaxMessageSize = 16
+	srv := newSSETestServer(t, NewSSEHandler(newTestServer(), options))
+	resp, err := http.Post(srv.URL+"/message", "application/json", strings.NewReader(ping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("stateless POST status = %d, want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestSSEKeepAlive(t *testing.T) {
+	tests := []struct {
+		name    string
+		options SSEOptions
+		// wantPing is whether a heartbeat arrives before the stream ends
+		wantPing bool
+		// wantClosedAfter is the least time before the server closes the
+		// stream; 0 means it stays open
+		wantClosedAfter time.Duration
+	}{
+		{"heartbeat", SSEOptions{HeartbeatInterval: 10 * time.Millisecond}, true, 0},
+		// Heartbeats are not client activity
+		{"idle timeout", SSEOptions{HeartbeatInterval: 10 * time.Millisecond, IdleTimeout: 100 * time.Millisecond}, true, 100 * time.Millisecond},
+		{"max lifetime", SSEOptions{MaxLifetime: 100 * time.Millisecond}, false, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSSETestServer(t, NewSSEHandler(newTestServer(), tt.options))
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			stream := openSSEStream(t, ctx, srv)
+			pinged := false
+			for {
+				event, err := stream.next()
+				if err != nil {
+					if ctx.Err() != nil {
+						t.Fatal("stream still open")
+					}
+					if tt.wantClosedAfter == 0 {
+						t.Fatalf("stream closed: %v", err)
+					}
+					if elapsed := time.Since(start); elapsed < tt.wantClosedAfter {
+						t.Errorf("stream closed after %v, want at least %v", elapsed, tt.wantClosedAfter)
+					}
+					break
+				}
+				if event != ": ping" {
+					t.Fatalf("event = %q, want a heartbeat", event)
+				}
+				pinged = true
+				if tt.wantClosedAfter == 0 {
+					break
+				}
+			}
+			if pinged != tt.wantPing {
+				t.Errorf("heartbeat received = %v, want %v", pinged, tt.wantPing)
+			}
+		})
+	}
+}
+
+func TestSSEIdleTimeoutReset(t *testing.T) {
+	idle := 200 * time.Millisecond
+	srv := newSSETestServer(t, NewSSEHandler(newTestServer(), SSEOptions{IdleTimeout: idle}))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream := openSSEStream(t, ctx, srv)
+
+	// Messages posted within the idle timeout keep the session open well
+	// past it
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		time.Sleep(idle / 4)
+		if got := stream.post(t, ctx, "", `{"jsonrpc":"2.0","id":1,"method":"ping"}`); got != http.StatusAccepted {
+			t.Fatalf("POST %d status = %d after %v", i, got, time.Since(start))
+		}
+		if _, err := stream.next(); err != nil {
+			t.Fatalf("stream closed after %v: %v", time.Since(start), err)
+		}
+	}
+
+	if _, err := stream.next(); err == nil || ctx.Err() != nil {
+		t.Errorf("stream still open once idle, error = %v", err)
+	}
+}
+
+func TestSSEAuthorizeMessage(t *testing.T) {
+	options := DefaultSSEOptions()
+	options.AuthorizeMessage = func(stream, message context.Context) bool {
+		return stream.Value(callerKey{}) == message.Value(callerKey{})
+	}
+	srv := newSSETestServer(t, NewSSEHandler(newTestServer(), options))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The stream is opened by the caller without an X-Caller header
+	stream := openSSEStream(t, ctx, srv)
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+	if got := stream.post(t, ctx, "mallory", ping); got != http.StatusForbidden {
+		t.Errorf("POST from another caller status = %d, want %d", got, http.StatusForbidden)
+	}
+	if got := stream.post(t, ctx, "", ping); got != http.StatusAccepted {
+		t.Errorf("POST from the stream's caller status = %d, want %d", got, http.StatusAccepted)
+	}
+	event, err := stream.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(event, "event: message\n") {
+		t.Errorf("event = %q, want the ping response", event)
+	}
+}
@@ -1,67 +1,189 @@
 package mcp
 
 import (
-	"bufio"
-	"fmt"
+	"context"
 	"io"
-	"log"
 	"os"
+	"reflect"
+	"sync"
 )
 
-// StdioTransport handles stdio-based transport for MCP
-type StdioTransport struct {
-	server *Server
-	reader *bufio.Reader
-	writer io.Writer
+// StdioOption configures a stream transport
+type StdioOption func(*streamConfig)
+
+// streamConfig holds the framing settings of a stream connection
+type streamConfig struct {
+	framing        Framing
+	maxMessageSize int
 }
 
-// NewStdioTransport creates a new stdio transport
-func NewStdioTransport(server *Server) *StdioTransport {
-	return &StdioTransport{
-		server: server,
-		reader: bufio.NewReader(os.Stdin),
-		writer: os.Stdout,
+// WithFraming sets how messages are delimited. The default, FramingAuto,
+// detects newline or Content-Length framing from the first message.
+func WithFraming(framing Framing) StdioOption {
+	return func(c *streamConfig) {
+		c.framing = framing
 	}
 }
 
-// Start starts the stdio transport loop
-func (t *StdioTransport) Start() error {
-	log.Println("MCP Server (stdio) started")
-
-	for {
-		// Read line from stdin
-		line, err := t.reader.ReadBytes('\n')
-		if err != nil {
-			if err == io.EOF {
-				log.Println("EOF received, shutting down")
-				return nil
-			}
-			return fmt.Errorf("failed to read from stdin: %w", err)
-		}
+// WithMaxMessageSize sets the largest message accepted in bytes
+func WithMaxMessageSize(size int) StdioOption {
+	return func(c *streamConfig) {
+		c.maxMessageSize = size
+	}
+}
 
-		// Skip empty lines
-		if len(line) == 0 || (len(line) == 1 && line[0] == '\n') {
-			continue
-		}
+// StreamConnection is a Connection over a byte stream such as stdio or a socket
+type StreamConnection struct {
+	stream    *framedStream
+	closer    io.Closer
+	readMux   sync.Mutex
+	writeMux  sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
 
-		// Handle request
-		response, err := t.server.HandleRequest(line)
-		if err != nil {
-			log.Printf("Error handling request: %v", err)
-			continue
-		}
+// NewStreamConnection creates a connection reading messages from r and writing
+// them to w. If r or w implements io.Closer it is closed with the connection.
+func NewStreamConnection(r io.Reader, w io.Writer, options ...StdioOption) *StreamConnection {
+	config := streamConfig{
+		framing:        FramingAuto,
+		maxMessageSize: DefaultMaxMessageSize,
+	}
+	for _, option := range options {
+		option(&config)
+	}
 
-		// Skip if no response (notification)
-		if response == nil {
-			continue
-		}
+	var closers multiCloser
+	if c, ok := r.(io.Closer); ok {
+		closers = append(closers, c)
+	}
+	if c, ok := w.(io.Closer); ok && !sameValue(r, w) {
+		closers = append(closers, c)
+	}
 
-		// Write response to stdout
-		if _, err := t.writer.Write(response); err != nil {
-			return fmt.Errorf("failed to write response: %w", err)
+	return &StreamConnection{
+		stream: newFramedStream(r, w, config.framing, config.maxMessageSize),
+		closer: closers,
+		done:   make(chan struct{}),
+	}
+}
+
+// Read returns the next message from the stream
+func (c *StreamConnection) Read(ctx context.Context) ([]byte, error) {
+	c.readMux.Lock()
+	defer c.readMux.Unlock()
+
+	select {
+	case <-c.done:
+		return nil, ErrConnectionClosed
+	default:
+	}
+
+	data, err := c.stream.ReadMessage()
+	if err != nil {
+		select {
+		case <-c.done:
+			return nil, ErrConnectionClosed
+		default:
 		}
-		if _, err := t.writer.Write([]byte("\n")); err != nil {
-			return fmt.Errorf("failed to write newline: %w", err)
+	}
+	return data, err
+}
+
+// Write sends a message using the framing detected or configured for the stream
+func (c *StreamConnection) Write(ctx context.Context, data []byte) error {
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+
+	select {
+	case <-c.done:
+		return ErrConnectionClosed
+	default:
+	}
+
+	return c.stream.WriteMessage(data)
+}
+
+// Close closes the underlying reader and writer if they are closable
+func (c *StreamConnection) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.closer.Close()
+	})
+	return err
+}
+
+// sameValue reports whether a and b are the same comparable value, e.g. a
+// net.Conn passed as both reader and writer
+func sameValue(a, b interface{}) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
+
+// multiCloser closes several closers, returning the first error
+type multiCloser []io.Closer
+
+// Close closes all closers
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
 		}
 	}
+	return first
+}
+
+// StreamTransport is a Transport that yields exactly one connection over a
+// byte stream. Accept returns ErrTransportClosed once that connection is closed.
+type StreamTransport struct {
+	conn      *StreamConnection
+	accepted  chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewStreamTransport creates a transport serving one connection over r and w
+func NewStreamTransport(r io.Reader, w io.Writer, options ...StdioOption) *StreamTransport {
+	t := &StreamTransport{
+		conn:     NewStreamConnection(r, w, options...),
+		accepted: make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+	t.accepted <- struct{}{}
+	return t
+}
+
+// NewStdioTransport creates a transport serving one connection over stdin and stdout
+func NewStdioTransport(options ...StdioOption) *StreamTransport {
+	// Wrapped so that closing the connection leaves the process's stdio open
+	return NewStreamTransport(struct{ io.Reader }{os.Stdin}, struct{ io.Writer }{os.Stdout}, options...)
+}
+
+// Accept returns the stream connection on the first call. Later calls wait
+// until the connection or transport is closed.
+func (t *StreamTransport) Accept(ctx context.Context) (Connection, error) {
+	select {
+	case <-t.accepted:
+		return t.conn, nil
+	default:
+	}
+
+	select {
+	case <-t.conn.done:
+		return nil, ErrTransportClosed
+	case <-t.closed:
+		return nil, ErrTransportClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the transport and its connection
+func (t *StreamTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+	})
+	return t.conn.Close()
 }
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// ListenerTransport is a Transport serving stream connections accepted from
+// a net.Listener, with the same framing options as the stdio transport
+type ListenerTransport struct {
+	listener net.Listener
+	options  []StdioOption
+}
+
+// NewListenerTransport creates a transport accepting connections from ln
+func NewListenerTransport(ln net.Listener, options ...StdioOption) *ListenerTransport {
+	return &ListenerTransport{
+		listener: ln,
+		options:  options,
+	}
+}
+
+// Accept waits for the next connection on the listener
+func (t *ListenerTransport) Accept(ctx context.Context) (Connection, error) {
+	// Closing the listener is the only way to interrupt Accept
+	stop := context.AfterFunc(ctx, func() {
+		t.listener.Close()
+	})
+	defer stop()
+
+	conn, err := t.listener.Accept()
+	if err != nil {
+		if errors.Is(err, net.ErrClosed) {
+			return nil, ErrTransportClosed
+		}
+		return nil, err
+	}
+
+	return NewStreamConnection(conn, conn, t.options...), nil
+}
+
+// Close closes the listener
+func (t *ListenerTransport) Close() error {
+	return t.listener.Close()
+}
@@ -0,0 +1,211 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketSubprotocol is the subprotocol clients must offer to speak MCP over WebSocket
+const WebSocketSubprotocol = "mcp"
+
+// WebSocketOptions configures the WebSocket transport
+type WebSocketOptions struct {
+	// PingInterval is how often a ping frame is sent to the client
+	PingInterval time.Duration
+	// PongTimeout is how long to wait for any frame after a ping before
+	// the connection is considered dead
+	PongTimeout time.Duration
+	// WriteTimeout bounds every write to the client
+	WriteTimeout time.Duration
+	// MaxMessageSize is the largest message accepted from the client in bytes
+	MaxMessageSize int64
+	// EnableCompression negotiates permessage-deflate with the client
+	EnableCompression bool
+	// CheckOrigin validates the Origin header; nil uses the same-origin check
+	CheckOrigin func(r *http.Request) bool
+}
+
+// DefaultWebSocketOptions returns the WebSocket options used when none are configured
+func DefaultWebSocketOptions() WebSocketOptions {
+	return WebSocketOptions{
+		PingInterval:   30 * time.Second,
+		PongTimeout:    10 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		MaxMessageSize: 4 << 20,
+	}
+}
+
+// WebSocketHandler upgrades HTTP requests to MCP WebSocket connections
+type WebSocketHandler struct {
+	handler  ConnectionHandler
+	options  WebSocketOptions
+	upgrader websocket.Upgrader
+}
+
+// NewWebSocketHandler creates a new WebSocket handler that serves each
+// connection with handler, usually a *Server
+func NewWebSocketHandler(handler ConnectionHandler, options WebSocketOptions) *WebSocketHandler {
+	return &WebSocketHandler{
+		handler: handler,
+		options: options,
+		upgrader: websocket.Upgrader{
+			Subprotocols:      []string{WebSocketSubprotocol},
+			EnableCompression: options.EnableCompression,
+			CheckOrigin:       options.CheckOrigin,
+		},
+	}
+}
+
+// HandleWebSocket handles a WebSocket upgrade request. It blocks until the connection ends.
+func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) error {
+	if !offersSubprotocol(r, WebSocketSubprotocol) {
+		http.Error(w, fmt.Sprintf("WebSocket subprotocol %q is required", WebSocketSubprotocol), http.StatusBadRequest)
+		return fmt.Errorf("client did not offer the %s subprotocol", WebSocketSubprotocol)
+	}
+
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return err
+	}
+
+	conn := &WebSocketConnection{
+		conn:    ws,
+		options: h.options,
+		done:    make(chan struct{}),
+	}
+
+	logger.InfoContext(r.Context(), "WebSocket connection established", "remote_addr", r.RemoteAddr)
+	err = conn.serve(r.Context(), h.handler)
+	logger.InfoContext(r.Context(), "WebSocket connection closed", "remote_addr", r.RemoteAddr)
+
+	return err
+}
+
+// offersSubprotocol reports whether the client listed protocol in Sec-WebSocket-Protocol
+func offersSubprotocol(r *http.Request, protocol string) bool {
+	for _, p := range websocket.Subprotocols(r) {
+		if p == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// WebSocketConnection represents a single WebSocket connection. It
+// implements Connection with one JSON-RPC message per text frame.
+type WebSocketConnection struct {
+	conn      *websocket.Conn
+	options   WebSocketOptions
+	writeMux  sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// serve configures keepalives and serves the connection until it ends
+func (c *WebSocketConnection) serve(ctx context.Context, handler ConnectionHandler) error {
+	defer c.Close()
+
+	c.conn.EnableWriteCompression(c.options.EnableCompression)
+	if c.options.MaxMessageSize > 0 {
+		c.conn.SetReadLimit(c.options.MaxMessageSize)
+	}
+
+	c.extendReadDeadline()
+	c.conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+
+	if c.options.PingInterval > 0 {
+		go c.pingLoop()
+	}
+
+	return handler.ServeConnection(ctx, c)
+}
+
+// Read returns the next message from the client
+func (c *WebSocketConnection) Read(ctx context.Context) ([]byte, error) {
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			return nil, io.EOF
+		}
+		select {
+		case <-c.done:
+			return nil, ErrConnectionClosed
+		default:
+		}
+		return nil, err
+	}
+
+	c.extendReadDeadline()
+	return data, nil
+}
+
+// Write sends a JSON-RPC message to the client
+func (c *WebSocketConnection) Write(ctx context.Context, data []byte) error {
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+
+	select {
+	case <-c.done:
+		return ErrConnectionClosed
+	default:
+	}
+
+	if c.options.WriteTimeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.options.WriteTimeout)); err != nil {
+			return err
+		}
+	}
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// pingLoop sends ping frames until the connection is closed
+func (c *WebSocketConnection) pingLoop() {
+	ticker := time.NewTicker(c.options.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			deadline := time.Now().Add(c.options.PongTimeout)
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				logger.Warn("WebSocket ping failed", "error", err)
+				c.Close()
+				return
+			}
+		}
+	}
+}
+
+// extendReadDeadline pushes the read deadline out past the next expected pong
+func (c *WebSocketConnection) extendReadDeadline() {
+	if c.options.PingInterval <= 0 {
+		return
+	}
+	c.conn.SetReadDeadline(time.Now().Add(c.options.PingInterval + c.options.PongTimeout))
+}
+
+// Close closes the WebSocket connection
+func (c *WebSocketConnection) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		// Say goodbye so clients can tell a shutdown from a dropped connection
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server closing"),
+			time.Now().Add(time.Second))
+		err = c.conn.Close()
+	})
+	return err
+}
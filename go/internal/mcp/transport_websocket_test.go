@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newWebSocketTestServer serves handler at /ws and returns its ws:// URL
+func newWebSocketTestServer(t *testing.T, handler *WebSocketHandler) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.HandleWebSocket(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+func TestWebSocketSubprotocol(t *testing.T) {
+	tests := []struct {
+		name         string
+		subprotocols []string
+		wantStatus   int
+	}{
+		{"mcp", []string{WebSocketSubprotocol}, http.StatusSwitchingProtocols},
+		{"mcp among others", []string{"chat", WebSocketSubprotocol}, http.StatusSwitchingProtocols},
+		{"none", nil, http.StatusBadRequest},
+		{"other", []string{"chat"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := newWebSocketTestServer(t, NewWebSocketHandler(newTestServer(), DefaultWebSocketOptions()))
+			dialer := websocket.Dialer{Subprotocols: tt.subprotocols}
+
+			conn, resp, err := dialer.Dial(url, nil)
+			if resp == nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if conn == nil {
+				return
+			}
+			defer conn.Close()
+			if got := conn.Subprotocol(); got != WebSocketSubprotocol {
+				t.Errorf("Subprotocol() = %q, want %q", got, WebSocketSubprotocol)
+			}
+		})
+	}
+}
+
+func TestWebSocketCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name        string
+		checkOrigin func(r *http.Request) bool
+		// origin is the Origin header; "same" is the server's own origin
+		origin     string
+		wantStatus int
+	}{
+		{"no origin", nil, "", http.StatusSwitchingProtocols},
+		{"same origin", nil, "same", http.StatusSwitchingProtocols},
+		{"other origin", nil, "https://evil.example.com", http.StatusForbidden},
+		{"allowed by check", func(r *http.Request) bool { return true }, "https://app.example.com", http.StatusSwitchingProtocols},
+		{"denied by check", func(r *http.Request) bool { return false }, "same", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultWebSocketOptions()
+			options.CheckOrigin = tt.checkOrigin
+			url := newWebSocketTestServer(t, NewWebSocketHandler(newTestServer(), options))
+
+			header := http.Header{}
+			switch tt.origin {
+			case "":
+			case "same":
+				header.Set("Origin", "http"+strings.TrimSuffix(strings.TrimPrefix(url, "ws"), "/ws"))
+			default:
+				header.Set("Origin", tt.origin)
+			}
+			dialer := websocket.Dialer{Subprotocols: []string{WebSocketSubprotocol}}
+
+			conn, resp, err := dialer.Dial(url, header)
+			if resp == nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			if conn != nil {
+				conn.Close()
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWebSocketPing(t *testing.T) {
+	tests := []struct {
+		name string
+		// answer is whether the client answers pings with pongs
+		answer   bool
+		wantOpen bool
+	}{
+		{"answered", true, true},
+		{"unanswered", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultWebSocketOptions()
+			options.PingInterval = 20 * time.Millisecond
+			options.PongTimeout = 50 * time.Millisecond
+			url := newWebSocketTestServer(t, NewWebSocketHandler(newTestServer(), options))
+
+			dialer := websocket.Dialer{Subprotocols: []string{WebSocketSubprotocol}}
+			conn, _, err := dialer.Dial(url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			var pings atomic.Int32
+			conn.SetPingHandler(func(data string) error {
+				pings.Add(1)
+				if !tt.answer {
+					return nil
+				}
+				return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+			})
+
+			// Control frames are handled while reading; the read ends when
+			// the server gives up on the connection
+			closed := make(chan error, 1)
+			go func() {
+				for {
+					if _, _, err := conn.ReadMessage(); err != nil {
+						closed <- err
+						return
+					}
+				}
+			}()
+
+			select {
+			case err := <-closed:
+				if tt.wantOpen {
+					t.Fatalf("connection closed: %v", err)
+				}
+			case <-time.After(500 * time.Millisecond):
+				if !tt.wantOpen {
+					t.Fatal("connection still open without pongs")
+				}
+			}
+			if pings.Load() == 0 {
+				t.Error("no ping received")
+			}
+		})
+	}
+}
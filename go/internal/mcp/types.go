@@ -1,5 +1,10 @@
 package mcp
 
+import (
+	"context"
+	"fmt"
+)
+
 // JSONRPCRequest represents a JSON-RPC 2.0 request
 type JSONRPCRequest struct {
 	JSONRPC string                 `json:"jsonrpc"`
@@ -10,10 +15,17 @@ type JSONRPCRequest struct {
 
 // JSONRPCResponse represents a JSON-RPC 2.0 response
 type JSONRPCResponse struct {
-	JSONRPC string                 `json:"jsonrpc"`
-	ID      interface{}            `json:"id,omitempty"`
-	Result  interface{}            `json:"result,omitempty"`
-	Error   *JSONRPCError          `json:"error,omitempty"`
+	JSONRPC string        `json:"jsonrpc"`
+	ID      interface{}   `json:"id,omitempty"`
+	Result  interface{}   `json:"result,omitempty"`
+	Error   *JSONRPCError `json:"error,omitempty"`
+}
+
+// JSONRPCNotification represents a JSON-RPC 2.0 notification
+type JSONRPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
 }
 
 // JSONRPCError represents a JSON-RPC 2.0 error
@@ -21,6 +33,13 @@ type JSONRPCError struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	// Kind labels the error in metrics when tool middleware returns it
+	Kind string `json:"-"`
+}
+
+// Error implements the error interface so JSON-RPC errors can be returned to callers
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
 }
 
 // InitializeParams represents initialization parameters
@@ -54,29 +73,100 @@ type Tool struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
 	InputSchema map[string]interface{} `json:"inputSchema"`
+	Annotations *ToolAnnotations       `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations describe a tool's behavior to clients. They are hints:
+// clients shouldn't rely on them for tools from untrusted servers.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    bool   `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  bool   `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+// ReadOnly reports whether the tool is annotated as not modifying its environment
+func (t Tool) ReadOnly() bool {
+	return t.Annotations != nil && t.Annotations.ReadOnlyHint
 }
 
 // ListToolsResult represents the result of listing tools
 type ListToolsResult struct {
-	Tools []Tool `json:"tools"`
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // CallToolParams represents parameters for calling a tool
 type CallToolParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
+}
+
+// RequestMeta represents the _meta field of a request
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"`
+}
+
+// ProgressNotificationParams represents the params of notifications/progress
+type ProgressNotificationParams struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
 }
 
 // CallToolResult represents the result of calling a tool
 type CallToolResult struct {
 	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
 }
 
 // Content represents tool result content
 type Content struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// Resource represents an MCP resource
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ListResourcesResult represents the result of listing resources
+type ListResourcesResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// ReadResourceParams represents parameters for reading a resource
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// ResourceContents represents the contents of a resource
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// ReadResourceResult represents the result of reading a resource
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
 }
 
 // ToolHandler is a function that handles tool execution
 type ToolHandler func(args map[string]interface{}) (interface{}, error)
+
+// ContextToolHandler is a tool handler that receives the request context.
+// Returning a *CallToolResult sends it to the client as is; any other value
+// is marshalled to JSON text content.
+type ContextToolHandler func(ctx context.Context, args map[string]interface{}) (interface{}, error)
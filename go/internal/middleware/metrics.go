@@ -0,0 +1,10 @@
+package middleware
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// AuthFailures counts requests rejected by the authentication middleware,
+// by reason. Register it with the application's Prometheus registry.
+var AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "mcp_auth_failures_total",
+	Help: "Requests rejected by authentication, by reason.",
+}, []string{"reason"})
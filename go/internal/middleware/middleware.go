@@ -0,0 +1,309 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/oauth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+)
+
+// logger is the logger of the "auth" component
+var logger = logging.Component("auth")
+
+// Auth validates the API key sent as a Bearer token. Requests already
+// authenticated by ClientCert pass through; with an empty apiKey only
+// those are accepted.
+func Auth(apiKey string) gin.HandlerFunc {
+	keys := auth.SingleKey(apiKey)
+	return AuthFunc(func() *auth.KeyStore { return keys })
+}
+
+// AuthFunc validates the Bearer token against the key store returned by
+// keys, which is looked up on every request so keys can be rotated while
+// the server runs
+func AuthFunc(keys func() *auth.KeyStore) gin.HandlerFunc {
+	return AuthWith(AuthOptions{Keys: keys})
+}
+
+// TokenValidator validates OAuth access tokens
+type TokenValidator interface {
+	Validate(ctx context.Context, token string) (*auth.Identity, error)
+}
+
+// AuthOptions configures AuthWith
+type AuthOptions struct {
+	// Keys returns the API keys; it is called on every request
+	Keys func() *auth.KeyStore
+	// Tokens, if set, validates Bearer credentials that are JWTs
+	Tokens TokenValidator
+	// ResourceMetadata is the URL of the protected resource metadata,
+	// sent in WWW-Authenticate so clients can find the authorization server
+	ResourceMetadata string
+}
+
+// AuthWith authenticates the Bearer credential as an API key or, when
+// options.Tokens is set, an OAuth access token, and attaches the caller's
+// identity to the request context. Requests already authenticated by
+// ClientCert pass through.
+func AuthWith(options AuthOptions) gin.HandlerFunc {
+	authenticator := NewAuthenticator(options)
+	identify, require := authenticator.Identify(), authenticator.Require()
+	return func(c *gin.Context) {
+		identify(c)
+		require(c)
+	}
+}
+
+// Authenticator splits authentication in two steps, so middleware in
+// between (such as rate limiting) can tell callers apart: Identify runs
+// for every request and attaches the identity of valid credentials, and
+// Require rejects the requests of routes that need one but have none.
+type Authenticator struct {
+	options AuthOptions
+}
+
+// NewAuthenticator creates an Authenticator
+func NewAuthenticator(options AuthOptions) *Authenticator {
+	return &Authenticator{options: options}
+}
+
+// authFailure records why a request's credential was rejected, for Require
+type authFailure struct {
+	// reason labels the failure in the AuthFailures metric
+	reason      string
+	message     string
+	code        string
+	description string
+}
+
+const authFailureKey = "auth.failure"
+
+// Identify attaches the identity of the request's Bearer credential to the
+// request context. It never rejects a request; invalid credentials are
+// remembered for Require.
+func (a *Authenticator) Identify() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.IdentityFromContext(c.Request.Context()) != nil {
+			return
+		}
+
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			return
+		}
+
+		// Check for Bearer token
+		if !strings.HasPrefix(header, "Bearer ") {
+			c.Set(authFailureKey, authFailure{reason: "invalid_header", message: "Invalid Authorization header format", code: "invalid_request"})
+			return
+		}
+		credential := strings.TrimPrefix(header, "Bearer ")
+
+		var identity *auth.Identity
+		var err error
+		if a.options.Tokens != nil && oauth.LooksLikeToken(credential) {
+			identity, err = a.options.Tokens.Validate(c.Request.Context(), credential)
+			if err != nil {
+				logger.InfoContext(c.Request.Context(), "Access token rejected", "error", err)
+				c.Set(authFailureKey, authFailure{reason: "invalid_token", message: "Invalid access token", code: "invalid_token", description: oauth.Describe(err)})
+				return
+			}
+		} else {
+			identity, err = a.options.Keys().Authenticate(credential)
+			if err != nil {
+				failure := authFailure{reason: "invalid_key", message: "Invalid API key", code: "invalid_token"}
+				if errors.Is(err, auth.ErrExpiredKey) {
+					failure.reason, failure.message = "expired_key", "API key expired"
+				}
+				c.Set(authFailureKey, failure)
+				return
+			}
+		}
+
+		c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), identity))
+	}
+}
+
+// Require rejects requests Identify found no identity for, with a
+// WWW-Authenticate challenge (RFC 6750)
+func (a *Authenticator) Require() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.IsAborted() {
+			return
+		}
+		if auth.IdentityFromContext(c.Request.Context()) != nil {
+			c.Next()
+			return
+		}
+
+		if a.options.Keys().Empty() && a.options.Tokens == nil {
+			AuthFailures.WithLabelValues("no_client_cert").Inc()
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Client certificate required"})
+			c.Abort()
+			return
+		}
+
+		failure := authFailure{reason: "missing", message: "Missing Authorization header"}
+		if f, ok := c.Get(authFailureKey); ok {
+			failure = f.(authFailure)
+		}
+		AuthFailures.WithLabelValues(failure.reason).Inc()
+
+		challenge := `Bearer realm="mcp"`
+		if a.options.ResourceMetadata != "" {
+			challenge += fmt.Sprintf(`, resource_metadata=%q`, a.options.ResourceMetadata)
+		}
+		if failure.code != "" {
+			challenge += fmt.Sprintf(`, error=%q`, failure.code)
+		}
+		if failure.description != "" {
+			challenge += fmt.Sprintf(`, error_description=%q`, failure.description)
+		}
+		c.Header("WWW-Authenticate", challenge)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": failure.message})
+		c.Abort()
+	}
+}
+
+// RequireScope rejects authenticated callers without scope with 403. It
+// goes after the authentication middleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := auth.IdentityFromContext(c.Request.Context())
+		if identity == nil || !identity.HasScope(scope) {
+			AuthFailures.WithLabelValues("insufficient_scope").Inc()
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm="mcp", error="insufficient_scope", scope=%q`, scope))
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient scope"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ClientCert authenticates requests that presented a verified TLS client
+// certificate, mapping its subject to an identity with subjects. Requests
+// without one are left to Auth; certificates with no mapped identity are
+// refused.
+func ClientCert(subjects auth.SubjectMap) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := c.Request.TLS
+		if state == nil || len(state.VerifiedChains) == 0 {
+			c.Next()
+			return
+		}
+
+		identity, ok := subjects.Identity(state.VerifiedChains[0][0])
+		if !ok {
+			AuthFailures.WithLabelValues("unknown_certificate").Inc()
+			c.JSON(http.StatusForbidden, gin.H{"error": "Client certificate not authorized"})
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), identity))
+		c.Next()
+	}
+}
+
+// CORS adds CORS headers; an empty origin allows any origin
+func CORS(origin string) gin.HandlerFunc {
+	return CORSFunc(func() string { return origin })
+}
+
+// CORSFunc is CORS with the allowed origin looked up on every request
+func CORSFunc(origin func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := origin()
+		if origin == "" {
+			origin = "*"
+		}
+
+		c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+RequestIDHeader)
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "WWW-Authenticate, "+RequestIDHeader)
+		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// CheckOrigin returns a WebSocket origin check mirroring CORS: any origin
+// is accepted unless origin restricts it
+func CheckOrigin(origin string) func(r *http.Request) bool {
+	return CheckOriginFunc(func() string { return origin })
+}
+
+// CheckOriginFunc is CheckOrigin with the allowed origin looked up on every
+// connection
+func CheckOriginFunc(origin func() string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := origin()
+		if origin == "" || origin == "*" {
+			return true
+		}
+		o := r.Header.Get("Origin")
+		return o == "" || o == origin
+	}
+}
+
+// RateLimit allows each caller at most limit requests per minute
+func RateLimit(limit int) gin.HandlerFunc {
+	return RateLimitFunc(func() int { return limit })
+}
+
+// RateLimitFunc is RateLimit with the limit looked up on every request
+func RateLimitFunc(limit func() int) gin.HandlerFunc {
+	return RateLimitWith(ratelimit.New(), func() ratelimit.Limit {
+		return ratelimit.Limit{PerMinute: limit()}
+	})
+}
+
+// RateLimitWith limits requests with a token bucket per caller: the
+// authenticated identity if an earlier middleware attached one, otherwise
+// the client IP. limit is looked up on every request. Responses carry
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, and
+// rejected ones Retry-After.
+func RateLimitWith(limiter *ratelimit.Limiter, limit func() ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := ratelimit.Principal(auth.IdentityFromContext(c.Request.Context()))
+		if key == "" {
+			key = "ip:" + c.ClientIP()
+		}
+
+		result := limiter.Allow(key, limit())
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			ratelimit.Rejections.WithLabelValues("http").Inc()
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,683 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+)
+
+// logger is the logger of the "oauth" component
+var logger = logging.Component("oauth")
+
+// Endpoints of the embedded authorization server
+const (
+	ServerMetadataPath = "/.well-known/oauth-authorization-server"
+	JWKSPath           = "/.well-known/jwks.json"
+	AuthorizePath      = "/authorize"
+	TokenPath          = "/token"
+	RegisterPath       = "/register"
+)
+
+// codeTTL is how long an authorization code can be exchanged for tokens
+const codeTTL = time.Minute
+
+// ServerOptions configures the embedded authorization server
+type ServerOptions struct {
+	// Issuer is the server's public base URL, e.g. https://mcp.example.com
+	Issuer string
+	// Resource is the audience of issued access tokens
+	Resource string
+	// Signer signs access tokens
+	Signer *Signer
+	// Users may sign in on the consent page
+	Users []User
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// AuthorizationServer is a minimal OAuth 2.1 authorization server for
+// setups without an identity provider. It supports the authorization code
+// grant with PKCE (S256 only), rotating refresh tokens and dynamic client
+// registration; users sign in with a password on its consent page.
+type AuthorizationServer struct {
+	options  ServerOptions
+	users    map[string]User
+	store    *store
+	throttle *signInThrottle
+}
+
+// ServerMetadata is the authorization server metadata (RFC 8414)
+type ServerMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+var (
+	supportedScopes      = []string{auth.ScopeToolsRead, auth.ScopeToolsCall}
+	supportedGrantTypes  = []string{"authorization_code", "refresh_token"}
+	supportedAuthMethods = []string{"none", "client_secret_basic", "client_secret_post"}
+)
+
+// NewAuthorizationServer creates an authorization server, checking its users
+func NewAuthorizationServer(options ServerOptions) (*AuthorizationServer, error) {
+	users := make(map[string]User)
+	for i, user := range options.Users {
+		if err := user.Validate(); err != nil {
+			return nil, fmt.Errorf("user %d (%s): %w", i, user.Name, err)
+		}
+		if _, ok := users[user.Name]; ok {
+			return nil, fmt.Errorf("user %d (%s): duplicate name", i, user.Name)
+		}
+		users[user.Name] = user
+	}
+
+	options.Issuer = strings.TrimSuffix(options.Issuer, "/")
+	return &AuthorizationServer{
+		options:  options,
+		users:    users,
+		store:    newStore(),
+		throttle: &signInThrottle{limiter: ratelimit.New()},
+	}, nil
+}
+
+// Metadata returns the server's metadata
+func (s *AuthorizationServer) Metadata() ServerMetadata {
+	return ServerMetadata{
+		Issuer:                            s.options.Issuer,
+		AuthorizationEndpoint:             s.options.Issuer + AuthorizePath,
+		TokenEndpoint:                     s.options.Issuer + TokenPath,
+		RegistrationEndpoint:              s.options.Issuer + RegisterPath,
+		JWKSURI:                           s.options.Issuer + JWKSPath,
+		ScopesSupported:                   supportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               supportedGrantTypes,
+		TokenEndpointAuthMethodsSupported: supportedAuthMethods,
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	}
+}
+
+// HandleMetadata serves the server metadata
+func (s *AuthorizationServer) HandleMetadata(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.Metadata())
+}
+
+// HandleJWKS serves the public key that signs access tokens
+func (s *AuthorizationServer) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.options.Signer.JWKS())
+}
+
+// registrationRequest is the client metadata sent to /register
+type registrationRequest struct {
+	RedirectURIs            []string `json:"redirect_uris"`
+	ClientName              string   `json:"client_name,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+}
+
+// registrationResponse is the registered client returned by /register
+type registrationResponse struct {
+	registrationRequest
+	ClientID              string `json:"client_id"`
+	ClientSecret          string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt      int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt *int64 `json:"client_secret_expires_at,omitempty"`
+}
+
+// HandleRegister registers a client (RFC 7591). Registration is open, as
+// MCP clients expect; clients still need a user to approve them.
+func (s *AuthorizationServer) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	var req registrationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "Body must be a JSON object")
+		return
+	}
+
+	if len(req.RedirectURIs) == 0 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_redirect_uri", "redirect_uris is required")
+		return
+	}
+	for _, uri := range req.RedirectURIs {
+		if err := checkRedirectURI(uri); err != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_redirect_uri", err.Error())
+			return
+		}
+	}
+
+	if req.TokenEndpointAuthMethod == "" {
+		req.TokenEndpointAuthMethod = "client_secret_basic"
+	}
+	if !slices.Contains(supportedAuthMethods, req.TokenEndpointAuthMethod) {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "Unsupported token_endpoint_auth_method")
+		return
+	}
+	if len(req.GrantTypes) == 0 {
+		req.GrantTypes = []string{"authorization_code"}
+	}
+	for _, grantType := range req.GrantTypes {
+		if !slices.Contains(supportedGrantTypes, grantType) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "Unsupported grant type "+grantType)
+			return
+		}
+	}
+	if len(req.ResponseTypes) == 0 {
+		req.ResponseTypes = []string{"code"}
+	}
+	for _, responseType := range req.ResponseTypes {
+		if responseType != "code" {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_client_metadata", "Unsupported response type "+responseType)
+			return
+		}
+	}
+
+	id, err := randomToken()
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	client := &Client{
+		ID:           id,
+		Name:         req.ClientName,
+		RedirectURIs: req.RedirectURIs,
+		AuthMethod:   req.TokenEndpointAuthMethod,
+		CreatedAt:    time.Now(),
+	}
+	resp := registrationResponse{
+		registrationRequest: req,
+		ClientID:            client.ID,
+		ClientIDIssuedAt:    client.CreatedAt.Unix(),
+	}
+	if client.AuthMethod != "none" {
+		secret, err := randomToken()
+		if err != nil {
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		sum := sha256.Sum256([]byte(secret))
+		client.SecretHash = sum[:]
+		resp.ClientSecret = secret
+		never := int64(0)
+		resp.ClientSecretExpiresAt = &never
+	}
+
+	if err := s.store.addClient(client); err != nil {
+		writeOAuthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", err.Error())
+		return
+	}
+	logger.InfoContext(r.Context(), "OAuth client registered", "client_name", client.Name, "client_id", client.ID)
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// checkRedirectURI accepts https URIs, http URIs on a loopback address and
+// private-use schemes of native apps (RFC 8252)
+func checkRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() {
+		return fmt.Errorf("redirect URI %q is not an absolute URI", uri)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect URI %q must not have a fragment", uri)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if isLoopback(u.Hostname()) {
+			return nil
+		}
+		return fmt.Errorf("redirect URI %q must use https unless it is on localhost", uri)
+	case "javascript", "data", "file", "vbscript":
+		return fmt.Errorf("redirect URI %q has a forbidden scheme", uri)
+	default:
+		return nil
+	}
+}
+
+// isLoopback reports whether host is localhost or a loopback address
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// matchRedirectURI reports whether uri is one the client registered.
+// Loopback redirects may use any port, since native apps pick a free one
+// when they start (RFC 8252 section 7.3).
+func matchRedirectURI(registered []string, uri string) bool {
+	if slices.Contains(registered, uri) {
+		return true
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "http" || !isLoopback(u.Hostname()) {
+		return false
+	}
+	for _, r := range registered {
+		ru, err := url.Parse(r)
+		if err == nil && ru.Scheme == "http" && ru.Hostname() == u.Hostname() &&
+			ru.Path == u.Path && ru.RawQuery == u.RawQuery {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeRequest is a validated authorization request
+type authorizeRequest struct {
+	client      *Client
+	redirectURI string
+	state       string
+	challenge   string
+	scopes      []string
+	resource    string
+}
+
+// authorizeError is an error in an authorization request. Errors before
+// the redirect URI is known are shown to the user; later ones are sent
+// back to the client.
+type authorizeError struct {
+	code        string
+	description string
+	redirect    bool
+}
+
+// parseAuthorize validates the parameters of an authorization request
+func (s *AuthorizationServer) parseAuthorize(values url.Values) (*authorizeRequest, *authorizeError) {
+	client, ok := s.store.client(values.Get("client_id"))
+	if !ok {
+		return nil, &authorizeError{code: "invalid_client", description: "Unknown client_id"}
+	}
+
+	req := &authorizeRequest{
+		client:      client,
+		redirectURI: values.Get("redirect_uri"),
+		state:       values.Get("state"),
+	}
+	if req.redirectURI == "" && len(client.RedirectURIs) == 1 {
+		req.redirectURI = client.RedirectURIs[0]
+	}
+	if !matchRedirectURI(client.RedirectURIs, req.redirectURI) {
+		return nil, &authorizeError{code: "invalid_request", description: "redirect_uri is not registered for this client"}
+	}
+
+	// The redirect URI is trusted from here on
+	fail := func(code, description string) (*authorizeRequest, *authorizeError) {
+		return req, &authorizeError{code: code, description: description, redirect: true}
+	}
+
+	if values.Get("response_type") != "code" {
+		return fail("unsupported_response_type", "response_type must be code")
+	}
+	req.challenge = values.Get("code_challenge")
+	if req.challenge == "" {
+		return fail("invalid_request", "code_challenge is required (PKCE)")
+	}
+	if values.Get("code_challenge_method") != "S256" {
+		return fail("invalid_request", "code_challenge_method must be S256")
+	}
+
+	req.resource = values.Get("resource")
+	if req.resource == "" {
+		req.resource = s.options.Resource
+	}
+	if req.resource != s.options.Resource {
+		return fail("invalid_target", "Unknown resource")
+	}
+
+	req.scopes = strings.Fields(values.Get("scope"))
+	for _, scope := range req.scopes {
+		if !slices.Contains(supportedScopes, scope) {
+			return fail("invalid_scope", "Unknown scope "+scope)
+		}
+	}
+	return req, nil
+}
+
+// redirect sends the user agent back to the client with params, adding
+// the state and issuer (RFC 9207)
+func (s *AuthorizationServer) redirect(w http.ResponseWriter, r *http.Request, req *authorizeRequest, params url.Values) {
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+	params.Set("iss", s.options.Issuer)
+
+	target, _ := url.Parse(req.redirectURI) // checked at registration
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// HandleAuthorize shows the consent page (GET) and handles its form (POST).
+// The user signs in with their name and password and approves or denies
+// the client's request.
+func (s *AuthorizationServer) HandleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderError(w, http.StatusBadRequest, "The request is malformed.")
+		return
+	}
+	params := r.URL.Query()
+	if r.Method == http.MethodPost {
+		params = r.PostForm
+	}
+
+	req, aerr := s.parseAuthorize(params)
+	if aerr != nil {
+		if !aerr.redirect {
+			renderError(w, http.StatusBadRequest, aerr.description)
+			return
+		}
+		s.redirect(w, r, req, url.Values{"error": {aerr.code}, "error_description": {aerr.description}})
+		return
+	}
+
+	page := consentPage{
+		Client:      req.client.Name,
+		RedirectURI: req.redirectURI,
+		Scopes:      req.scopes,
+		Params:      params,
+	}
+	if page.Client == "" {
+		page.Client = req.client.ID
+	}
+	if len(page.Scopes) == 0 {
+		page.Scopes = []string{"(all scopes you hold)"}
+	}
+
+	if r.Method != http.MethodPost {
+		renderConsent(w, http.StatusOK, page)
+		return
+	}
+
+	if r.PostForm.Get("action") != "approve" {
+		s.redirect(w, r, req, url.Values{"error": {"access_denied"}, "error_description": {"The user denied the request"}})
+		return
+	}
+
+	username, ip := r.PostForm.Get("username"), clientIP(r)
+	page.Username = username
+	if wait := s.throttle.wait(username, ip); wait > 0 {
+		logger.WarnContext(r.Context(), "OAuth sign-in throttled", "user", username, "client_ip", ip)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		page.Error = "Too many failed sign-ins. Try again later."
+		renderConsent(w, http.StatusTooManyRequests, page)
+		return
+	}
+	user, ok := checkPassword(s.users, username, r.PostForm.Get("password"))
+	if !ok {
+		s.throttle.failed(username, ip)
+		logger.WarnContext(r.Context(), "OAuth sign-in failed", "user", username, "client_ip", ip)
+		page.Error = "Incorrect user name or password."
+		renderConsent(w, http.StatusUnauthorized, page)
+		return
+	}
+
+	scopes := grantScopes(req.scopes, user.grantable())
+	if len(scopes) == 0 {
+		s.redirect(w, r, req, url.Values{"error": {"access_denied"}, "error_description": {"The user may not grant the requested scopes"}})
+		return
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		s.redirect(w, r, req, url.Values{"error": {"server_error"}})
+		return
+	}
+	s.store.saveCode(code, &grant{
+		ClientID:    req.client.ID,
+		User:        user.Name,
+		Scopes:      scopes,
+		Resource:    req.resource,
+		RedirectURI: req.redirectURI,
+		Challenge:   req.challenge,
+		ExpiresAt:   time.Now().Add(codeTTL),
+	})
+	logger.InfoContext(r.Context(), "OAuth client approved", "user", user.Name, "client", page.Client, "scope", strings.Join(scopes, " "))
+	s.redirect(w, r, req, url.Values{"code": {code}})
+}
+
+// grantScopes returns the requested scopes the user holds, or all of the
+// user's scopes if none were requested. tools:call covers tools:read.
+func grantScopes(requested, held []string) []string {
+	if len(requested) == 0 {
+		return held
+	}
+	var granted []string
+	for _, scope := range requested {
+		if slices.Contains(held, scope) || (scope == auth.ScopeToolsRead && slices.Contains(held, auth.ScopeToolsCall)) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted
+}
+
+// tokenResponse is returned by /token
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// HandleToken exchanges an authorization code or refresh token for tokens
+func (s *AuthorizationServer) HandleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Body must be form-encoded")
+		return
+	}
+
+	client, ok := s.authenticateClient(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	var g *grant
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		g, ok = s.store.takeCode(r.PostForm.Get("code"))
+		if !ok || g.ClientID != client.ID {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+			return
+		}
+		if uri := r.PostForm.Get("redirect_uri"); uri != "" && uri != g.RedirectURI {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the authorization request")
+			return
+		}
+		if !verifyChallenge(r.PostForm.Get("code_verifier"), g.Challenge) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code_challenge")
+			return
+		}
+		s.store.markGranted(client.ID)
+
+	case "refresh_token":
+		// The token is only consumed once the request is known to succeed,
+		// so a rejected refresh leaves it usable
+		token := r.PostForm.Get("refresh_token")
+		g, ok = s.store.refreshToken(token)
+		if !ok || g.ClientID != client.ID {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired refresh token")
+			return
+		}
+		// A refresh may narrow the scopes, never widen them
+		if scope := r.PostForm.Get("scope"); scope != "" {
+			narrowed := strings.Fields(scope)
+			for _, requested := range narrowed {
+				if !slices.Contains(g.Scopes, requested) {
+					writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "Scope exceeds the original grant")
+					return
+				}
+			}
+			g.Scopes = narrowed
+		}
+		if !s.checkResource(w, r, g) {
+			return
+		}
+		// Another request may have used the token in the meantime
+		if _, ok := s.store.takeRefreshToken(token); !ok {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired refresh token")
+			return
+		}
+
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+		return
+	}
+
+	if !s.checkResource(w, r, g) {
+		return
+	}
+
+	resp, err := s.issue(g)
+	if err != nil {
+		logger.ErrorContext(r.Context(), "OAuth: failed to issue tokens", "error", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// checkResource checks that a token request's resource, if any, is the
+// grant's, writing the error response if not
+func (s *AuthorizationServer) checkResource(w http.ResponseWriter, r *http.Request, g *grant) bool {
+	if resource := r.PostForm.Get("resource"); resource != "" && resource != g.Resource {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_target", "Unknown resource")
+		return false
+	}
+	return true
+}
+
+// authenticateClient identifies the client of a token request. Public
+// clients only send their ID; confidential ones also prove their secret,
+// in the Authorization header or the form.
+func (s *AuthorizationServer) authenticateClient(r *http.Request) (*Client, bool) {
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		// Credentials in the Basic header are form-encoded (RFC 6749 2.3.1)
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client, ok := s.store.client(id)
+	if !ok {
+		return nil, false
+	}
+	switch client.AuthMethod {
+	case "none":
+		return client, secret == ""
+	case "client_secret_basic":
+		if !basic {
+			return nil, false
+		}
+	case "client_secret_post":
+		if basic {
+			return nil, false
+		}
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return client, subtle.ConstantTimeCompare(sum[:], client.SecretHash) == 1
+}
+
+// verifyChallenge checks a PKCE code verifier against its S256 challenge
+func verifyChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// issue creates an access token and a new refresh token for a grant
+func (s *AuthorizationServer) issue(g *grant) (*tokenResponse, error) {
+	now := time.Now()
+	jti, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.options.Signer.Sign(jwt.MapClaims{
+		"iss":       s.options.Issuer,
+		"sub":       g.User,
+		"aud":       g.Resource,
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.options.AccessTokenTTL).Unix(),
+		"jti":       jti,
+		"client_id": g.ClientID,
+		"scope":     strings.Join(g.Scopes, " "),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	s.store.saveRefreshToken(refreshToken, &grant{
+		ClientID:  g.ClientID,
+		User:      g.User,
+		Scopes:    g.Scopes,
+		Resource:  g.Resource,
+		ExpiresAt: now.Add(s.options.RefreshTokenTTL),
+	})
+
+	return &tokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.options.AccessTokenTTL / time.Second),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(g.Scopes, " "),
+	}, nil
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeOAuthError writes an OAuth error response (RFC 6749 section 5.2)
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	body := map[string]string{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,211 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+)
+
+func TestVerifyChallenge(t *testing.T) {
+	// The example of RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"matching", verifier, challenge, true},
+		{"wrong verifier", verifier[:42] + "Y", challenge, false},
+		{"plain challenge", verifier, verifier, false},
+		{"empty challenge", verifier, "", false},
+		{"verifier too short", verifier[:42], s256(verifier[:42]), false},
+		{"shortest verifier", verifier[:43], s256(verifier[:43]), true},
+		{"longest verifier", strings.Repeat("a", 128), s256(strings.Repeat("a", 128)), true},
+		{"verifier too long", strings.Repeat("a", 129), s256(strings.Repeat("a", 129)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyChallenge(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("verifyChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// s256 returns the S256 code challenge of verifier
+func s256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestCheckRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		wantErr bool
+	}{
+		{"https://app.example.com/callback", false},
+		{"http://localhost:3000/callback", false},
+		{"http://127.0.0.1/callback", false},
+		{"http://[::1]:8080/callback", false},
+		{"com.example.app:/callback", false},
+		{"http://app.example.com/callback", true},
+		{"https://app.example.com/callback#fragment", true},
+		{"/callback", true},
+		{"javascript:alert(1)", true},
+		{"data:text/html,hi", true},
+		{"file:///etc/passwd", true},
+		{"vbscript:msgbox", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			err := checkRedirectURI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkRedirectURI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchRedirectURI(t *testing.T) {
+	registered := []string{
+		"https://app.example.com/callback",
+		"http://127.0.0.1:3000/callback",
+		"http://localhost/cb?app=1",
+	}
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{"https://app.example.com/callback", true},
+		{"https://app.example.com/callback/", false},
+		{"https://app.example.com/callback?x=1", false},
+		{"https://app.example.com:8443/callback", false},
+		{"https://evil.example.com/callback", false},
+		// Loopback redirects may use any port
+		{"http://127.0.0.1:3000/callback", true},
+		{"http://127.0.0.1:49152/callback", true},
+		{"http://127.0.0.1/callback", true},
+		{"http://localhost:49152/cb?app=1", true},
+		// ... but not another host, path, query or scheme
+		{"http://localhost:3000/callback", false},
+		{"http://127.0.0.1:49152/other", false},
+		{"http://localhost:49152/cb?app=2", false},
+		{"https://127.0.0.1:49152/callback", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			if got := matchRedirectURI(registered, tt.uri); got != tt.want {
+				t.Errorf("matchRedirectURI(%q) = %v, want %v", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrantScopes(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested []string
+		held      []string
+		want      []string
+	}{
+		{"none requested", nil, []string{auth.ScopeToolsCall}, []string{auth.ScopeToolsCall}},
+		{"held", []string{auth.ScopeToolsRead}, []string{auth.ScopeToolsRead}, []string{auth.ScopeToolsRead}},
+		{"call covers read", []string{auth.ScopeToolsRead}, []string{auth.ScopeToolsCall}, []string{auth.ScopeToolsRead}},
+		{"read doesn't cover call", []string{auth.ScopeToolsCall}, []string{auth.ScopeToolsRead}, nil},
+		{"not held", []string{auth.ScopeAdmin, auth.ScopeToolsRead}, []string{auth.ScopeToolsRead}, []string{auth.ScopeToolsRead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grantScopes(tt.requested, tt.held); !slices.Equal(got, tt.want) {
+				t.Errorf("grantScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleTokenRefresh(t *testing.T) {
+	tests := []struct {
+		name     string
+		form     url.Values
+		wantCode string
+		// wantUsable is whether the refresh token still works afterwards
+		wantUsable bool
+	}{
+		{"refresh", url.Values{}, "", false},
+		{"narrowed scope", url.Values{"scope": {auth.ScopeToolsRead}}, "", false},
+		{"invalid scope", url.Values{"scope": {auth.ScopeAdmin}}, "invalid_scope", true},
+		{"other client", url.Values{"client_id": {"other"}}, "invalid_grant", true},
+		{"other resource", url.Values{"resource": {"https://other.example.com"}}, "invalid_target", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigner()
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, err := NewAuthorizationServer(ServerOptions{
+				Issuer:          "https://mcp.example.com",
+				Resource:        "https://mcp.example.com/mcp",
+				Signer:          signer,
+				AccessTokenTTL:  time.Minute,
+				RefreshTokenTTL: time.Hour,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range []string{"client", "other"} {
+				if err := s.store.addClient(&Client{ID: id, AuthMethod: "none", CreatedAt: time.Now()}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s.store.saveRefreshToken("token", &grant{
+				ClientID:  "client",
+				User:      "alice",
+				Scopes:    []string{auth.ScopeToolsRead, auth.ScopeToolsCall},
+				Resource:  "https://mcp.example.com/mcp",
+				ExpiresAt: time.Now().Add(time.Hour),
+			})
+
+			refresh := func(form url.Values) (int, map[string]string) {
+				t.Helper()
+				values := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"token"}, "client_id": {"client"}}
+				for key, value := range form {
+					values[key] = value
+				}
+				req := httptest.NewRequest(http.MethodPost, TokenPath, strings.NewReader(values.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				rec := httptest.NewRecorder()
+				s.HandleToken(rec, req)
+				var body map[string]string
+				json.Unmarshal(rec.Body.Bytes(), &body)
+				return rec.Code, body
+			}
+
+			code, body := refresh(tt.form)
+			if body["error"] != tt.wantCode {
+				t.Fatalf("HandleToken() status = %d, error = %q, want %q", code, body["error"], tt.wantCode)
+			}
+			if tt.wantCode == "" && body["refresh_token"] == "" {
+				t.Error("HandleToken() returned no new refresh token")
+			}
+
+			code, _ = refresh(nil)
+			if usable := code == http.StatusOK; usable != tt.wantUsable {
+				t.Errorf("refresh token usable afterwards = %v, want %v", usable, tt.wantUsable)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package oauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+)
+
+// consentPage is the data shown on the consent page
+type consentPage struct {
+	Client      string
+	RedirectURI string
+	Scopes      []string
+	Username    string
+	Error       string
+	// Params are the authorization request's parameters, posted back
+	// with the form
+	Params url.Values
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Authorize {{.Client}}</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #f4f4f5; display: flex; justify-content: center; padding-top: 10vh; }
+form { background: #fff; padding: 2rem; border-radius: 8px; box-shadow: 0 1px 4px rgba(0,0,0,.1); width: 22rem; }
+h1 { font-size: 1.2rem; margin-top: 0; }
+label { display: block; margin: .8rem 0 .2rem; font-size: .9rem; }
+input[type=text], input[type=password] { width: 100%; padding: .4rem; box-sizing: border-box; }
+code { font-size: .85rem; word-break: break-all; }
+.error { color: #b91c1c; }
+.buttons { display: flex; gap: .5rem; margin-top: 1.2rem; }
+button { flex: 1; padding: .5rem; }
+</style>
+</head>
+<body>
+<form method="post">
+<h1><strong>{{.Client}}</strong> wants to access this MCP server</h1>
+<p>It asks for:</p>
+<ul>{{range .Scopes}}<li><code>{{.}}</code></li>{{end}}</ul>
+<p>You will be sent back to <code>{{.RedirectURI}}</code>.</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{range $name, $values := .Params}}{{if and (ne $name "username") (ne $name "password") (ne $name "action")}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">
+{{end}}{{end}}{{end}}
+<label for="username">User name</label>
+<input type="text" id="username" name="username" value="{{.Username}}" autocomplete="username" autofocus>
+<label for="password">Password</label>
+<input type="password" id="password" name="password" autocomplete="current-password">
+<div class="buttons">
+<button type="submit" name="action" value="deny">Deny</button>
+<button type="submit" name="action" value="approve">Approve</button>
+</div>
+</form>
+</body>
+</html>
+`))
+
+var errorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Authorization failed</title></head>
+<body style="font-family: system-ui, sans-serif; padding: 10vh 2rem">
+<h1>Authorization failed</h1>
+<p>{{.}}</p>
+</body>
+</html>
+`))
+
+// renderConsent writes the consent page. It may not be framed, so other
+// sites can't trick users into approving clients.
+func renderConsent(w http.ResponseWriter, status int, page consentPage) {
+	setPageHeaders(w)
+	w.WriteHeader(status)
+	consentTemplate.Execute(w, page)
+}
+
+// renderError writes an error page for requests that can't be sent back
+// to the client
+func renderError(w http.ResponseWriter, status int, message string) {
+	setPageHeaders(w)
+	w.WriteHeader(status)
+	errorTemplate.Execute(w, message)
+}
+
+func setPageHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+}
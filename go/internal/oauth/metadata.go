@@ -0,0 +1,50 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MetadataPath is where protected resource metadata is served (RFC 9728)
+const MetadataPath = "/.well-known/oauth-protected-resource"
+
+// ResourceMetadata describes this server to OAuth clients (RFC 9728), so
+// they can discover which authorization server issues its tokens
+type ResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers,omitempty"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported,omitempty"`
+	ResourceName           string   `json:"resource_name,omitempty"`
+}
+
+// MetadataURL returns the metadata URL of resource. A resource with a path
+// has its metadata under the well-known path followed by that path.
+func MetadataURL(resource string) string {
+	u, err := url.Parse(resource)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + MetadataPathFor(resource)
+}
+
+// MetadataPathFor returns the path MetadataURL points at on this server
+func MetadataPathFor(resource string) string {
+	u, err := url.Parse(resource)
+	if err != nil {
+		return MetadataPath
+	}
+	return MetadataPath + strings.TrimSuffix(u.Path, "/")
+}
+
+// Handler serves the metadata as JSON
+func (m ResourceMetadata) Handler() http.HandlerFunc {
+	body, _ := json.Marshal(m)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(body)
+	}
+}
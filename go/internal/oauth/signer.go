@@ -0,0 +1,125 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer signs access tokens with a private key
+type Signer struct {
+	kid    string
+	key    crypto.Signer
+	method jwt.SigningMethod
+}
+
+// NewSigner creates a signer with a new P-256 key
+func NewSigner() (*Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newSigner(key)
+}
+
+// LoadSigner reads a PEM private key (EC P-256 or RSA) from path. If the
+// file doesn't exist a new P-256 key is generated and written there, so
+// tokens stay valid across restarts.
+func LoadSigner(path string) (*Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		signer, err := NewSigner()
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(signer.key)
+		if err != nil {
+			return nil, err
+		}
+		block := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(path, block, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write signing key: %w", err)
+		}
+		return signer, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM")
+	}
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	return newSigner(key)
+}
+
+// newSigner picks the signing method for key and derives its key ID
+func newSigner(key interface{}) (*Signer, error) {
+	var method jwt.SigningMethod
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, errors.New("EC signing keys must use P-256")
+		}
+		method = jwt.SigningMethodES256
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+
+	signer := key.(crypto.Signer)
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return &Signer{
+		kid:    base64.RawURLEncoding.EncodeToString(sum[:12]),
+		key:    signer,
+		method: method,
+	}, nil
+}
+
+// Sign returns a signed JWT with claims
+func (s *Signer) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["kid"] = s.kid
+	token.Header["typ"] = "at+jwt"
+	return token.SignedString(s.key)
+}
+
+// JWKS returns the public key for publishing
+func (s *Signer) JWKS() JWKS {
+	jwk, _ := NewJWK(s.kid, s.key.Public()) // the key type was checked by newSigner
+	jwk.Alg = s.method.Alg()
+	return JWKS{Keys: []JWK{jwk}}
+}
+
+// KeySet returns a key set that verifies tokens from this signer
+func (s *Signer) KeySet() *KeySet {
+	return StaticKeySet(map[string]crypto.PublicKey{s.kid: s.key.Public()})
+}
@@ -0,0 +1,195 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxClients bounds dynamic registration, which anyone can call
+const maxClients = 10000
+
+// unusedClientTTL is how long a registered client is kept without ever
+// exchanging an authorization code, so abandoned or abusive registrations
+// don't fill the store
+const unusedClientTTL = time.Hour
+
+// Client is a client registered with the authorization server (RFC 7591)
+type Client struct {
+	ID           string
+	Name         string
+	RedirectURIs []string
+	// AuthMethod is "none" for public clients, or "client_secret_basic" or
+	// "client_secret_post"
+	AuthMethod string
+	// SecretHash is the SHA-256 of the client secret of confidential clients
+	SecretHash []byte
+	CreatedAt  time.Time
+
+	// granted is set once the client exchanged an authorization code; it
+	// is guarded by the store's mutex
+	granted bool
+}
+
+// grant is what an authorization code or refresh token stands for
+type grant struct {
+	ClientID string
+	User     string
+	Scopes   []string
+	Resource string
+	// RedirectURI and Challenge are only set on authorization codes
+	RedirectURI string
+	Challenge   string
+
+	ExpiresAt time.Time
+}
+
+// store keeps clients, authorization codes and refresh tokens in memory.
+// They are lost on restart; clients then register again and users sign in
+// again.
+type store struct {
+	mu            sync.Mutex
+	clients       map[string]*Client
+	codes         map[string]*grant
+	refreshTokens map[string]*grant
+	sweptAt       time.Time
+}
+
+func newStore() *store {
+	return &store{
+		clients:       make(map[string]*Client),
+		codes:         make(map[string]*grant),
+		refreshTokens: make(map[string]*grant),
+	}
+}
+
+// addClient registers a client
+func (s *store) addClient(client *Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+	if len(s.clients) >= maxClients {
+		// Make room from unused clients now rather than within a minute
+		s.sweptAt = time.Time{}
+		s.sweep()
+	}
+	if len(s.clients) >= maxClients {
+		return errors.New("too many registered clients")
+	}
+	s.clients[client.ID] = client
+	return nil
+}
+
+// client returns the client with the given ID
+func (s *store) client(id string) (*Client, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client, ok := s.clients[id]
+	return client, ok
+}
+
+// markGranted records that the client completed a grant, so it is kept
+func (s *store) markGranted(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if client, ok := s.clients[id]; ok {
+		client.granted = true
+	}
+}
+
+// saveCode stores an authorization code
+func (s *store) saveCode(code string, g *grant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+	s.codes[code] = g
+}
+
+// takeCode removes and returns an unexpired authorization code; codes are
+// single use
+func (s *store) takeCode(code string) (*grant, bool) {
+	return s.take(s.codes, code)
+}
+
+// saveRefreshToken stores a refresh token
+func (s *store) saveRefreshToken(token string, g *grant) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+	s.refreshTokens[token] = g
+}
+
+// refreshToken returns a copy of an unexpired refresh token's grant
+// without using the token up
+func (s *store) refreshToken(token string) (*grant, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.refreshTokens[token]
+	if !ok || time.Now().After(g.ExpiresAt) {
+		return nil, false
+	}
+	copied := *g
+	return &copied, true
+}
+
+// takeRefreshToken removes and returns an unexpired refresh token. Refresh
+// tokens are rotated, so each one is used once.
+func (s *store) takeRefreshToken(token string) (*grant, bool) {
+	return s.take(s.refreshTokens, token)
+}
+
+func (s *store) take(grants map[string]*grant, key string) (*grant, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := grants[key]
+	if !ok {
+		return nil, false
+	}
+	delete(grants, key)
+	if time.Now().After(g.ExpiresAt) {
+		return nil, false
+	}
+	return g, true
+}
+
+// sweep drops expired codes and refresh tokens, and clients that never
+// completed a grant within unusedClientTTL, at most once a minute. s.mu
+// must be held.
+func (s *store) sweep() {
+	now := time.Now()
+	if now.Sub(s.sweptAt) < time.Minute {
+		return
+	}
+	s.sweptAt = now
+
+	for _, grants := range []map[string]*grant{s.codes, s.refreshTokens} {
+		for key, g := range grants {
+			if now.After(g.ExpiresAt) {
+				delete(grants, key)
+			}
+		}
+	}
+	for id, client := range s.clients {
+		if !client.granted && now.Sub(client.CreatedAt) > unusedClientTTL {
+			delete(s.clients, id)
+		}
+	}
+}
+
+// randomToken returns a random URL-safe string with 256 bits of entropy
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
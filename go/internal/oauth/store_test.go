@@ -0,0 +1,66 @@
+package oauth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreSweep(t *testing.T) {
+	old := time.Now().Add(-unusedClientTTL - time.Minute)
+	tests := []struct {
+		name     string
+		client   Client
+		granted  bool
+		wantKept bool
+	}{
+		{"new", Client{ID: "new", CreatedAt: time.Now()}, false, true},
+		{"unused", Client{ID: "unused", CreatedAt: old}, false, false},
+		{"granted", Client{ID: "granted", CreatedAt: old}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore()
+			client := tt.client
+			if err := s.addClient(&client); err != nil {
+				t.Fatal(err)
+			}
+			if tt.granted {
+				s.markGranted(client.ID)
+			}
+
+			s.mu.Lock()
+			s.sweptAt = time.Time{}
+			s.sweep()
+			s.mu.Unlock()
+
+			if _, ok := s.client(client.ID); ok != tt.wantKept {
+				t.Errorf("client kept = %v, want %v", ok, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestStoreTake(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      bool
+	}{
+		{"valid", time.Now().Add(time.Minute), true},
+		{"expired", time.Now().Add(-time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore()
+			s.saveCode("code", &grant{ClientID: "client", ExpiresAt: tt.expiresAt})
+
+			if _, ok := s.takeCode("code"); ok != tt.want {
+				t.Errorf("takeCode() = %v, want %v", ok, tt.want)
+			}
+			// Codes are single use
+			if _, ok := s.takeCode("code"); ok {
+				t.Error("takeCode() succeeded twice")
+			}
+		})
+	}
+}
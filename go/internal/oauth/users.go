@@ -0,0 +1,126 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+)
+
+// User may sign in on the authorization server's consent page
+type User struct {
+	Name string `yaml:"name"`
+	// PasswordHash is a bcrypt hash, as printed by HashPassword
+	PasswordHash string `yaml:"password_hash"`
+	// Scopes the user may grant to clients; empty grants tools:call
+	Scopes []string `yaml:"scopes,omitempty"`
+}
+
+// Validate checks the user's fields
+func (u *User) Validate() error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+		return errors.New("password_hash must be a bcrypt hash")
+	}
+	for _, scope := range u.Scopes {
+		if scope != auth.ScopeToolsRead && scope != auth.ScopeToolsCall {
+			return fmt.Errorf("unknown scope %q (expected %s or %s)", scope, auth.ScopeToolsRead, auth.ScopeToolsCall)
+		}
+	}
+	return nil
+}
+
+// grantable returns the scopes the user may grant
+func (u *User) grantable() []string {
+	if len(u.Scopes) == 0 {
+		return []string{auth.ScopeToolsCall}
+	}
+	return u.Scopes
+}
+
+// HashPassword returns the stored form of a user's password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// dummyHash is compared against when a user name is unknown, so the
+// response time doesn't reveal which names exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+
+// checkPassword returns the named user if password is theirs
+func checkPassword(users map[string]User, name, password string) (*User, bool) {
+	user, ok := users[name]
+	hash := dummyHash
+	if ok {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil || !ok {
+		return nil, false
+	}
+	return &user, true
+}
+
+// Failed sign-ins allowed per user name and per client IP. Each failure
+// takes a token; while a bucket is empty, sign-ins are refused without
+// checking the password.
+var (
+	userSignInLimit = ratelimit.Limit{PerMinute: 1, Burst: 5}
+	ipSignInLimit   = ratelimit.Limit{PerMinute: 10, Burst: 20}
+)
+
+// signInThrottle slows down password guessing on the consent page
+type signInThrottle struct {
+	limiter *ratelimit.Limiter
+}
+
+// wait returns how long until user may try again from ip, or 0 if they may
+// try now
+func (t *signInThrottle) wait(user, ip string) time.Duration {
+	var wait time.Duration
+	for _, result := range []ratelimit.Result{
+		t.limiter.Peek("user:"+user, userSignInLimit),
+		t.limiter.Peek("ip:"+ip, ipSignInLimit),
+	} {
+		if !result.Allowed && result.RetryAfter > wait {
+			wait = result.RetryAfter
+		}
+	}
+	return wait
+}
+
+// failed records a failed sign-in by user from ip
+func (t *signInThrottle) failed(user, ip string) {
+	t.limiter.Allow("user:"+user, userSignInLimit)
+	t.limiter.Allow("ip:"+ip, ipSignInLimit)
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the client IP the sign-in
+// throttle counts failures by, e.g. as resolved by a proxy-aware router.
+// Without it the request's remote address is used.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// clientIP returns the IP of the client sending r
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,83 @@
+package oauth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/ratelimit"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := map[string]User{"alice": {PasswordHash: hash}}
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		want     bool
+	}{
+		{"correct", "alice", "secret", true},
+		{"wrong password", "alice", "wrong", false},
+		{"empty password", "alice", "", false},
+		{"unknown user", "bob", "secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := checkPassword(users, tt.user, tt.password); got != tt.want {
+				t.Errorf("checkPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignInThrottle(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []string // "user ip" of each failed sign-in
+		user, ip string
+		want     bool // whether the sign-in is throttled
+	}{
+		{"no failures", nil, "alice", "10.0.0.1", false},
+		{"below the user burst", repeat("alice 10.0.0.1", userSignInLimit.Burst-1), "alice", "10.0.0.1", false},
+		{"user burst used up", repeat("alice 10.0.0.1", userSignInLimit.Burst), "alice", "10.0.0.1", true},
+		{"user throttled from any IP", repeat("alice 10.0.0.1", userSignInLimit.Burst), "alice", "10.0.0.2", true},
+		{"other users unaffected", repeat("alice 10.0.0.1", userSignInLimit.Burst), "bob", "10.0.0.2", false},
+		{"IP burst used up", spread(ipSignInLimit.Burst, "10.0.0.1"), "carol", "10.0.0.1", true},
+		{"other IPs unaffected", spread(ipSignInLimit.Burst, "10.0.0.1"), "carol", "10.0.0.2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle := &signInThrottle{limiter: ratelimit.New()}
+			for _, f := range tt.failures {
+				user, ip, _ := strings.Cut(f, " ")
+				throttle.failed(user, ip)
+			}
+			if got := throttle.wait(tt.user, tt.ip) > 0; got != tt.want {
+				t.Errorf("throttled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// repeat returns n copies of failure
+func repeat(failure string, n int) []string {
+	failures := make([]string, n)
+	for i := range failures {
+		failures[i] = failure
+	}
+	return failures
+}
+
+// spread returns n failures from ip, each by a different user
+func spread(n int, ip string) []string {
+	failures := make([]string, n)
+	for i := range failures {
+		failures[i] = fmt.Sprintf("user%d %s", i, ip)
+	}
+	return failures
+}
@@ -0,0 +1,147 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+)
+
+// signingMethods are the JWT algorithms accepted; "none" and HMAC are not,
+// since the server only holds the issuer's public keys
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// ValidatorOptions configures access token validation
+type ValidatorOptions struct {
+	// Issuer is the required iss claim
+	Issuer string
+	// Audience is the required aud claim, normally this server's resource URL
+	Audience string
+	// Keys verifies token signatures
+	Keys *KeySet
+	// NameClaim is the claim used as the identity name; defaults to "sub"
+	NameClaim string
+	// ScopeMap renames token scopes to the server's, e.g. "mcp.read" to
+	// "tools:read". Unmapped scopes are dropped unless they already are
+	// tools:read or tools:call, so a token can never grant admin.
+	ScopeMap map[string]string
+	// ClockSkew is the leeway allowed on exp, nbf and iat
+	ClockSkew time.Duration
+}
+
+// Validator validates JWT access tokens and turns them into identities
+type Validator struct {
+	options ValidatorOptions
+	parser  *jwt.Parser
+}
+
+// NewValidator creates a token validator
+func NewValidator(options ValidatorOptions) *Validator {
+	if options.NameClaim == "" {
+		options.NameClaim = "sub"
+	}
+	return &Validator{
+		options: options,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(signingMethods),
+			jwt.WithIssuer(options.Issuer),
+			jwt.WithAudience(options.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+			jwt.WithLeeway(options.ClockSkew),
+		),
+	}
+}
+
+// LooksLikeToken reports whether a bearer credential is a JWT rather than
+// an API key
+func LooksLikeToken(credential string) bool {
+	return strings.Count(credential, ".") == 2
+}
+
+// Validate checks the token's signature, issuer, audience and lifetime and
+// returns the caller's identity. The token's claims are kept on the
+// identity for tools to use.
+func (v *Validator) Validate(ctx context.Context, token string) (*auth.Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.options.Keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := claims[v.options.NameClaim].(string)
+	if name == "" {
+		return nil, fmt.Errorf("token has no %s claim", v.options.NameClaim)
+	}
+
+	return &auth.Identity{
+		Name:   name,
+		Method: auth.MethodOAuth,
+		Scopes: v.scopes(claims),
+		Claims: claims,
+	}, nil
+}
+
+// scopes reads the token's scopes from the "scope" claim (a space-separated
+// string, RFC 9068) or the "scp" claim (a list or string) and maps them,
+// keeping only the tool scopes
+func (v *Validator) scopes(claims jwt.MapClaims) []string {
+	var raw []string
+	if scope, ok := claims["scope"].(string); ok {
+		raw = strings.Fields(scope)
+	}
+	switch scp := claims["scp"].(type) {
+	case string:
+		raw = append(raw, strings.Fields(scp)...)
+	case []interface{}:
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, s := range raw {
+		if mapped, ok := v.options.ScopeMap[s]; ok {
+			s = mapped
+		}
+		if s != auth.ScopeToolsRead && s != auth.ScopeToolsCall {
+			continue
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// Describe returns a short description of a validation error suitable for
+// the error_description of a WWW-Authenticate header
+func Describe(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "The access token expired"
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "The access token is for another resource"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return "The access token is from an untrusted issuer"
+	default:
+		return "The access token is invalid"
+	}
+}
@@ -0,0 +1,107 @@
+package oauth
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+)
+
+func TestValidatorScopes(t *testing.T) {
+	v := NewValidator(ValidatorOptions{
+		ScopeMap: map[string]string{"mcp.read": auth.ScopeToolsRead, "mcp.admin": auth.ScopeAdmin},
+	})
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   []string
+	}{
+		{"none", jwt.MapClaims{}, nil},
+		{"scope claim", jwt.MapClaims{"scope": "tools:read tools:call"}, []string{auth.ScopeToolsRead, auth.ScopeToolsCall}},
+		{"scp list", jwt.MapClaims{"scp": []interface{}{"tools:call", 1}}, []string{auth.ScopeToolsCall}},
+		{"scp string", jwt.MapClaims{"scp": "tools:read"}, []string{auth.ScopeToolsRead}},
+		{"mapped", jwt.MapClaims{"scope": "mcp.read"}, []string{auth.ScopeToolsRead}},
+		{"duplicates", jwt.MapClaims{"scope": "mcp.read tools:read", "scp": "tools:read"}, []string{auth.ScopeToolsRead}},
+		{"unmapped dropped", jwt.MapClaims{"scope": "openid profile tools:read"}, []string{auth.ScopeToolsRead}},
+		{"admin never granted", jwt.MapClaims{"scope": "admin"}, nil},
+		{"admin never mapped", jwt.MapClaims{"scope": "mcp.admin"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.scopes(tt.claims); !slices.Equal(got, tt.want) {
+				t.Errorf("scopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorValidate(t *testing.T) {
+	signer, err := NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewValidator(ValidatorOptions{
+		Issuer:   "https://issuer.example.com",
+		Audience: "https://mcp.example.com",
+		Keys:     signer.KeySet(),
+	})
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   "https://issuer.example.com",
+			"aud":   "https://mcp.example.com",
+			"sub":   "alice",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"scope": "tools:call",
+		}
+	}
+	with := func(key string, value interface{}) jwt.MapClaims {
+		claims := valid()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		signer  *Signer
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{"valid", signer, valid(), false},
+		{"unknown key", other, valid(), true},
+		{"wrong issuer", signer, with("iss", "https://evil.example.com"), true},
+		{"wrong audience", signer, with("aud", "https://other.example.com"), true},
+		{"expired", signer, with("exp", now.Add(-time.Minute).Unix()), true},
+		{"no expiry", signer, with("exp", nil), true},
+		{"no subject", signer, with("sub", nil), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.signer.Sign(tt.claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			identity, err := v.Validate(context.Background(), token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (identity.Name != "alice" || identity.Method != auth.MethodOAuth || !identity.HasScope(auth.ScopeToolsCall)) {
+				t.Errorf("Validate() = %+v", identity)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package ratelimit
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Rejections counts HTTP requests ("http") and tool calls ("tool") refused
+// by rate limits. Register it with the application's Prometheus registry.
+var Rejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "mcp_rate_limit_rejections_total",
+	Help: "Requests and tool calls refused by rate limits, by layer.",
+}, []string{"layer"})
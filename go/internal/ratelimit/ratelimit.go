@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+)
+
+// Limit is a token bucket's size and refill rate
+type Limit struct {
+	// PerMinute is the sustained rate
+	PerMinute int
+	// Burst is how many tokens the bucket holds; 0 uses PerMinute
+	Burst int
+}
+
+// capacity returns the bucket size
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.PerMinute)
+}
+
+// rate returns the refill rate in tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// Result describes the bucket after taking a token
+type Result struct {
+	Allowed bool
+	// Limit is the bucket size
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token, if not Allowed
+	RetryAfter time.Duration
+}
+
+// bucket is a token bucket, refilled lazily when it is used
+type bucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64
+	updated  time.Time
+}
+
+// refill adds the tokens accrued since the last update
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+	b.updated = now
+}
+
+// sweepInterval is how often full buckets are dropped
+const sweepInterval = time.Minute
+
+// Limiter keeps a token bucket per key. A full bucket behaves like a
+// missing one, so buckets that have refilled are evicted and memory only
+// grows with the number of recently active keys.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+// New creates a limiter
+func New() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from key's bucket. The limit is passed on every call
+// so it can change while the server runs; a bucket keeps its tokens when
+// it does.
+func (l *Limiter) Allow(key string, limit Limit) Result {
+	return l.take(key, limit, 1)
+}
+
+// Peek reports what Allow would return without taking a token, e.g. to
+// refuse a request whose failures are what the bucket counts
+func (l *Limiter) Peek(key string, limit Limit) Result {
+	return l.take(key, limit, 0)
+}
+
+// take takes n tokens from key's bucket if it holds at least one
+func (l *Limiter) take(key string, limit Limit, n float64) Result {
+	now := time.Now()
+	capacity, rate := limit.capacity(), limit.rate()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.sweptAt) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.capacity, b.rate = capacity, rate
+	b.refill(now)
+
+	result := Result{Limit: int(capacity)}
+	if b.tokens >= 1 {
+		b.tokens -= n
+		result.Allowed = true
+	} else if rate > 0 {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	if rate > 0 {
+		result.Reset = seconds((capacity - b.tokens) / rate)
+	}
+	return result
+}
+
+// Bucket is the state of a key's token bucket
+type Bucket struct {
+	Key string
+	// Tokens is how many tokens are left, refilled to now
+	Tokens   float64
+	Capacity float64
+}
+
+// Buckets returns the buckets that are not full, by key
+func (l *Limiter) Buckets() []Bucket {
+	now := time.Now()
+
+	l.mu.Lock()
+	buckets := make([]Bucket, 0, len(l.buckets))
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens < b.capacity {
+			buckets = append(buckets, Bucket{Key: key, Tokens: b.tokens, Capacity: b.capacity})
+		}
+	}
+	l.mu.Unlock()
+
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Key < buckets[j].Key })
+	return buckets
+}
+
+// sweep drops buckets that have refilled. l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	l.sweptAt = now
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= b.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// seconds converts a number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Principal returns the bucket key of an authenticated caller, or "" for
+// anonymous ones
+func Principal(identity *auth.Identity) string {
+	if identity == nil {
+		return ""
+	}
+	return identity.Method + ":" + identity.Name
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       Limit
+		calls       int
+		wantAllowed int
+	}{
+		{"within the limit", Limit{PerMinute: 10}, 5, 5},
+		{"up to the limit", Limit{PerMinute: 10}, 10, 10},
+		{"over the limit", Limit{PerMinute: 10}, 15, 10},
+		{"burst below the rate", Limit{PerMinute: 60, Burst: 3}, 5, 3},
+		{"burst above the rate", Limit{PerMinute: 1, Burst: 4}, 6, 4},
+		{"no rate", Limit{Burst: 2}, 4, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New()
+			allowed := 0
+			var last Result
+			for i := 0; i < tt.calls; i++ {
+				last = l.Allow("key", tt.limit)
+				if last.Allowed {
+					allowed++
+				}
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d of %d calls, want %d", allowed, tt.calls, tt.wantAllowed)
+			}
+			if last.Limit != int(tt.limit.capacity()) {
+				t.Errorf("Limit = %d, want %v", last.Limit, tt.limit.capacity())
+			}
+			if !last.Allowed && tt.limit.PerMinute > 0 && (last.RetryAfter <= 0 || last.RetryAfter > time.Minute/time.Duration(tt.limit.PerMinute)) {
+				t.Errorf("RetryAfter = %v", last.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestLimiterKeys(t *testing.T) {
+	l := New()
+	limit := Limit{PerMinute: 1}
+	if !l.Allow("alice", limit).Allowed {
+		t.Fatal("first call refused")
+	}
+	if l.Allow("alice", limit).Allowed {
+		t.Error("second call of alice allowed")
+	}
+	if !l.Allow("bob", limit).Allowed {
+		t.Error("bob limited by alice's calls")
+	}
+}
+
+func TestLimiterPeek(t *testing.T) {
+	l := New()
+	limit := Limit{PerMinute: 60, Burst: 2}
+	for i := 0; i < 5; i++ {
+		if result := l.Peek("key", limit); !result.Allowed || result.Remaining != 2 {
+			t.Fatalf("Peek() = %+v, want allowed with 2 remaining", result)
+		}
+	}
+	l.Allow("key", limit)
+	l.Allow("key", limit)
+	if result := l.Peek("key", limit); result.Allowed || result.RetryAfter <= 0 {
+		t.Errorf("Peek() on an empty bucket = %+v", result)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l := New()
+	limit := Limit{PerMinute: 60, Burst: 1}
+	l.Allow("key", limit)
+	if l.Allow("key", limit).Allowed {
+		t.Fatal("empty bucket allowed a call")
+	}
+
+	// A second later the bucket has a token again
+	l.mu.Lock()
+	l.buckets["key"].updated = l.buckets["key"].updated.Add(-time.Second)
+	l.mu.Unlock()
+	if !l.Allow("key", limit).Allowed {
+		t.Error("refilled bucket refused a call")
+	}
+}
+
+func TestLimiterSweep(t *testing.T) {
+	l := New()
+	limit := Limit{PerMinute: 60}
+	l.Allow("idle", limit)
+	l.Allow("busy", limit)
+
+	l.mu.Lock()
+	l.buckets["idle"].updated = time.Now().Add(-time.Minute)
+	l.sweep(time.Now())
+	_, idle := l.buckets["idle"]
+	_, busy := l.buckets["busy"]
+	l.mu.Unlock()
+
+	if idle {
+		t.Error("refilled bucket kept")
+	}
+	if !busy {
+		t.Error("bucket in use dropped")
+	}
+	if buckets := l.Buckets(); len(buckets) != 1 || buckets[0].Key != "busy" {
+		t.Errorf("Buckets() = %+v", buckets)
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	tests := []struct {
+		identity *auth.Identity
+		want     string
+	}{
+		{nil, ""},
+		{&auth.Identity{Name: "ci", Method: auth.MethodAPIKey}, "api_key:ci"},
+		{&auth.Identity{Name: "ci", Method: auth.MethodOAuth}, "oauth:ci"},
+	}
+	for _, tt := range tests {
+		if got := Principal(tt.identity); got != tt.want {
+			t.Errorf("Principal(%+v) = %q, want %q", tt.identity, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+// ErrorCodeRateLimited is the JSON-RPC error code of a rate-limited tool call
+const ErrorCodeRateLimited = -32003
+
+// ToolKey returns the bucket key of a caller's calls of a tool
+func ToolKey(principal, tool string) string {
+	return principal + "\x00" + tool
+}
+
+// SplitKey returns the caller and tool of a bucket key; tool is empty for
+// the buckets of HTTP requests
+func SplitKey(key string) (principal, tool string) {
+	principal, tool, _ = strings.Cut(key, "\x00")
+	return principal, tool
+}
+
+// Tools returns tool middleware limiting how often each caller may call
+// each tool. limits maps tool names to calls per minute, with "*" applying
+// to tools not listed; it is looked up on every call so limits can be
+// reloaded. Tools without a limit are not counted.
+func Tools(limiter *Limiter, limits func() map[string]int) mcp.ToolMiddleware {
+	return func(next mcp.ToolCallFunc) mcp.ToolCallFunc {
+		return func(ctx context.Context, tool mcp.Tool, args map[string]interface{}) (interface{}, error) {
+			toolLimits := limits()
+			perMinute, ok := toolLimits[tool.Name]
+			if !ok {
+				perMinute, ok = toolLimits["*"]
+			}
+			if !ok || perMinute <= 0 {
+				return next(ctx, tool, args)
+			}
+
+			principal := Principal(auth.IdentityFromContext(ctx))
+			result := limiter.Allow(ToolKey(principal, tool.Name), Limit{PerMinute: perMinute})
+			if !result.Allowed {
+				Rejections.WithLabelValues("tool").Inc()
+				return nil, &mcp.JSONRPCError{
+					Code:    ErrorCodeRateLimited,
+					Kind:    "rate_limited",
+					Message: fmt.Sprintf("Rate limit exceeded for tool %s", tool.Name),
+					Data: map[string]interface{}{
+						"limit":      result.Limit,
+						"retryAfter": int(math.Ceil(result.RetryAfter.Seconds())),
+					},
+				}
+			}
+			return next(ctx, tool, args)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+func TestSplitKey(t *testing.T) {
+	tests := []struct {
+		key           string
+		wantPrincipal string
+		wantTool      string
+	}{
+		{ToolKey("api_key:ci", "echo"), "api_key:ci", "echo"},
+		{ToolKey("", "echo"), "", "echo"},
+		{"api_key:ci", "api_key:ci", ""},
+	}
+	for _, tt := range tests {
+		principal, tool := SplitKey(tt.key)
+		if principal != tt.wantPrincipal || tool != tt.wantTool {
+			t.Errorf("SplitKey(%q) = %q, %q, want %q, %q", tt.key, principal, tool, tt.wantPrincipal, tt.wantTool)
+		}
+	}
+}
+
+func TestTools(t *testing.T) {
+	limits := map[string]int{"storage_set": 2, "*": 3, "echo": 0}
+	tests := []struct {
+		name        string
+		tool        string
+		calls       int
+		wantAllowed int
+	}{
+		{"listed", "storage_set", 4, 2},
+		{"default", "calculator", 5, 3},
+		{"disabled", "echo", 10, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call := Tools(New(), func() map[string]int { return limits })(
+				func(ctx context.Context, tool mcp.Tool, args map[string]interface{}) (interface{}, error) {
+					return "ok", nil
+				})
+			ctx := auth.WithIdentity(context.Background(), &auth.Identity{Name: "ci", Method: auth.MethodAPIKey})
+
+			allowed := 0
+			for i := 0; i < tt.calls; i++ {
+				_, err := call(ctx, mcp.Tool{Name: tt.tool}, nil)
+				var rpcErr *mcp.JSONRPCError
+				switch {
+				case err == nil:
+					allowed++
+				case !errors.As(err, &rpcErr) || rpcErr.Code != ErrorCodeRateLimited:
+					t.Fatalf("call error = %v", err)
+				}
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d of %d calls, want %d", allowed, tt.calls, tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+		// wantFile is whether the span is written to the trace file
+		wantFile bool
+	}{
+		{"default", "", false, false},
+		{"none", ExporterNone, false, false},
+		{"file", ExporterFile, false, true},
+		{"unknown", "jaeger", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := otel.GetTracerProvider()
+			t.Cleanup(func() {
+				if otel.GetTracerProvider() != previous {
+					otel.SetTracerProvider(previous)
+				}
+			})
+
+			file := filepath.Join(t.TempDir(), "traces.json")
+			shutdown, err := Setup(context.Background(), Options{
+				Exporter:    tt.exporter,
+				File:        file,
+				SampleRatio: 1,
+				ServiceName: "test",
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Setup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+			span.End()
+			if err := shutdown(context.Background()); err != nil {
+				t.Fatalf("shutdown error = %v", err)
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(string(data), `"Name":"test-span"`); got != tt.wantFile {
+				t.Errorf("span in trace file = %v, want %v\n%s", got, tt.wantFile, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+)
+
+// logger is the logger of the "tls" component
+var logger = logging.Component("tls")
+
+// Options configures server-side TLS
+type Options struct {
+	// CertFile and KeyFile hold the PEM server certificate chain and key
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile holds PEM CA certificates that client certificates are
+	// verified against. It is required for any ClientAuth other than "none".
+	ClientCAFile string
+
+	// ClientAuth is "none", "request" (verify a certificate if one is sent)
+	// or "require"
+	ClientAuth string
+
+	// ReloadInterval is how often the files are checked for changes; 0 disables reloading
+	ReloadInterval time.Duration
+}
+
+// ParseClientAuth converts a ClientAuth option to a tls.ClientAuthType
+func ParseClientAuth(value string) (tls.ClientAuthType, error) {
+	switch value {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("invalid client auth %q (expected none, request or require)", value)
+	}
+}
+
+// Reloader serves a TLS configuration built from certificate files and
+// rebuilds it whenever the files change, so renewed certificates and CA
+// bundles are picked up without a restart. Handshakes in progress keep the
+// configuration they started with.
+type Reloader struct {
+	options    Options
+	clientAuth tls.ClientAuthType
+
+	mu       sync.RWMutex
+	config   *tls.Config
+	modTimes map[string]time.Time
+}
+
+// New loads the configured files and returns a Reloader serving them
+func New(options Options) (*Reloader, error) {
+	if options.CertFile == "" || options.KeyFile == "" {
+		return nil, fmt.Errorf("both a certificate and a key file are required")
+	}
+
+	clientAuth, err := ParseClientAuth(options.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if clientAuth != tls.NoClientCert && options.ClientCAFile == "" {
+		return nil, fmt.Errorf("client auth %q requires a client CA file", options.ClientAuth)
+	}
+
+	r := &Reloader{options: options, clientAuth: clientAuth}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Config returns a tls.Config for an http.Server. It always hands out the
+// most recently loaded certificates.
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.config, nil
+		},
+	}
+}
+
+// Reload reads the files again and swaps in the new configuration. On
+// error the previous configuration stays in use.
+func (r *Reloader) Reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.options.CertFile, r.options.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.clientAuth,
+		// GetConfigForClient bypasses the server's own ALPN setup
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	if r.options.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.options.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.options.ClientCAFile)
+		}
+		config.ClientCAs = pool
+	}
+
+	r.mu.Lock()
+	r.config = config
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the configuration whenever one of the files changes, until
+// ctx is cancelled
+func (r *Reloader) Watch(ctx context.Context) {
+	if r.options.ReloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.options.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		if !r.changed() {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			// Files are often replaced one at a time; retry on the next tick
+			logger.Warn("TLS reload failed", "error", err)
+			continue
+		}
+		logger.Info("TLS certificates reloaded")
+	}
+}
+
+// changed reports whether any file's modification time differs from the
+// loaded configuration's
+func (r *Reloader) changed() bool {
+	modTimes, err := r.stat()
+	if err != nil {
+		return true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for path, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// stat returns the modification times of the configured files. os.Stat
+// follows symlinks, so Kubernetes-style secret volumes, which swap a
+// symlinked directory, are detected too.
+func (r *Reloader) stat() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, path := range []string{r.options.CertFile, r.options.KeyFile, r.options.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[path] = info.ModTime()
+	}
+	return modTimes, nil
+}
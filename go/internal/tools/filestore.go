@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore keeps the pairs in memory and writes them to a JSON file on
+// every change, so they survive restarts. The file is replaced atomically
+// and synced to disk before a change returns, so a crash leaves either the
+// old or the new contents.
+type FileStore struct {
+	path string
+
+	mu     sync.RWMutex
+	data   map[string]string
+	closed bool
+}
+
+// OpenFileStore loads the store at path; a missing file is an empty store
+func OpenFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, errors.New("storage file is required")
+	}
+	s := &FileStore{path: path, data: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse storage file %s: %w", path, err)
+	}
+	if s.data == nil {
+		s.data = make(map[string]string)
+	}
+	return s, nil
+}
+
+// Get returns the value of key and whether it exists
+func (s *FileStore) Get(ctx context.Context, key string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.data[key]
+	return value, ok, nil
+}
+
+// Set stores value under key and saves the file
+func (s *FileStore) Set(ctx context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+
+	previous, existed := s.data[key]
+	s.data[key] = value
+	if err := s.save(); err != nil {
+		// Keep memory and file in step
+		if existed {
+			s.data[key] = previous
+		} else {
+			delete(s.data, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes key, reporting whether it existed, and saves the file
+func (s *FileStore) Delete(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, ErrStoreClosed
+	}
+
+	previous, existed := s.data[key]
+	if !existed {
+		return false, nil
+	}
+	delete(s.data, key)
+	if err := s.save(); err != nil {
+		s.data[key] = previous
+		return false, err
+	}
+	return true, nil
+}
+
+// List returns every key, sorted
+func (s *FileStore) List(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedKeys(s.data, ""), nil
+}
+
+// Scan calls fn for each pair whose key starts with prefix
+func (s *FileStore) Scan(ctx context.Context, prefix string, fn func(key, value string) bool) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return scan(ctx, s.data, prefix, fn)
+}
+
+// Close refuses further changes. Every change is already on disk, so
+// there is nothing left to write.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Check reports whether changes can still be saved, i.e. the file's
+// directory is writable
+func (s *FileStore) Check(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".storage-check-*")
+	if err != nil {
+		return fmt.Errorf("storage file not writable: %w", err)
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	return ctx.Err()
+}
+
+// save replaces the file with the current pairs; s.mu must be held
+func (s *FileStore) save() error {
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to save storage: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".storage-*")
+	if err != nil {
+		return fmt.Errorf("failed to save storage: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save storage: %w", err)
+	}
+	// The contents must be on disk before the rename makes them the file
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save storage: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save storage: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save storage: %w", err)
+	}
+	// ... and the rename itself survives a crash once the directory is synced
+	if err := syncDir(filepath.Dir(s.path)); err != nil {
+		return fmt.Errorf("failed to save storage: %w", err)
+	}
+	return nil
+}
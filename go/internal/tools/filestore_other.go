@@ -0,0 +1,8 @@
+//go:build !unix
+
+package tools
+
+// syncDir is a no-op: directories can't be synced on this platform
+func syncDir(dir string) error {
+	return nil
+}
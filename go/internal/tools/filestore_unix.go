@@ -0,0 +1,15 @@
+//go:build unix
+
+package tools
+
+import "os"
+
+// syncDir flushes the directory entries of dir, e.g. a file renamed into it
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
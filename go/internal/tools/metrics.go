@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StorageMetrics are the Prometheus gauges of the storage tools. It is a
+// prometheus.Collector; register it with the application's registry.
+type StorageMetrics struct {
+	storage *StorageTools
+	keys    *prometheus.Desc
+	bytes   *prometheus.Desc
+}
+
+// NewStorageMetrics creates the metrics of storage
+func NewStorageMetrics(storage *StorageTools) *StorageMetrics {
+	return &StorageMetrics{
+		storage: storage,
+		keys:    prometheus.NewDesc("mcp_storage_keys", "Keys held by the storage tools.", nil, nil),
+		bytes:   prometheus.NewDesc("mcp_storage_bytes", "Size of the keys and values held by the storage tools.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *StorageMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.keys
+	ch <- m.bytes
+}
+
+// Collect implements prometheus.Collector. The store is scanned once per
+// scrape for both gauges; a failed scan leaves them out.
+func (m *StorageMetrics) Collect(ch chan<- prometheus.Metric) {
+	keys, bytes, err := m.storage.Stats(context.Background())
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(m.keys, prometheus.GaugeValue, float64(keys))
+	ch <- prometheus.MustNewConstMetric(m.bytes, prometheus.GaugeValue, float64(bytes))
+}
@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scanCounter counts the scans of a store
+type scanCounter struct {
+	Store
+	scans atomic.Int32
+}
+
+func (s *scanCounter) Scan(ctx context.Context, prefix string, fn func(key, value string) bool) error {
+	s.scans.Add(1)
+	return s.Store.Scan(ctx, prefix, fn)
+}
+
+func TestStorageMetrics(t *testing.T) {
+	store := &scanCounter{Store: NewMemoryStore()}
+	ctx := context.Background()
+	for _, kv := range [][2]string{{"user:1", "alice"}, {"team:1", "eng"}} {
+		if err := store.Set(ctx, kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewStorageMetrics(NewStorageTools(store)))
+	srv := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"mcp_storage_keys 2", "mcp_storage_bytes 20"} {
+		if !strings.Contains(string(body), want+"\n") {
+			t.Errorf("scrape is missing %q:\n%s", want, body)
+		}
+	}
+	// Both gauges come from a single pass over the store
+	if n := store.scans.Load(); n != 1 {
+		t.Errorf("scans per scrape = %d, want 1", n)
+	}
+}
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+// definition pairs a built-in tool with its handler
+type definition struct {
+	tool    mcp.Tool
+	handler mcp.ContextToolHandler
+}
+
+// Annotations shared by the built-in tools; API keys with the tools:read
+// scope may only call read-only tools
+var (
+	readOnly    = &mcp.ToolAnnotations{ReadOnlyHint: true}
+	destructive = true
+)
+
+// definitions lists the built-in tools in registration order, with the
+// storage tools backed by storage, which may be nil if only the names are
+// needed
+func definitions(storage *StorageTools) []definition {
+	return []definition{
+		// Calculator
+		{mcp.Tool{
+			Name:        "calculator",
+			Description: "Perform basic arithmetic operations (add, subtract, multiply, divide)",
+			InputSchema: GetCalculatorSchema(),
+			Annotations: readOnly,
+		}, plain(Calculator)},
+
+		// Storage operations
+		{mcp.Tool{
+			Name:        "storage_set",
+			Description: "Store a key-value pair",
+			InputSchema: GetStorageSetSchema(),
+			Annotations: &mcp.ToolAnnotations{IdempotentHint: true, DestructiveHint: &destructive},
+		}, storage.StorageSet},
+		{mcp.Tool{
+			Name:        "storage_get",
+			Description: "Retrieve a value by key",
+			InputSchema: GetStorageGetSchema(),
+			Annotations: readOnly,
+		}, storage.StorageGet},
+		{mcp.Tool{
+			Name:        "storage_delete",
+			Description: "Delete a key-value pair",
+			InputSchema: GetStorageDeleteSchema(),
+			Annotations: &mcp.ToolAnnotations{IdempotentHint: true, DestructiveHint: &destructive},
+		}, storage.StorageDelete},
+		{mcp.Tool{
+			Name:        "storage_list",
+			Description: "List all stored keys",
+			InputSchema: GetStorageListSchema(),
+			Annotations: readOnly,
+		}, storage.StorageList},
+
+		// System info
+		{mcp.Tool{
+			Name:        "system_info",
+			Description: "Get system information about the Go runtime",
+			InputSchema: GetSystemInfoSchema(),
+			Annotations: readOnly,
+		}, plain(SystemInfo)},
+
+		// Echo
+		{mcp.Tool{
+			Name:        "echo",
+			Description: "Echo back a message (for testing)",
+			InputSchema: GetEchoSchema(),
+			Annotations: readOnly,
+		}, plain(Echo)},
+	}
+}
+
+// plain adapts a handler that doesn't need the request context
+func plain(handler mcp.ToolHandler) mcp.ContextToolHandler {
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return handler(args)
+	}
+}
+
+// Names returns the names of the built-in tools
+func Names() []string {
+	defs := definitions(nil)
+	names := make([]string, len(defs))
+	for i, d := range defs {
+		names[i] = d.tool.Name
+	}
+	return names
+}
+
+// Register registers the built-in tools on server, with the storage tools
+// backed by storage. If enabled is non-empty, only the tools it names are
+// registered. It returns how many were registered.
+func Register(server *mcp.Server, storage *StorageTools, enabled []string) (int, error) {
+	if err := checkNames(enabled); err != nil {
+		return 0, err
+	}
+
+	want := enabledSet(enabled)
+	count := 0
+	for _, d := range definitions(storage) {
+		if want[d.tool.Name] {
+			server.RegisterToolContext(d.tool, d.handler)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Update changes the registered built-in tools from the previous enabled
+// list to a new one. Tools enabled in both are left alone, so clients are
+// only notified of actual changes.
+func Update(server *mcp.Server, storage *StorageTools, previous, enabled []string) error {
+	if err := checkNames(enabled); err != nil {
+		return err
+	}
+
+	was, want := enabledSet(previous), enabledSet(enabled)
+	for _, d := range definitions(storage) {
+		switch {
+		case want[d.tool.Name] && !was[d.tool.Name]:
+			server.RegisterToolContext(d.tool, d.handler)
+		case was[d.tool.Name] && !want[d.tool.Name]:
+			server.UnregisterTool(d.tool.Name)
+		}
+	}
+	return nil
+}
+
+// enabledSet returns the names of the enabled tools; an empty list enables all
+func enabledSet(enabled []string) map[string]bool {
+	set := make(map[string]bool)
+	if len(enabled) == 0 {
+		for _, d := range definitions(nil) {
+			set[d.tool.Name] = true
+		}
+		return set
+	}
+	for _, name := range enabled {
+		set[name] = true
+	}
+	return set
+}
+
+// checkNames reports the first name that isn't a built-in tool
+func checkNames(names []string) error {
+	known := enabledSet(nil)
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("unknown tool %q", name)
+		}
+	}
+	return nil
+}
@@ -1,17 +1,24 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
-)
 
-// In-memory storage
-var (
-	storage      = make(map[string]string)
-	storageMutex = &sync.RWMutex{}
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/health"
 )
 
+// StorageTools are the storage_* tools, built around the store that holds
+// their data
+type StorageTools struct {
+	store Store
+}
+
+// NewStorageTools creates the storage tools for store
+func NewStorageTools(store Store) *StorageTools {
+	return &StorageTools{store: store}
+}
+
 // StorageSetInput represents input for storage_set
 type StorageSetInput struct {
 	Key   string `json:"key"`
@@ -29,7 +36,7 @@ type StorageDeleteInput struct {
 }
 
 // StorageSet stores a key-value pair
-func StorageSet(args map[string]interface{}) (interface{}, error) {
+func (t *StorageTools) StorageSet(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	argBytes, err := json.Marshal(args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal arguments: %w", err)
@@ -44,10 +51,9 @@ func StorageSet(args map[string]interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("key is required")
 	}
 
-	storageMutex.Lock()
-	defer storageMutex.Unlock()
-
-	storage[input.Key] = input.Value
+	if err := t.store.Set(ctx, input.Key, input.Value); err != nil {
+		return nil, err
+	}
 
 	return map[string]interface{}{
 		"success": true,
@@ -56,7 +62,7 @@ func StorageSet(args map[string]interface{}) (interface{}, error) {
 }
 
 // StorageGet retrieves a value by key
-func StorageGet(args map[string]interface{}) (interface{}, error) {
+func (t *StorageTools) StorageGet(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	argBytes, err := json.Marshal(args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal arguments: %w", err)
@@ -71,10 +77,10 @@ func StorageGet(args map[string]interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("key is required")
 	}
 
-	storageMutex.RLock()
-	defer storageMutex.RUnlock()
-
-	value, exists := storage[input.Key]
+	value, exists, err := t.store.Get(ctx, input.Key)
+	if err != nil {
+		return nil, err
+	}
 	if !exists {
 		return map[string]interface{}{
 			"found": false,
@@ -90,7 +96,7 @@ func StorageGet(args map[string]interface{}) (interface{}, error) {
 }
 
 // StorageDelete deletes a key-value pair
-func StorageDelete(args map[string]interface{}) (interface{}, error) {
+func (t *StorageTools) StorageDelete(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	argBytes, err := json.Marshal(args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal arguments: %w", err)
@@ -105,10 +111,10 @@ func StorageDelete(args map[string]interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("key is required")
 	}
 
-	storageMutex.Lock()
-	defer storageMutex.Unlock()
-
-	_, exists := storage[input.Key]
+	exists, err := t.store.Delete(ctx, input.Key)
+	if err != nil {
+		return nil, err
+	}
 	if !exists {
 		return map[string]interface{}{
 			"success": false,
@@ -116,8 +122,6 @@ func StorageDelete(args map[string]interface{}) (interface{}, error) {
 		}, nil
 	}
 
-	delete(storage, input.Key)
-
 	return map[string]interface{}{
 		"success": true,
 		"message": fmt.Sprintf("Deleted key '%s'", input.Key),
@@ -125,13 +129,10 @@ func StorageDelete(args map[string]interface{}) (interface{}, error) {
 }
 
 // StorageList lists all stored keys
-func StorageList(args map[string]interface{}) (interface{}, error) {
-	storageMutex.RLock()
-	defer storageMutex.RUnlock()
-
-	keys := make([]string, 0, len(storage))
-	for key := range storage {
-		keys = append(keys, key)
+func (t *StorageTools) StorageList(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	keys, err := t.store.List(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	return map[string]interface{}{
@@ -140,6 +141,27 @@ func StorageList(args map[string]interface{}) (interface{}, error) {
 	}, nil
 }
 
+// Stats returns the number of stored keys and the total size of their
+// keys and values in bytes
+func (t *StorageTools) Stats(ctx context.Context) (keys, bytes int, err error) {
+	err = t.store.Scan(ctx, "", func(key, value string) bool {
+		keys++
+		bytes += len(key) + len(value)
+		return true
+	})
+	return keys, bytes, err
+}
+
+// Check reports whether the store works, using its own check if it has
+// one and a read otherwise
+func (t *StorageTools) Check(ctx context.Context) error {
+	if checker, ok := t.store.(health.Checker); ok {
+		return checker.Check(ctx)
+	}
+	_, _, err := t.store.Get(ctx, "")
+	return err
+}
+
 // GetStorageSetSchema returns the JSON schema for storage_set
 func GetStorageSetSchema() map[string]interface{} {
 	return map[string]interface{}{
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store is where the storage tools keep their key-value pairs.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value of key and whether it exists
+	Get(ctx context.Context, key string) (string, bool, error)
+	// Set stores value under key, replacing any previous value
+	Set(ctx context.Context, key, value string) error
+	// Delete removes key, reporting whether it existed
+	Delete(ctx context.Context, key string) (bool, error)
+	// List returns every key, sorted
+	List(ctx context.Context) ([]string, error)
+	// Scan calls fn for each pair whose key starts with prefix, in key
+	// order, until fn returns false
+	Scan(ctx context.Context, prefix string, fn func(key, value string) bool) error
+	// Close flushes the pairs to durable storage, if any; changes fail
+	// with ErrStoreClosed afterwards
+	Close() error
+}
+
+// ErrStoreClosed is returned by changes made after Close
+var ErrStoreClosed = errors.New("storage is closed")
+
+// MemoryStore keeps the pairs in memory; they are lost on restart
+type MemoryStore struct {
+	mu     sync.RWMutex
+	data   map[string]string
+	closed bool
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string]string)}
+}
+
+// Get returns the value of key and whether it exists
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.data[key]
+	return value, ok, nil
+}
+
+// Set stores value under key
+func (s *MemoryStore) Set(ctx context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	s.data[key] = value
+	return nil
+}
+
+// Delete removes key, reporting whether it existed
+func (s *MemoryStore) Delete(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, ErrStoreClosed
+	}
+	_, ok := s.data[key]
+	delete(s.data, key)
+	return ok, nil
+}
+
+// List returns every key, sorted
+func (s *MemoryStore) List(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return sortedKeys(s.data, ""), nil
+}
+
+// Scan calls fn for each pair whose key starts with prefix
+func (s *MemoryStore) Scan(ctx context.Context, prefix string, fn func(key, value string) bool) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return scan(ctx, s.data, prefix, fn)
+}
+
+// Close refuses further changes; there is nothing to flush
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Check reports whether the store answers. It blocks while the store is
+// locked, which the health check's timeout turns into a failure.
+func (s *MemoryStore) Check(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return ctx.Err()
+}
+
+// sortedKeys returns the keys of data starting with prefix, sorted
+func sortedKeys(data map[string]string, prefix string) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scan calls fn for the pairs of data whose key starts with prefix, in key
+// order, stopping early if fn returns false or ctx ends
+func scan(ctx context.Context, data map[string]string, prefix string, fn func(key, value string) bool) error {
+	for _, key := range sortedKeys(data, prefix) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !fn(key, data[key]) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// stores returns a new store of every kind
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	file, err := OpenFileStore(filepath.Join(t.TempDir(), "storage.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]Store{"memory": NewMemoryStore(), "file": file}
+}
+
+func TestStore(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, kv := range [][2]string{{"user:1", "alice"}, {"user:2", "bob"}, {"team:1", "eng"}, {"user:1", "carol"}} {
+				if err := store.Set(ctx, kv[0], kv[1]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tests := []struct {
+				key       string
+				wantValue string
+				wantOK    bool
+			}{
+				{"user:1", "carol", true},
+				{"team:1", "eng", true},
+				{"user:3", "", false},
+			}
+			for _, tt := range tests {
+				value, ok, err := store.Get(ctx, tt.key)
+				if err != nil || value != tt.wantValue || ok != tt.wantOK {
+					t.Errorf("Get(%q) = %q, %v, %v, want %q, %v", tt.key, value, ok, err, tt.wantValue, tt.wantOK)
+				}
+			}
+
+			if keys, err := store.List(ctx); err != nil || !slices.Equal(keys, []string{"team:1", "user:1", "user:2"}) {
+				t.Errorf("List() = %v, %v", keys, err)
+			}
+
+			var scanned []string
+			store.Scan(ctx, "user:", func(key, value string) bool {
+				scanned = append(scanned, key+"="+value)
+				return true
+			})
+			if !slices.Equal(scanned, []string{"user:1=carol", "user:2=bob"}) {
+				t.Errorf("Scan(user:) = %v", scanned)
+			}
+			scanned = nil
+			store.Scan(ctx, "", func(key, value string) bool {
+				scanned = append(scanned, key)
+				return false
+			})
+			if len(scanned) != 1 {
+				t.Errorf("Scan() didn't stop: %v", scanned)
+			}
+
+			if ok, err := store.Delete(ctx, "user:2"); !ok || err != nil {
+				t.Errorf("Delete(user:2) = %v, %v", ok, err)
+			}
+			if ok, err := store.Delete(ctx, "user:2"); ok || err != nil {
+				t.Errorf("Delete(user:2) again = %v, %v", ok, err)
+			}
+
+			// Reads keep working after Close; changes fail
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Set(ctx, "user:3", "dave"); !errors.Is(err, ErrStoreClosed) {
+				t.Errorf("Set() after Close error = %v, want %v", err, ErrStoreClosed)
+			}
+			if _, err := store.Delete(ctx, "user:1"); !errors.Is(err, ErrStoreClosed) {
+				t.Errorf("Delete() after Close error = %v, want %v", err, ErrStoreClosed)
+			}
+			if value, ok, _ := store.Get(ctx, "user:1"); !ok || value != "carol" {
+				t.Errorf("Get() after Close = %q, %v", value, ok)
+			}
+		})
+	}
+}
+
+func TestFileStorePersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.json")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set(ctx, "a", "1")
+	store.Set(ctx, "b", "2")
+	store.Delete(ctx, "a")
+	store.Close()
+
+	reopened, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := reopened.List(ctx); !slices.Equal(keys, []string{"b"}) {
+		t.Errorf("reopened store holds %v, want [b]", keys)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want 1", len(entries))
+	}
+}
+
+func TestFileStoreSaveFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenFileStore(filepath.Join(dir, "storage.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "kept", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without its directory the file can't be saved, and memory must not
+	// get ahead of it
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "lost", "2"); err == nil {
+		t.Fatal("Set() succeeded without a directory")
+	}
+	if _, ok, _ := store.Get(ctx, "lost"); ok {
+		t.Error("failed Set() kept the value")
+	}
+	if ok, err := store.Delete(ctx, "kept"); err == nil || ok {
+		t.Fatalf("Delete() = %v, %v, want an error", ok, err)
+	}
+	if _, ok, _ := store.Get(ctx, "kept"); !ok {
+		t.Error("failed Delete() removed the value")
+	}
+	if err := store.Check(ctx); err == nil {
+		t.Error("Check() reported a store that can't save as healthy")
+	}
+}
+
+func TestOpenFileStore(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  bool
+		wantKeys []string
+	}{
+		{"missing", "", false, nil},
+		{"empty object", "{}", false, nil},
+		{"null", "null", false, nil},
+		{"pairs", `{"b":"2","a":"1"}`, false, []string{"a", "b"}},
+		{"not JSON", "a=1", true, nil},
+		{"not strings", `{"a":1}`, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if tt.contents != "" {
+				if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store, err := OpenFileStore(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OpenFileStore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			keys, _ := store.List(context.Background())
+			if len(keys) != len(tt.wantKeys) || (len(keys) > 0 && !slices.Equal(keys, tt.wantKeys)) {
+				t.Errorf("List() = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+// ErrorCodeQuotaExceeded is the JSON-RPC error code of a tool call over
+// the caller's quota
+const ErrorCodeQuotaExceeded = -32004
+
+// Anonymous is the caller name recorded for unauthenticated calls, such as
+// those of the stdio server
+const Anonymous = "anonymous"
+
+// Quota limits a caller's usage of all tools; zero fields are unlimited
+type Quota struct {
+	DailyCalls   int64 `yaml:"daily_calls,omitempty" json:"daily_calls,omitempty"`
+	MonthlyCalls int64 `yaml:"monthly_calls,omitempty" json:"monthly_calls,omitempty"`
+	// DailyBytes and MonthlyBytes limit bytes in and out together
+	DailyBytes   int64 `yaml:"daily_bytes,omitempty" json:"daily_bytes,omitempty"`
+	MonthlyBytes int64 `yaml:"monthly_bytes,omitempty" json:"monthly_bytes,omitempty"`
+}
+
+// Validate checks the quota's fields
+func (q Quota) Validate() error {
+	if q.DailyCalls < 0 || q.MonthlyCalls < 0 || q.DailyBytes < 0 || q.MonthlyBytes < 0 {
+		return fmt.Errorf("quotas must not be negative")
+	}
+	return nil
+}
+
+// exceeded returns the first limit of q that day or month has reached
+func (q Quota) exceeded(day, month Counters) (period, unit string, limit, used int64, ok bool) {
+	checks := []struct {
+		period, unit string
+		limit, used  int64
+	}{
+		{"daily", "calls", q.DailyCalls, day.Calls},
+		{"monthly", "calls", q.MonthlyCalls, month.Calls},
+		{"daily", "bytes", q.DailyBytes, day.BytesIn + day.BytesOut},
+		{"monthly", "bytes", q.MonthlyBytes, month.BytesIn + month.BytesOut},
+	}
+	for _, c := range checks {
+		if c.limit > 0 && c.used >= c.limit {
+			return c.period, c.unit, c.limit, c.used, true
+		}
+	}
+	return "", "", 0, 0, false
+}
+
+// Caller returns the name usage of identity is recorded under
+func Caller(identity *auth.Identity) string {
+	if identity == nil {
+		return Anonymous
+	}
+	return identity.Name
+}
+
+// Tools returns tool middleware recording every tool call in tracker and
+// rejecting calls once the caller's quota is used up. quotas maps caller
+// names to quotas, with "*" applying to callers not listed; it is looked up
+// on every call so quotas can be reloaded.
+func Tools(tracker *Tracker, quotas func() map[string]Quota) mcp.ToolMiddleware {
+	return func(next mcp.ToolCallFunc) mcp.ToolCallFunc {
+		return func(ctx context.Context, tool mcp.Tool, args map[string]interface{}) (interface{}, error) {
+			caller := Caller(auth.IdentityFromContext(ctx))
+
+			all := quotas()
+			quota, ok := all[caller]
+			if !ok {
+				quota = all["*"]
+			}
+			if quota != (Quota{}) {
+				day, month := tracker.Totals(caller)
+				if period, unit, limit, used, over := quota.exceeded(day, month); over {
+					return nil, &mcp.JSONRPCError{
+						Code:    ErrorCodeQuotaExceeded,
+						Kind:    "quota_exceeded",
+						Message: fmt.Sprintf("Usage quota exceeded: %s %s", period, unit),
+						Data: map[string]interface{}{
+							"period":  period,
+							"unit":    unit,
+							"limit":   limit,
+							"used":    used,
+							"resetAt": resetAt(period, time.Now().UTC()).Format(time.RFC3339),
+						},
+					}
+				}
+			}
+
+			start := time.Now()
+			result, err := next(ctx, tool, args)
+			counters := Counters{
+				Calls:      1,
+				BytesIn:    size(args),
+				DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				counters.Errors = 1
+			} else {
+				counters.BytesOut = size(result)
+			}
+			tracker.Record(caller, tool.Name, counters)
+			return result, err
+		}
+	}
+}
+
+// resetAt returns when a daily or monthly quota starts over
+func resetAt(period string, now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if period == "monthly" {
+		return day.AddDate(0, 1, 1-now.Day())
+	}
+	return day.AddDate(0, 0, 1)
+}
+
+// size returns the length of v encoded as JSON, as sent on the wire
+func size(v interface{}) int64 {
+	if v == nil {
+		return 0
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
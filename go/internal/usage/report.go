@@ -0,0 +1,116 @@
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ReportPath is where the usage report is served
+const ReportPath = "/admin/usage"
+
+// reportHeader is the header row of CSV reports
+var reportHeader = []string{"period", "caller", "tool", "calls", "errors", "bytes_in", "bytes_out", "duration_ms"}
+
+// report is the JSON form of a usage report
+type report struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Group   string   `json:"group"`
+	Records []Record `json:"records"`
+}
+
+// Handler serves usage reports. Query parameters:
+//
+//	from, to  first and last day (2006-01-02, UTC); default this month
+//	caller    only this caller
+//	group     day (default) or month
+//	format    json (default) or csv
+//
+// It does not check who is asking; mount it behind authentication.
+func (t *Tracker) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		now := time.Now().UTC()
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		to := now
+		var err error
+		if v := query.Get("from"); v != "" {
+			if from, err = time.Parse(dayFormat, v); err != nil {
+				writeError(w, "from must be a date like 2006-01-02")
+				return
+			}
+		}
+		if v := query.Get("to"); v != "" {
+			if to, err = time.Parse(dayFormat, v); err != nil {
+				writeError(w, "to must be a date like 2006-01-02")
+				return
+			}
+		}
+		if to.Before(from) {
+			writeError(w, "to must not be before from")
+			return
+		}
+
+		group := query.Get("group")
+		switch group {
+		case "":
+			group = "day"
+		case "day", "month":
+		default:
+			writeError(w, "group must be day or month")
+			return
+		}
+
+		records := t.Report(from, to, query.Get("caller"), group == "month")
+		w.Header().Set("Cache-Control", "no-store")
+
+		switch query.Get("format") {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(report{
+				From:    from.Format(dayFormat),
+				To:      to.Format(dayFormat),
+				Group:   group,
+				Records: records,
+			})
+		case "csv":
+			name := fmt.Sprintf("usage-%s-%s.csv", from.Format(dayFormat), to.Format(dayFormat))
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+			writeCSV(w, records)
+		default:
+			writeError(w, "format must be json or csv")
+		}
+	}
+}
+
+// writeCSV writes records as CSV with a header row
+func writeCSV(w http.ResponseWriter, records []Record) {
+	out := csv.NewWriter(w)
+	out.Write(reportHeader)
+	for _, r := range records {
+		out.Write([]string{
+			r.Period,
+			r.Caller,
+			r.Tool,
+			strconv.FormatInt(r.Calls, 10),
+			strconv.FormatInt(r.Errors, 10),
+			strconv.FormatInt(r.BytesIn, 10),
+			strconv.FormatInt(r.BytesOut, 10),
+			strconv.FormatFloat(r.DurationMS, 'f', 3, 64),
+		})
+	}
+	out.Flush()
+}
+
+// writeError writes a 400 response for a bad query
+func writeError(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,279 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/logging"
+)
+
+// logger is the logger of the "usage" component
+var logger = logging.Component("usage")
+
+// dayFormat is how days are written; days and months are in UTC
+const dayFormat = "2006-01-02"
+
+// Counters accumulate the usage of one tool by one caller on one day
+type Counters struct {
+	Calls      int64   `json:"calls"`
+	Errors     int64   `json:"errors"`
+	BytesIn    int64   `json:"bytes_in"`
+	BytesOut   int64   `json:"bytes_out"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// add adds other to c
+func (c *Counters) add(other Counters) {
+	c.Calls += other.Calls
+	c.Errors += other.Errors
+	c.BytesIn += other.BytesIn
+	c.BytesOut += other.BytesOut
+	// Round to microseconds so sums don't collect float noise
+	c.DurationMS = math.Round((c.DurationMS+other.DurationMS)*1000) / 1000
+}
+
+// Record is a row of a usage report
+type Record struct {
+	// Period is a day (2006-01-02) or, in monthly reports, a month (2006-01)
+	Period string `json:"period"`
+	Caller string `json:"caller"`
+	Tool   string `json:"tool"`
+	Counters
+}
+
+// key identifies a caller's use of a tool
+type key struct {
+	Caller string
+	Tool   string
+}
+
+// Tracker counts tool usage per day, caller and tool, and persists the
+// counters to a file so they survive restarts
+type Tracker struct {
+	path      string
+	retention int
+
+	mu    sync.Mutex
+	days  map[string]map[key]*Counters
+	dirty bool
+}
+
+// NewTracker creates a tracker keeping retentionDays of usage. With a
+// path, counters saved there earlier are loaded.
+func NewTracker(path string, retentionDays int) (*Tracker, error) {
+	t := &Tracker{
+		path:      path,
+		retention: retentionDays,
+		days:      make(map[string]map[key]*Counters),
+	}
+	if path == "" {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file %s: %w", path, err)
+	}
+	for _, r := range records {
+		t.counters(r.Period, key{r.Caller, r.Tool}).add(r.Counters)
+	}
+	return t, nil
+}
+
+// counters returns the counters of k on day, creating them. t.mu must be
+// held, except while loading.
+func (t *Tracker) counters(day string, k key) *Counters {
+	tools, ok := t.days[day]
+	if !ok {
+		tools = make(map[key]*Counters)
+		t.days[day] = tools
+	}
+	c, ok := tools[k]
+	if !ok {
+		c = &Counters{}
+		tools[k] = c
+	}
+	return c
+}
+
+// Record adds one tool call to today's counters
+func (t *Tracker) Record(caller, tool string, c Counters) {
+	day := time.Now().UTC().Format(dayFormat)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counters(day, key{caller, tool}).add(c)
+	t.dirty = true
+}
+
+// Totals returns a caller's usage of all tools today and this month
+func (t *Tracker) Totals(caller string) (day, month Counters) {
+	now := time.Now().UTC()
+	today := now.Format(dayFormat)
+	thisMonth := today[:7]
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for d, tools := range t.days {
+		if d[:7] != thisMonth {
+			continue
+		}
+		for k, c := range tools {
+			if k.Caller != caller {
+				continue
+			}
+			month.add(*c)
+			if d == today {
+				day.add(*c)
+			}
+		}
+	}
+	return day, month
+}
+
+// Report returns the usage between from and to (inclusive days), by day or,
+// if monthly, by month. An empty caller matches every caller.
+func (t *Tracker) Report(from, to time.Time, caller string, monthly bool) []Record {
+	first, last := from.UTC().Format(dayFormat), to.UTC().Format(dayFormat)
+
+	t.mu.Lock()
+	rows := make(map[Record]*Counters)
+	for d, tools := range t.days {
+		if d < first || d > last {
+			continue
+		}
+		period := d
+		if monthly {
+			period = d[:7]
+		}
+		for k, c := range tools {
+			if caller != "" && k.Caller != caller {
+				continue
+			}
+			id := Record{Period: period, Caller: k.Caller, Tool: k.Tool}
+			if rows[id] == nil {
+				rows[id] = &Counters{}
+			}
+			rows[id].add(*c)
+		}
+	}
+	t.mu.Unlock()
+
+	records := make([]Record, 0, len(rows))
+	for id, c := range rows {
+		id.Counters = *c
+		records = append(records, id)
+	}
+	sortRecords(records)
+	return records
+}
+
+// sortRecords orders records by period, caller and tool
+func sortRecords(records []Record) {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.Caller != b.Caller {
+			return a.Caller < b.Caller
+		}
+		return a.Tool < b.Tool
+	})
+}
+
+// Save writes the counters to the file, dropping days past the retention
+// period. The file is replaced atomically so a crash can't truncate it.
+func (t *Tracker) Save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	if !t.dirty {
+		t.mu.Unlock()
+		return nil
+	}
+	oldest := time.Now().UTC().AddDate(0, 0, -t.retention).Format(dayFormat)
+	var records []Record
+	for d, tools := range t.days {
+		if t.retention > 0 && d < oldest {
+			delete(t.days, d)
+			continue
+		}
+		for k, c := range tools {
+			records = append(records, Record{Period: d, Caller: k.Caller, Tool: k.Tool, Counters: *c})
+		}
+	}
+	t.dirty = false
+	t.mu.Unlock()
+
+	sortRecords(records)
+	if err := writeFile(t.path, records); err != nil {
+		// Try again on the next save
+		t.mu.Lock()
+		t.dirty = true
+		t.mu.Unlock()
+		return fmt.Errorf("failed to save usage: %w", err)
+	}
+	return nil
+}
+
+// writeFile replaces the file at path with records
+func writeFile(path string, records []Record) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".usage-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Run saves the counters every interval until ctx is done. Callers save
+// once more on shutdown.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	if t.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Save(); err != nil {
+				logger.Error("Usage save failed", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/auth"
+	"github.com/martians-sheep/remote-mcpserver-sample/go/internal/mcp"
+)
+
+func TestToolsQuota(t *testing.T) {
+	quotas := map[string]Quota{
+		"alice": {DailyCalls: 2},
+		"bob":   {MonthlyBytes: 40},
+		"*":     {DailyCalls: 1},
+	}
+	tests := []struct {
+		name   string
+		caller string
+		// allowed is how many calls succeed before the quota is used up;
+		// -1 means unlimited
+		allowed  int
+		wantUnit string
+		quotas   map[string]Quota
+	}{
+		{"daily calls", "alice", 2, "calls", quotas},
+		// Each call moves 16 bytes in and 16 out
+		{"monthly bytes", "bob", 2, "bytes", quotas},
+		{"default quota", "carol", 1, "calls", quotas},
+		{"anonymous", "", 1, "calls", quotas},
+		{"no default", "carol", -1, "", map[string]Quota{"alice": {DailyCalls: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, err := NewTracker("", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			call := Tools(tracker, func() map[string]Quota { return tt.quotas })(
+				func(ctx context.Context, tool mcp.Tool, args map[string]interface{}) (interface{}, error) {
+					return args, nil
+				})
+			ctx := context.Background()
+			if tt.caller != "" {
+				ctx = auth.WithIdentity(ctx, &auth.Identity{Name: tt.caller})
+			}
+
+			for i := 0; i < 5; i++ {
+				_, err := call(ctx, mcp.Tool{Name: "echo"}, map[string]interface{}{"message": "hi"})
+				if tt.allowed < 0 || i < tt.allowed {
+					if err != nil {
+						t.Fatalf("call %d error = %v", i, err)
+					}
+					continue
+				}
+
+				var rpcErr *mcp.JSONRPCError
+				if !errors.As(err, &rpcErr) || rpcErr.Code != ErrorCodeQuotaExceeded {
+					t.Fatalf("call %d error = %v, want the quota error", i, err)
+				}
+				if unit := rpcErr.Data.(map[string]interface{})["unit"]; unit != tt.wantUnit {
+					t.Errorf("quota unit = %v, want %s", unit, tt.wantUnit)
+				}
+			}
+
+			// Rejected calls are not counted
+			day, _ := tracker.Totals(Caller(auth.IdentityFromContext(ctx)))
+			want := int64(tt.allowed)
+			if tt.allowed < 0 {
+				want = 5
+			}
+			if day.Calls != want {
+				t.Errorf("calls recorded = %d, want %d", day.Calls, want)
+			}
+		})
+	}
+}
+
+func TestResetAt(t *testing.T) {
+	now := time.Date(2026, 1, 31, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		period string
+		want   time.Time
+	}{
+		{"daily", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"monthly", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			if got := resetAt(tt.period, now); !got.Equal(tt.want) {
+				t.Errorf("resetAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	old := time.Now().UTC().AddDate(0, 0, -30).Format(dayFormat)
+	data, err := json.Marshal([]Record{{Period: old, Caller: "alice", Tool: "echo", Counters: Counters{Calls: 7}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker, err := NewTracker(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records := tracker.Report(time.Now().AddDate(0, 0, -30), time.Now(), "alice", false); len(records) != 1 {
+		t.Fatalf("loaded records = %v, want the saved day", records)
+	}
+	tracker.Record("alice", "echo", Counters{Calls: 1, BytesIn: 10, BytesOut: 20})
+	tracker.Record("alice", "echo", Counters{Calls: 1, Errors: 1, BytesIn: 5})
+	if err := tracker.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Counters survive a restart; days past the retention period don't
+	reloaded, err := NewTracker(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day, month := reloaded.Totals("alice")
+	want := Counters{Calls: 2, Errors: 1, BytesIn: 15, BytesOut: 20}
+	if day != want || month != want {
+		t.Errorf("Totals() after reload = %+v, %+v, want %+v", day, month, want)
+	}
+	if records := reloaded.Report(time.Now().AddDate(0, 0, -30), time.Now(), "", false); len(records) != 1 {
+		t.Errorf("records after reload = %v, want only today's", records)
+	}
+
+	if _, err := NewTracker(filepath.Join(t.TempDir(), "missing.json"), 10); err != nil {
+		t.Errorf("NewTracker() with a missing file error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTracker(path, 10); err == nil {
+		t.Error("NewTracker() accepted a corrupt file")
+	}
+}